@@ -27,9 +27,12 @@ import (
 	"runtime/pprof"
 
 	"flag"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -39,9 +42,10 @@ import (
 	"github.com/Jeffail/benthos/lib/output"
 	"github.com/Jeffail/benthos/lib/processor"
 	"github.com/Jeffail/benthos/lib/processor/condition"
-	"github.com/Jeffail/benthos/lib/types"
-	"github.com/Jeffail/benthos/lib/util"
+	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/stream/manager"
 	"github.com/Jeffail/benthos/lib/util/service"
+	"github.com/Jeffail/benthos/lib/util/service/config"
 	"github.com/Jeffail/benthos/lib/util/service/log"
 	"github.com/Jeffail/benthos/lib/util/service/metrics"
 )
@@ -50,13 +54,14 @@ import (
 
 // Config is the benthos configuration struct.
 type Config struct {
-	HTTP                 api.Config       `json:"http" yaml:"http"`
-	Input                input.Config     `json:"input" yaml:"input"`
-	Output               output.Config    `json:"output" yaml:"output"`
-	Buffer               buffer.Config    `json:"buffer" yaml:"buffer"`
-	Logger               log.LoggerConfig `json:"logger" yaml:"logger"`
-	Metrics              metrics.Config   `json:"metrics" yaml:"metrics"`
-	SystemCloseTimeoutMS int              `json:"sys_exit_timeout_ms" yaml:"sys_exit_timeout_ms"`
+	HTTP                 api.Config               `json:"http" yaml:"http"`
+	Input                input.Config             `json:"input" yaml:"input"`
+	Output               output.Config            `json:"output" yaml:"output"`
+	Buffer               buffer.Config            `json:"buffer" yaml:"buffer"`
+	Streams              map[string]stream.Config `json:"streams,omitempty" yaml:"streams,omitempty"`
+	Logger               log.LoggerConfig         `json:"logger" yaml:"logger"`
+	Metrics              metrics.Config           `json:"metrics" yaml:"metrics"`
+	SystemCloseTimeoutMS int                      `json:"sys_exit_timeout_ms" yaml:"sys_exit_timeout_ms"`
 }
 
 // NewConfig returns a new configuration with default values.
@@ -96,6 +101,16 @@ func (c Config) Sanitised() (interface{}, error) {
 		return nil, err
 	}
 
+	var streamConfs map[string]interface{}
+	if len(c.Streams) > 0 {
+		streamConfs = map[string]interface{}{}
+		for id, sConf := range c.Streams {
+			if streamConfs[id], err = sConf.Sanitised(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	var metConf interface{}
 	metConf, err = metrics.SanitiseConfig(c.Metrics)
 	if err != nil {
@@ -103,18 +118,20 @@ func (c Config) Sanitised() (interface{}, error) {
 	}
 
 	return struct {
-		HTTP                 interface{} `json:"http" yaml:"http"`
-		Input                interface{} `json:"input" yaml:"input"`
-		Output               interface{} `json:"output" yaml:"output"`
-		Buffer               interface{} `json:"buffer" yaml:"buffer"`
-		Logger               interface{} `json:"logger" yaml:"logger"`
-		Metrics              interface{} `json:"metrics" yaml:"metrics"`
-		SystemCloseTimeoutMS interface{} `json:"sys_exit_timeout_ms" yaml:"sys_exit_timeout_ms"`
+		HTTP                 interface{}            `json:"http" yaml:"http"`
+		Input                interface{}            `json:"input" yaml:"input"`
+		Output               interface{}            `json:"output" yaml:"output"`
+		Buffer               interface{}            `json:"buffer" yaml:"buffer"`
+		Streams              map[string]interface{} `json:"streams,omitempty" yaml:"streams,omitempty"`
+		Logger               interface{}            `json:"logger" yaml:"logger"`
+		Metrics              interface{}            `json:"metrics" yaml:"metrics"`
+		SystemCloseTimeoutMS interface{}            `json:"sys_exit_timeout_ms" yaml:"sys_exit_timeout_ms"`
 	}{
 		HTTP:                 c.HTTP,
 		Input:                inConf,
 		Output:               outConf,
 		Buffer:               bufConf,
+		Streams:              streamConfs,
 		Logger:               c.Logger,
 		Metrics:              metConf,
 		SystemCloseTimeoutMS: c.SystemCloseTimeoutMS,
@@ -145,6 +162,16 @@ var (
 		"list-conditions", false,
 		"Print a list of available processor condition options, then exit",
 	)
+	streamsMode = flag.Bool(
+		"streams", false,
+		"Run Benthos in streams mode, where streams can be created, updated and"+
+			" removed at runtime through the HTTP API",
+	)
+	streamsDir = flag.String(
+		"streams-dir", "",
+		"Path to a directory of stream configs, where each file defines a stream"+
+			" named after the file. Runs Benthos in streams mode",
+	)
 )
 
 //------------------------------------------------------------------------------
@@ -169,7 +196,8 @@ func bootstrap() Config {
 		fmt.Fprintf(os.Stderr,
 			"\nFor example configs use --print-yaml or --print-json\n"+
 				"For a list of available inputs or outputs use --list-inputs or --list-outputs\n"+
-				"For a list of available buffer options use --list-buffers\n")
+				"For a list of available buffer options use --list-buffers\n"+
+				"To run multiple streams define them under 'streams' or use --streams-dir\n")
 	}
 
 	// Load configuration etc
@@ -200,73 +228,77 @@ func bootstrap() Config {
 	return config
 }
 
-// createPipeline creates a pipeline based on the supplied configuration file,
-// and return a closable pool of pipeline objects, a channel indicating that all
-// inputs and outputs have seized, or an error.
-func createPipeline(
-	config Config, mgr types.Manager, logger log.Modular, stats metrics.Type,
-) (*util.ClosablePool, *util.ClosablePool, chan struct{}, error) {
-	// Create two pools, this helps manage ordered closure of all pipeline
-	// components. We have a tiered (t1) and an non-tiered (t2) pool. If the
-	// tiered pool cannot close within our allotted time period then we try
-	// closing the second non-tiered pool. If the second pool also fails then we
-	// exit the service ungracefully.
-	poolt1, poolt2 := util.NewClosablePool(), util.NewClosablePool()
-
-	// Create our input pipe
-	inputPipe, err := input.New(config.Input, mgr, logger, stats)
+// readStreamsDir reads each config file of a directory as a stream config,
+// where the stream id is the name of the file without its extension.
+func readStreamsDir(dir string) (map[string]stream.Config, error) {
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		logger.Errorf("Input error (%s): %v\n", config.Input.Type, err)
-		return nil, nil, nil, err
+		return nil, err
 	}
-	poolt1.Add(1, inputPipe)
-	poolt2.Add(0, inputPipe)
 
-	// Create a buffer
-	buf, err := buffer.New(config.Buffer, logger, stats)
-	if err != nil {
-		logger.Errorf("Buffer error (%s): %v\n", config.Buffer.Type, err)
-		return nil, nil, nil, err
-	}
-	poolt1.Add(3, buf)
-	poolt2.Add(0, buf)
+	confs := map[string]stream.Config{}
+	for _, info := range files {
+		ext := filepath.Ext(info.Name())
+		if info.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
 
-	// Create our output pipe
-	outputPipe, err := output.New(config.Output, mgr, logger, stats)
-	if err != nil {
-		logger.Errorf("Output error (%s): %v\n", config.Output.Type, err)
-		return nil, nil, nil, err
+		conf := stream.NewConfig()
+		if err = config.Read(filepath.Join(dir, info.Name()), true, &conf); err != nil {
+			return nil, fmt.Errorf("failed to read stream config '%v': %v", info.Name(), err)
+		}
+		confs[strings.TrimSuffix(info.Name(), ext)] = conf
 	}
-	poolt1.Add(10, outputPipe)
-	poolt2.Add(0, outputPipe)
-
-	outputPipe.StartReceiving(buf.TransactionChan())
-	buf.StartReceiving(inputPipe.TransactionChan())
+	return confs, nil
+}
 
-	closeChan := make(chan struct{})
+// streamConfigs returns the configs of all streams to run in streams mode, or
+// nil if Benthos should run a single stream.
+func streamConfigs(conf Config) (map[string]stream.Config, error) {
+	if !*streamsMode && len(conf.Streams) == 0 && len(*streamsDir) == 0 {
+		return nil, nil
+	}
 
-	// If our outputs close down then we should shut down the service
-	go func() {
-		for {
-			if err := outputPipe.WaitForClose(time.Second * 60); err == nil {
-				closeChan <- struct{}{}
-				return
+	confs := map[string]stream.Config{}
+	for id, sConf := range conf.Streams {
+		confs[id] = sConf
+	}
+	if len(*streamsDir) > 0 {
+		dirConfs, err := readStreamsDir(*streamsDir)
+		if err != nil {
+			return nil, err
+		}
+		for id, sConf := range dirConfs {
+			if _, exists := confs[id]; exists {
+				return nil, fmt.Errorf("stream '%v' is defined more than once", id)
 			}
+			confs[id] = sConf
 		}
-	}()
-
-	return poolt1, poolt2, closeChan, nil
+	}
+	return confs, nil
 }
 
 func main() {
 	// Bootstrap by reading cmd flags and configuration file
 	config := bootstrap()
 
-	// Logging and stats aggregation
-	var logger log.Modular
+	streamConfs, err := streamConfigs(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Streams error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Note: Only log to Stderr if one of our outputs is stdout
-	if config.Output.Type == "stdout" {
+	logToStderr := config.Output.Type == "stdout"
+	for _, sConf := range streamConfs {
+		if sConf.Output.Type == "stdout" {
+			logToStderr = true
+		}
+	}
+
+	// Logging and stats aggregation
+	var logger log.Modular
+	if logToStderr {
 		logger = log.NewLogger(os.Stderr, config.Logger)
 	} else {
 		logger = log.NewLogger(os.Stdout, config.Logger)
@@ -288,10 +320,37 @@ func main() {
 	}
 	httpServer := api.New(service.Version, service.DateBuilt, config.HTTP, sanConf, logger, stats)
 
-	poolTiered, poolNonTiered, outputsClosedChan, err := createPipeline(config, httpServer, logger, stats)
-	if err != nil {
-		logger.Errorf("Service closing due to: %v\n", err)
-		return
+	// In streams mode the service runs until terminated, otherwise the service
+	// closes once the output of its single stream has closed.
+	var outputsClosedChan <-chan struct{}
+	var stopStreams func(timeout time.Duration) error
+
+	if streamConfs != nil {
+		streamMgr := manager.New(
+			httpServer, logger, stats,
+			manager.OptSetAPITimeout(time.Millisecond*time.Duration(config.SystemCloseTimeoutMS)),
+		)
+		for id, sConf := range streamConfs {
+			if err = streamMgr.Create(id, sConf); err != nil {
+				logger.Errorf("Service closing due to stream '%v': %v\n", id, err)
+				streamMgr.Stop(time.Millisecond * time.Duration(config.SystemCloseTimeoutMS))
+				return
+			}
+		}
+		logger.Infof("Running %v streams.\n", len(streamConfs))
+		stopStreams = streamMgr.Stop
+	} else {
+		s, err := stream.New(stream.Config{
+			Input:  config.Input,
+			Buffer: config.Buffer,
+			Output: config.Output,
+		}, httpServer, logger, stats)
+		if err != nil {
+			logger.Errorf("Service closing due to: %v\n", err)
+			return
+		}
+		outputsClosedChan = s.Done()
+		stopStreams = s.Stop
 	}
 
 	httpServerClosedChan := make(chan struct{})
@@ -330,17 +389,11 @@ func main() {
 			os.Exit(1)
 		}()
 
-		if err := poolTiered.Close(tout / 2); err != nil {
+		if err := stopStreams(tout); err != nil {
 			logger.Warnln(
-				"Service failed to close using ordered tiers, you may receive a duplicate " +
-					"message on the next service start.",
+				"Service failed to close cleanly within allocated time. Exiting forcefully.",
 			)
-			if err = poolNonTiered.Close(tout / 2); err != nil {
-				logger.Warnln(
-					"Service failed to close cleanly within allocated time. Exiting forcefully.",
-				)
-				os.Exit(1)
-			}
+			os.Exit(1)
 		}
 	}()
 
@@ -90,6 +90,22 @@ func (d *dynamicConfMgr) Remove(id string) {
 
 //------------------------------------------------------------------------------
 
+// ErrStatus is an error that can be returned by CRUD event handlers in order to
+// respond to a request with a specific status code, where the error message is
+// written as the response body. All other errors result in a generic internal
+// server error response.
+type ErrStatus struct {
+	Code int
+	Err  error
+}
+
+// Error returns the Error string.
+func (e ErrStatus) Error() string {
+	return e.Err.Error()
+}
+
+//------------------------------------------------------------------------------
+
 // Dynamic is a type for exposing CRUD operations on dynamic broker
 // configurations as an HTTP interface. Events can be registered for listening
 // to configuration changes, and these events should be forwarded to the
@@ -160,6 +176,17 @@ func (d *Dynamic) Started(id string, config []byte) {
 	}
 }
 
+// Reset should be called whenever a dynamic component has closed by itself and
+// remains registered. The config of the component is forgotten for the purpose
+// of ignoring duplicate requests, so that a request with an identical config
+// recreates the component.
+func (d *Dynamic) Reset(id string) {
+	d.configsMut.Lock()
+	defer d.configsMut.Unlock()
+
+	d.configHashes.Remove(id)
+}
+
 //------------------------------------------------------------------------------
 
 // HandleList is an http.HandleFunc for returning maps of active dynamic
@@ -272,7 +299,9 @@ func (d *Dynamic) HandleCRUD(w http.ResponseWriter, r *http.Request) {
 		if r.Body != nil {
 			r.Body.Close()
 		}
-		if httpErr != nil {
+		if statusErr, ok := httpErr.(ErrStatus); ok {
+			http.Error(w, statusErr.Error(), statusErr.Code)
+		} else if httpErr != nil {
 			http.Error(w, "Internal server error", http.StatusBadGateway)
 		}
 	}()
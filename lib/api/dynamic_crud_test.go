@@ -139,6 +139,25 @@ func TestDynamicDelete(t *testing.T) {
 	}
 }
 
+func TestDynamicStatusErrors(t *testing.T) {
+	dAPI := NewDynamic()
+	r := router(dAPI)
+
+	dAPI.OnUpdate(func(id string, content []byte) error {
+		return ErrStatus{Code: http.StatusBadRequest, Err: errors.New("bad config")}
+	})
+
+	request, _ := http.NewRequest("POST", "/input/foo", bytes.NewReader([]byte("hello world")))
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+	if exp, act := "bad config\n", response.Body.String(); exp != act {
+		t.Errorf("Unexpected response body: %v != %v", act, exp)
+	}
+}
+
 func TestDynamicBasicCRUD(t *testing.T) {
 	dAPI := NewDynamic()
 	r := router(dAPI)
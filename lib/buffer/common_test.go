@@ -20,8 +20,26 @@
 
 package buffer
 
-import "github.com/Jeffail/benthos/lib/util/service/log"
+import (
+	"net/http"
+
+	"github.com/Jeffail/benthos/lib/util/service/log"
+)
 
 var logConfig = log.LoggerConfig{
 	LogLevel: "NONE",
 }
+
+type mockManager struct {
+	endpoints map[string]http.HandlerFunc
+}
+
+func newMockManager() *mockManager {
+	return &mockManager{
+		endpoints: map[string]http.HandlerFunc{},
+	}
+}
+
+func (m *mockManager) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	m.endpoints[path] = h
+}
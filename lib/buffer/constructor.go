@@ -36,7 +36,7 @@ import (
 
 // TypeSpec is a constructor and usage description for each buffer type.
 type TypeSpec struct {
-	constructor func(conf Config, log log.Modular, stats metrics.Type) (Type, error)
+	constructor func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error)
 	description string
 }
 
@@ -47,19 +47,21 @@ var Constructors = map[string]TypeSpec{}
 
 // Config is the all encompassing configuration struct for all input types.
 type Config struct {
-	Type   string                `json:"type" yaml:"type"`
-	Memory impl.MemoryConfig     `json:"memory" yaml:"memory"`
-	Mmap   impl.MmapBufferConfig `json:"mmap_file" yaml:"mmap_file"`
-	None   struct{}              `json:"none" yaml:"none"`
+	Type     string                `json:"type" yaml:"type"`
+	Memory   impl.MemoryConfig     `json:"memory" yaml:"memory"`
+	Mmap     impl.MmapBufferConfig `json:"mmap_file" yaml:"mmap_file"`
+	None     struct{}              `json:"none" yaml:"none"`
+	Priority PriorityConfig        `json:"priority" yaml:"priority"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:   "none",
-		Memory: impl.NewMemoryConfig(),
-		Mmap:   impl.NewMmapBufferConfig(),
-		None:   struct{}{},
+		Type:     "none",
+		Memory:   impl.NewMemoryConfig(),
+		Mmap:     impl.NewMmapBufferConfig(),
+		None:     struct{}{},
+		Priority: NewPriorityConfig(),
 	}
 }
 
@@ -114,10 +116,10 @@ func Descriptions() string {
 	return buf.String()
 }
 
-// New creates an input type based on an input configuration.
-func New(conf Config, log log.Modular, stats metrics.Type) (Type, error) {
+// New creates a buffer type based on a buffer configuration.
+func New(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 	if c, ok := Constructors[conf.Type]; ok {
-		return c.constructor(conf, log, stats)
+		return c.constructor(conf, mgr, log, stats)
 	}
 	return nil, types.ErrInvalidBufferType
 }
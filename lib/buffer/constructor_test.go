@@ -39,7 +39,7 @@ func TestConstructorBadType(t *testing.T) {
 	conf := NewConfig()
 	conf.Type = "not_exist"
 
-	if _, err := New(conf, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err == nil {
+	if _, err := New(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err == nil {
 		t.Error("Expected error, received nil for invalid type")
 	}
 }
@@ -65,7 +65,15 @@ func TestSanitise(t *testing.T) {
 	exp = map[string]interface{}{
 		"type": "memory",
 		"memory": map[string]interface{}{
-			"limit": float64(20),
+			"limit":    float64(20),
+			"overflow": "block",
+			"spill": map[string]interface{}{
+				"directory":           "",
+				"file_size":           float64(250 * 1024 * 1024),
+				"retry_period_ms":     float64(1000),
+				"clean_up":            true,
+				"reserved_disk_space": float64(100 * 1024 * 1024),
+			},
 		},
 	}
 
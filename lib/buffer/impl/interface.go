@@ -20,7 +20,11 @@
 
 package impl
 
-import "github.com/Jeffail/benthos/lib/types"
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
 
 //------------------------------------------------------------------------------
 
@@ -50,3 +54,36 @@ type Buffer interface {
 }
 
 //------------------------------------------------------------------------------
+
+// Stats is a snapshot of the contents of a buffer.
+type Stats struct {
+	// Bytes is the current backlog of the buffer in bytes.
+	Bytes int
+
+	// Count is the number of messages currently stored in the buffer.
+	Count int
+
+	// Oldest is the time at which the oldest stored message was pushed, or a
+	// zero time if the buffer is empty.
+	Oldest time.Time
+}
+
+// Inspectable is a Buffer that exposes its contents at runtime for debugging
+// and administrative purposes.
+type Inspectable interface {
+	Buffer
+
+	// Stats returns a snapshot of the current contents of the buffer.
+	Stats() Stats
+
+	// PeekMessages returns up to n of the oldest messages stored in the buffer
+	// without removing them.
+	PeekMessages(n int) ([]types.Message, error)
+
+	// Purge removes all stored messages from the buffer and returns the number
+	// of messages removed. A message that has been read with NextMessage but
+	// not yet shifted is preserved so that it can be shifted as normal.
+	Purge() (int, error)
+}
+
+//------------------------------------------------------------------------------
@@ -24,40 +24,73 @@ import (
 	"sync"
 
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
 )
 
 //------------------------------------------------------------------------------
 
+// Overflow policies of the memory buffer.
+const (
+	// OverflowBlock blocks new messages until space is freed.
+	OverflowBlock = "block"
+
+	// OverflowDropNewest discards new messages that do not fit.
+	OverflowDropNewest = "drop_newest"
+
+	// OverflowDropOldest discards the oldest messages until new messages fit.
+	OverflowDropOldest = "drop_oldest"
+
+	// OverflowSpill writes new messages to a memory mapped file buffer until
+	// space is freed. This policy is implemented by the Spillover type.
+	OverflowSpill = "spill"
+)
+
 // MemoryConfig is config values for a purely memory based ring buffer type.
 type MemoryConfig struct {
-	Limit int `json:"limit" yaml:"limit"`
+	Limit    int              `json:"limit" yaml:"limit"`
+	Overflow string           `json:"overflow" yaml:"overflow"`
+	Spill    MmapBufferConfig `json:"spill" yaml:"spill"`
 }
 
 // NewMemoryConfig creates a new MemoryConfig with default values.
 func NewMemoryConfig() MemoryConfig {
 	return MemoryConfig{
-		Limit: 1024 * 1024 * 500, // 500MB
+		Limit:    1024 * 1024 * 500, // 500MB
+		Overflow: OverflowBlock,
+		Spill:    NewMmapBufferConfig(),
 	}
 }
 
-// Memory is a purely memory based ring buffer. This buffer blocks when the
-// buffer is full.
+// Memory is a purely memory based ring buffer. By default this buffer blocks
+// when the buffer is full, but it can alternatively be configured to drop
+// either the newest or oldest messages.
 type Memory struct {
 	config MemoryConfig
+	stats  metrics.Type
 
 	block     []byte
 	readFrom  int
 	writtenTo int
 
+	// pending indicates that the oldest message has been read but not yet
+	// shifted.
+	pending bool
+	tracker pushTracker
+
+	// skipShift indicates that the message being read was dropped due to an
+	// overflow and therefore the next shift should be ignored.
+	skipShift bool
+
 	closed bool
 
 	cond *sync.Cond
 }
 
 // NewMemory creates a new memory based ring buffer.
-func NewMemory(config MemoryConfig) *Memory {
+func NewMemory(config MemoryConfig, stats metrics.Type) *Memory {
 	return &Memory{
 		config:    config,
+		stats:     stats,
 		block:     make([]byte, config.Limit),
 		readFrom:  0,
 		writtenTo: 0,
@@ -76,6 +109,47 @@ func (m *Memory) backlog() int {
 	return m.config.Limit - m.readFrom + m.writtenTo
 }
 
+// fits returns whether a serialised message of size bytes can be written
+// without waiting for the reader to catch up.
+func (m *Memory) fits(size int) bool {
+	index, size := m.writtenTo, size+4
+	if m.readFrom > index && m.readFrom <= index+size {
+		return false
+	}
+	if index+size > m.config.Limit && m.readFrom <= size {
+		return false
+	}
+	return true
+}
+
+// shift moves the reader past the oldest message.
+func (m *Memory) shift() {
+	msgSize := readMessageSize(m.block, m.readFrom)
+
+	// Messages are written in a contiguous array of bytes, therefore when the
+	// writer reaches the end it will zero the next four bytes (zero size
+	// message) to indicate to the reader that it has looped back to index 0.
+	if msgSize <= 0 {
+		m.readFrom = 0
+		msgSize = readMessageSize(m.block, m.readFrom)
+	}
+
+	// Set new read from position to next message start.
+	m.readFrom = m.readFrom + int(msgSize) + 4
+	m.tracker.shift()
+}
+
+// dropOldest removes the oldest message in order to free space. If the oldest
+// message is currently being read then the subsequent shift is skipped.
+func (m *Memory) dropOldest() {
+	if m.pending {
+		m.pending = false
+		m.skipShift = true
+	}
+	m.shift()
+	m.stats.Incr("buffer.overflow.dropped", 1)
+}
+
 // readMessageSize reads the size in bytes of a serialised message block
 // starting at index.
 func readMessageSize(block []byte, index int) int {
@@ -131,18 +205,16 @@ func (m *Memory) ShiftMessage() (int, error) {
 		m.cond.L.Unlock()
 	}()
 
-	msgSize := readMessageSize(m.block, m.readFrom)
-
-	// Messages are written in a contiguous array of bytes, therefore when the
-	// writer reaches the end it will zero the next four bytes (zero size
-	// message) to indicate to the reader that it has looped back to index 0.
-	if msgSize <= 0 {
-		m.readFrom = 0
-		msgSize = readMessageSize(m.block, m.readFrom)
+	// The message may have already been dropped or purged since it was read.
+	if m.skipShift {
+		m.skipShift = false
+		return m.backlog(), nil
 	}
-
-	// Set new read from position to next message start.
-	m.readFrom = m.readFrom + int(msgSize) + 4
+	if m.readFrom == m.writtenTo {
+		return 0, nil
+	}
+	m.pending = false
+	m.shift()
 
 	return m.backlog(), nil
 }
@@ -184,7 +256,19 @@ func (m *Memory) NextMessage() (types.Message, error) {
 		return nil, types.ErrBlockCorrupted
 	}
 
-	return types.FromBytes(m.block[index : index+int(msgSize)])
+	m.pending = true
+	msg, err := types.FromBytes(m.block[index : index+int(msgSize)])
+	if err != nil {
+		return nil, err
+	}
+
+	// When dropping the oldest messages the space of this message can be
+	// reclaimed and overwritten while it is still being processed, and
+	// therefore it must not share memory with the block.
+	if m.config.Overflow == OverflowDropOldest {
+		msg = msg.DeepCopy()
+	}
+	return msg, nil
 }
 
 // PushMessage pushes a new message onto the block, returns the backlog count.
@@ -202,6 +286,18 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 		return 0, types.ErrMessageTooLarge
 	}
 
+	switch m.config.Overflow {
+	case OverflowDropNewest:
+		if !m.fits(len(block)) && !m.closed {
+			m.stats.Incr("buffer.overflow.dropped", 1)
+			return m.backlog(), nil
+		}
+	case OverflowDropOldest:
+		for !m.fits(len(block)) && m.tracker.count() > 0 && !m.closed {
+			m.dropOldest()
+		}
+	}
+
 	// Block while the reader is catching up.
 	for m.readFrom > index && m.readFrom <= index+len(block)+4 {
 		m.cond.Wait()
@@ -237,14 +333,121 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 		return 0, types.ErrTypeClosed
 	}
 
+	m.write(index, block)
+	return m.backlog(), nil
+}
+
+// tryPushMessage pushes a new message onto the block only if it fits without
+// blocking. Returns the backlog count and whether the message was written.
+func (m *Memory) tryPushMessage(msg types.Message) (int, bool, error) {
+	m.cond.L.Lock()
+	defer func() {
+		m.cond.Broadcast()
+		m.cond.L.Unlock()
+	}()
+
+	if m.closed {
+		return 0, false, types.ErrTypeClosed
+	}
+
+	block := msg.Bytes()
+	if len(block)+4 > m.config.Limit || !m.fits(len(block)) {
+		return m.backlog(), false, nil
+	}
+
+	index := m.writtenTo
+	if len(block)+4+index > m.config.Limit {
+		for i := index; i < m.config.Limit && i < index+4; i++ {
+			m.block[i] = byte(0)
+		}
+		index = 0
+	}
+
+	m.write(index, block)
+	return m.backlog(), true, nil
+}
+
+// write writes a serialised message at index.
+func (m *Memory) write(index int, block []byte) {
 	writeMessageSize(m.block, index, len(block))
 	copy(m.block[index+4:], block)
 
 	// Move writtenTo index ahead. If writtenTo becomes m.config.Limit we want
 	// it to wrap back to 0
 	m.writtenTo = (index + len(block) + 4) % m.config.Limit
+	m.tracker.push()
+}
 
-	return m.backlog(), nil
+//------------------------------------------------------------------------------
+
+// Stats returns a snapshot of the current contents of the buffer.
+func (m *Memory) Stats() Stats {
+	m.cond.L.Lock()
+	defer m.cond.L.Unlock()
+
+	return Stats{
+		Bytes:  m.backlog(),
+		Count:  m.tracker.count(),
+		Oldest: m.tracker.oldest(),
+	}
+}
+
+// PeekMessages returns up to n of the oldest messages without removing them.
+func (m *Memory) PeekMessages(n int) ([]types.Message, error) {
+	m.cond.L.Lock()
+	defer m.cond.L.Unlock()
+
+	msgs := []types.Message{}
+	index := m.readFrom
+
+	for len(msgs) < n && index != m.writtenTo {
+		msgSize := readMessageSize(m.block, index)
+		if msgSize <= 0 {
+			if index = 0; index == m.writtenTo {
+				break
+			}
+			msgSize = readMessageSize(m.block, index)
+		}
+		if index+4+msgSize > m.config.Limit {
+			return msgs, types.ErrBlockCorrupted
+		}
+
+		msg, err := types.FromBytes(m.block[index+4 : index+4+msgSize])
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg.DeepCopy())
+		index = (index + msgSize + 4) % m.config.Limit
+	}
+
+	return msgs, nil
+}
+
+// Purge removes all stored messages and returns the number removed. If the
+// oldest message has been read and not yet shifted then it is preserved.
+func (m *Memory) Purge() (int, error) {
+	m.cond.L.Lock()
+	defer func() {
+		m.cond.Broadcast()
+		m.cond.L.Unlock()
+	}()
+
+	count := m.tracker.count()
+	if m.pending && m.readFrom != m.writtenTo {
+		index := m.readFrom
+		msgSize := readMessageSize(m.block, index)
+		if msgSize <= 0 {
+			index = 0
+			msgSize = readMessageSize(m.block, index)
+		}
+		m.writtenTo = (index + msgSize + 4) % m.config.Limit
+		m.tracker.truncate(1)
+	} else {
+		m.readFrom = m.writtenTo
+		m.tracker.truncate(0)
+	}
+
+	return count - m.tracker.count(), nil
 }
 
 //------------------------------------------------------------------------------
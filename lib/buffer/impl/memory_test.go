@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
 )
 
 func TestMemoryInterface(t *testing.T) {
@@ -40,7 +41,7 @@ func TestMemoryInterface(t *testing.T) {
 func TestMemoryBasic(t *testing.T) {
 	n := 100
 
-	block := NewMemory(MemoryConfig{Limit: 100000})
+	block := NewMemory(MemoryConfig{Limit: 100000}, metrics.DudType{})
 
 	for i := 0; i < n; i++ {
 		if _, err := block.PushMessage(types.NewMessage(
@@ -73,7 +74,7 @@ func TestMemoryBasic(t *testing.T) {
 }
 
 func TestMemoryBacklogCounter(t *testing.T) {
-	block := NewMemory(MemoryConfig{Limit: 100000})
+	block := NewMemory(MemoryConfig{Limit: 100000}, metrics.DudType{})
 
 	if _, err := block.PushMessage(types.NewMessage(
 		[][]byte{[]byte("1234")}, // 4 bytes + 4 bytes
@@ -120,7 +121,7 @@ func TestMemoryBacklogCounter(t *testing.T) {
 func TestMemoryNearLimit(t *testing.T) {
 	n, iter := 50, 5
 
-	block := NewMemory(MemoryConfig{Limit: 2285})
+	block := NewMemory(MemoryConfig{Limit: 2285}, metrics.DudType{})
 
 	for j := 0; j < iter; j++ {
 		for i := 0; i < n; i++ {
@@ -158,7 +159,7 @@ func TestMemoryNearLimit(t *testing.T) {
 func TestMemoryLoopingRandom(t *testing.T) {
 	n, iter := 50, 5
 
-	block := NewMemory(MemoryConfig{Limit: 8000})
+	block := NewMemory(MemoryConfig{Limit: 8000}, metrics.DudType{})
 
 	for j := 0; j < iter; j++ {
 		for i := 0; i < n; i++ {
@@ -199,7 +200,7 @@ func TestMemoryLoopingRandom(t *testing.T) {
 func TestMemoryLockStep(t *testing.T) {
 	n := 10000
 
-	block := NewMemory(MemoryConfig{Limit: 1000})
+	block := NewMemory(MemoryConfig{Limit: 1000}, metrics.DudType{})
 
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -245,7 +246,7 @@ func TestMemoryLockStep(t *testing.T) {
 func TestMemoryClose(t *testing.T) {
 	// Test reader block
 
-	block := NewMemory(MemoryConfig{Limit: 20})
+	block := NewMemory(MemoryConfig{Limit: 20}, metrics.DudType{})
 	doneChan := make(chan struct{})
 
 	go func() {
@@ -267,7 +268,7 @@ func TestMemoryClose(t *testing.T) {
 
 	// Test writer block
 
-	block = NewMemory(MemoryConfig{Limit: 100})
+	block = NewMemory(MemoryConfig{Limit: 100}, metrics.DudType{})
 	doneChan = make(chan struct{})
 
 	go func() {
@@ -314,10 +315,262 @@ func TestMemoryRejectLargeMessage(t *testing.T) {
 	tMsg := types.NewMessage(make([][]byte, 1))
 	tMsg.Set(0, []byte("hello world this message is too long!"))
 
-	block := NewMemory(MemoryConfig{Limit: 10})
+	block := NewMemory(MemoryConfig{Limit: 10}, metrics.DudType{})
 
 	_, err := block.PushMessage(tMsg)
 	if exp, actual := types.ErrMessageTooLarge, err; exp != actual {
 		t.Errorf("Unexpected error: %v != %v", exp, actual)
 	}
 }
+
+func TestMemoryInspect(t *testing.T) {
+	block := NewMemory(MemoryConfig{Limit: 100}, metrics.DudType{})
+
+	for i := 0; i < 5; i++ {
+		if _, err := block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("test%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := block.Stats()
+	if exp, act := 5, stats.Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", exp, act)
+	}
+	if exp, act := 85, stats.Bytes; exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+	if stats.Oldest.IsZero() {
+		t.Error("Oldest message time not set")
+	}
+
+	msgs, err := block.PeekMessages(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 3, len(msgs); exp != act {
+		t.Fatalf("Wrong count of peeked messages: %v != %v", exp, act)
+	}
+	for i, msg := range msgs {
+		if exp, act := fmt.Sprintf("test%v", i), string(msg.Get(0)); exp != act {
+			t.Errorf("Wrong peeked message: %v != %v", exp, act)
+		}
+	}
+
+	// Read and shift messages so that the next writes loop back to index 0.
+	for i := 0; i < 4; i++ {
+		if _, err = block.NextMessage(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = block.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 5; i < 8; i++ {
+		if _, err = block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("test%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if msgs, err = block.PeekMessages(10); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 4, len(msgs); exp != act {
+		t.Fatalf("Wrong count of peeked messages: %v != %v", exp, act)
+	}
+	for i, msg := range msgs {
+		if exp, act := fmt.Sprintf("test%v", i+4), string(msg.Get(0)); exp != act {
+			t.Errorf("Wrong peeked message: %v != %v", exp, act)
+		}
+	}
+
+	// The message currently being read should survive a purge.
+	var msg types.Message
+	if msg, err = block.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "test4", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", exp, act)
+	}
+
+	var purged int
+	if purged, err = block.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 3, purged; exp != act {
+		t.Errorf("Wrong count of purged messages: %v != %v", exp, act)
+	}
+	if exp, act := 1, block.Stats().Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", exp, act)
+	}
+
+	if _, err = block.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := (Stats{}), block.Stats(); exp != act {
+		t.Errorf("Wrong stats: %v != %v", exp, act)
+	}
+
+	if _, err = block.PushMessage(types.NewMessage(
+		[][]byte{[]byte("test8")},
+	)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err = block.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "test8", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", exp, act)
+	}
+	if _, err = block.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.PushMessage(types.NewMessage(
+		[][]byte{[]byte("test9")},
+	)); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err = block.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, purged; exp != act {
+		t.Errorf("Wrong count of purged messages: %v != %v", exp, act)
+	}
+	if exp, act := 0, block.Stats().Bytes; exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+}
+
+func TestMemoryDropNewest(t *testing.T) {
+	// Each message is 17 bytes including headers.
+	block := NewMemory(MemoryConfig{
+		Limit:    60,
+		Overflow: OverflowDropNewest,
+	}, metrics.DudType{})
+
+	for i := 0; i < 5; i++ {
+		if _, err := block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("test%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exp, act := 3, block.Stats().Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", exp, act)
+	}
+	for i := 0; i < 3; i++ {
+		m, err := block.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := fmt.Sprintf("test%v", i), string(m.Get(0)); exp != act {
+			t.Errorf("Wrong message: %v != %v", exp, act)
+		}
+		if _, err = block.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMemoryDropOldest(t *testing.T) {
+	// Each message is 17 bytes including headers.
+	block := NewMemory(MemoryConfig{
+		Limit:    60,
+		Overflow: OverflowDropOldest,
+	}, metrics.DudType{})
+
+	for i := 0; i < 2; i++ {
+		if _, err := block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("test%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Read the oldest message, which is dropped while it is being read.
+	m, err := block.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "test0", string(m.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", exp, act)
+	}
+
+	for i := 2; i < 6; i++ {
+		if _, err = block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("test%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = block.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remaining messages should be the newest that fit within the limit.
+	count := block.Stats().Count
+	if count < 2 {
+		t.Fatalf("Wrong message count: %v", count)
+	}
+	for i := 6 - count; i < 6; i++ {
+		if m, err = block.NextMessage(); err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := fmt.Sprintf("test%v", i), string(m.Get(0)); exp != act {
+			t.Errorf("Wrong message: %v != %v", exp, act)
+		}
+		if _, err = block.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := 0, block.Stats().Bytes; exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+}
+
+func TestMemoryDropOldestInFlight(t *testing.T) {
+	// Each message is 17 bytes including headers.
+	block := NewMemory(MemoryConfig{
+		Limit:    40,
+		Overflow: OverflowDropOldest,
+	}, metrics.DudType{})
+
+	if _, err := block.PushMessage(types.NewMessage(
+		[][]byte{[]byte("test0")},
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := block.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Push enough messages to drop and overwrite the message in flight.
+	for i := 1; i < 6; i++ {
+		if _, err = block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("next%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exp, act := "test0", string(m.Get(0)); exp != act {
+		t.Errorf("Message in flight was overwritten: %v != %v", act, exp)
+	}
+	if _, err = block.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err = block.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "next5", string(m.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+}
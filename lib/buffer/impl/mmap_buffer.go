@@ -53,6 +53,16 @@ type MmapBuffer struct {
 	writtenTo  int
 	writeIndex int
 
+	// pending indicates that the oldest message has been read but not yet
+	// shifted.
+	pending bool
+	tracker pushTracker
+
+	// untracked is the number of messages stored before the buffer was opened,
+	// which are the oldest messages and are not recorded by the tracker.
+	untracked int
+	openedAt  time.Time
+
 	closed bool
 }
 
@@ -75,6 +85,7 @@ func NewMmapBuffer(config MmapBufferConfig, log log.Modular, stats metrics.Type)
 		writtenTo:  0,
 		writeIndex: 0,
 		closed:     false,
+		openedAt:   time.Now(),
 	}
 
 	f.readTracker()
@@ -89,6 +100,8 @@ func NewMmapBuffer(config MmapBufferConfig, log log.Modular, stats metrics.Type)
 		log.Errorf("MMAP index write: %v, benthos will block writes until this is resolved.\n", err)
 	}
 
+	f.untracked = f.countBacklog()
+
 	go f.cacheManagerLoop(&f.writeIndex)
 	go f.cacheManagerLoop(&f.readIndex)
 
@@ -122,6 +135,39 @@ func (f *MmapBuffer) writeTracker() {
 	}
 }
 
+// countBacklog walks the stored messages between the read and write positions
+// and returns their count. Files that are not already cached are only mapped
+// for the duration of the count.
+func (f *MmapBuffer) countBacklog() int {
+	count := 0
+	for i := f.readIndex; i <= f.writeIndex; i++ {
+		wasCached := f.cache.IsCached(i)
+		if err := f.cache.EnsureCached(i); err != nil {
+			f.logger.Errorf("Failed to count stored messages of mmap file for index %v: %v\n", i, err)
+			break
+		}
+		block := f.cache.Get(i)
+
+		index := 0
+		if i == f.readIndex {
+			index = f.readFrom
+		}
+		for !(i == f.writeIndex && index >= f.writtenTo) && index+4 <= len(block) {
+			msgSize := readMessageSize(block, index)
+			if msgSize <= 0 {
+				break
+			}
+			count++
+			index = index + msgSize + 4
+		}
+
+		if !wasCached && i != f.readIndex && i != f.writeIndex {
+			f.cache.Remove(i)
+		}
+	}
+	return count
+}
+
 //------------------------------------------------------------------------------
 
 // cacheManagerLoop continuously checks whether the cache contains maps of our
@@ -167,6 +213,11 @@ func (f *MmapBuffer) cacheManagerLoop(indexPtr *int) {
 
 //------------------------------------------------------------------------------
 
+// empty returns whether the reader has caught up with the writer.
+func (f *MmapBuffer) empty() bool {
+	return f.writeIndex == f.readIndex && f.readFrom == f.writtenTo
+}
+
 // backlog reads the current backlog of messages stored.
 func (f *MmapBuffer) backlog() int {
 	// NOTE: For speed, the following calculation assumes that all mmap files
@@ -212,6 +263,17 @@ func (f *MmapBuffer) ShiftMessage() (int, error) {
 		f.cache.L.Unlock()
 	}()
 
+	// The buffer may have been purged since the message was read.
+	if f.empty() {
+		return 0, nil
+	}
+	f.pending = false
+	if f.untracked > 0 {
+		f.untracked--
+	} else {
+		f.tracker.shift()
+	}
+
 	if !f.closed && f.cache.IsCached(f.readIndex) {
 		msgSize := readMessageSize(f.cache.Get(f.readIndex), f.readFrom)
 		f.readFrom = f.readFrom + int(msgSize) + 4
@@ -294,6 +356,7 @@ func (f *MmapBuffer) NextMessage() (types.Message, error) {
 		return nil, types.ErrBlockCorrupted
 	}
 
+	f.pending = true
 	return types.FromBytes(block[index : index+int(msgSize)])
 }
 
@@ -367,8 +430,118 @@ func (f *MmapBuffer) PushMessage(msg types.Message) (int, error) {
 
 	// Move writtenTo ahead.
 	f.writtenTo = (index + len(blob) + 4)
+	f.tracker.push()
 
 	return f.backlog(), nil
 }
 
 //------------------------------------------------------------------------------
+
+// Stats returns a snapshot of the current contents of the buffer. The push
+// times of messages stored before the buffer was opened are unknown, and while
+// any remain the oldest message is reported as the time the buffer was opened.
+func (f *MmapBuffer) Stats() Stats {
+	f.cache.L.Lock()
+	defer f.cache.L.Unlock()
+
+	oldest := f.tracker.oldest()
+	if f.untracked > 0 {
+		oldest = f.openedAt
+	}
+	return Stats{
+		Bytes:  f.backlog(),
+		Count:  f.untracked + f.tracker.count(),
+		Oldest: oldest,
+	}
+}
+
+// PeekMessages returns up to n of the oldest messages without removing them.
+// Only messages within files that are currently cached are returned.
+func (f *MmapBuffer) PeekMessages(n int) ([]types.Message, error) {
+	f.cache.L.Lock()
+	defer f.cache.L.Unlock()
+
+	msgs := []types.Message{}
+	if f.closed {
+		return msgs, types.ErrTypeClosed
+	}
+
+	fileIndex, index := f.readIndex, f.readFrom
+	block := f.cache.Get(fileIndex)
+
+	for len(msgs) < n && !(fileIndex == f.writeIndex && index == f.writtenTo) {
+		msgSize := readMessageSize(block, index)
+		if msgSize <= 0 {
+			if fileIndex++; !f.cache.IsCached(fileIndex) {
+				break
+			}
+			block = f.cache.Get(fileIndex)
+			index = 0
+			continue
+		}
+		if index+4+msgSize > len(block) {
+			return msgs, types.ErrBlockCorrupted
+		}
+
+		msg, err := types.FromBytes(block[index+4 : index+4+msgSize])
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg.DeepCopy())
+		index = index + msgSize + 4
+	}
+
+	return msgs, nil
+}
+
+// Purge removes all stored messages and returns the number removed. If the
+// oldest message has been read and not yet shifted then it is preserved.
+func (f *MmapBuffer) Purge() (int, error) {
+	f.cache.L.Lock()
+	defer func() {
+		f.writeTracker()
+		f.cache.Broadcast()
+		f.cache.L.Unlock()
+	}()
+
+	if f.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	count := f.untracked + f.tracker.count()
+	if f.pending && !f.empty() {
+		// Files after the read index are left in place as the writer will
+		// overwrite them as it moves forward again.
+		msgSize := readMessageSize(f.cache.Get(f.readIndex), f.readFrom)
+		f.writeIndex = f.readIndex
+		f.writtenTo = f.readFrom + msgSize + 4
+		if f.untracked > 0 {
+			f.untracked = 1
+			f.tracker.truncate(0)
+		} else {
+			f.tracker.truncate(1)
+		}
+	} else {
+		if f.config.CleanUp && f.readIndex < f.writeIndex {
+			// The delete is done asynchronously as it has no impact on the
+			// reader or writer.
+			go func(from, to int) {
+				f.cache.L.Lock()
+				defer f.cache.L.Unlock()
+
+				for i := from; i < to; i++ {
+					f.cache.Remove(i)
+					f.cache.Delete(i)
+				}
+			}(f.readIndex, f.writeIndex)
+		}
+		f.readIndex = f.writeIndex
+		f.readFrom = f.writtenTo
+		f.untracked = 0
+		f.tracker.truncate(0)
+	}
+
+	return count - f.untracked - f.tracker.count(), nil
+}
+
+//------------------------------------------------------------------------------
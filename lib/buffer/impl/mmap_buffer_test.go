@@ -26,6 +26,7 @@ import (
 	"math/rand"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/service/log"
@@ -343,6 +344,98 @@ func TestMmapBufferRecoverFiles(t *testing.T) {
 	block.Close()
 }
 
+func TestMmapBufferReopenStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUpMmapDir(dir)
+
+	conf := NewMmapBufferConfig()
+	conf.FileSize = 1000
+	conf.Path = dir
+
+	block, err := NewMmapBuffer(conf, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Spread the backlog across multiple files.
+	for i := 0; i < 25; i++ {
+		msg := types.NewMessage([][]byte{make([]byte, 88)})
+		copy(msg.Get(0), fmt.Sprintf("old%v", i))
+		if _, err = block.PushMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if _, err = block.NextMessage(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = block.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block.Close()
+
+	if block, err = NewMmapBuffer(conf, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err != nil {
+		t.Fatal(err)
+	}
+	defer block.Close()
+
+	if exp, act := 20, block.Stats().Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", act, exp)
+	}
+	if block.Stats().Oldest.IsZero() {
+		t.Error("Oldest message time not set")
+	}
+
+	reopened := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err = block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("new%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := 23, block.Stats().Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", act, exp)
+	}
+
+	for i := 5; i < 25; i++ {
+		msg, err := block.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := fmt.Sprintf("old%v", i)
+		if act := string(msg.Get(0)[:len(exp)]); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		if _, err = block.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := block.Stats()
+	if exp, act := 3, stats.Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", act, exp)
+	}
+	if stats.Oldest.Before(reopened) {
+		t.Errorf("Oldest message predates new messages: %v < %v", stats.Oldest, reopened)
+	}
+
+	var purged int
+	if purged, err = block.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 3, purged; exp != act {
+		t.Errorf("Wrong count of purged messages: %v != %v", act, exp)
+	}
+	if exp, act := 0, block.Stats().Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", act, exp)
+	}
+}
+
 func TestMmapBufferRejectLargeMessage(t *testing.T) {
 	dir, err := ioutil.TempDir("", "benthos_test_")
 	if err != nil {
@@ -424,3 +517,102 @@ func BenchmarkMmapBufferBasic(b *testing.B) {
 		}
 	}
 }
+
+func TestMmapBufferInspect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUpMmapDir(dir)
+
+	conf := NewMmapBufferConfig()
+	conf.FileSize = 1000
+	conf.Path = dir
+
+	block, err := NewMmapBuffer(conf, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer block.Close()
+
+	// Each message is 100 bytes including headers, which spreads the messages
+	// across multiple files.
+	for i := 0; i < 20; i++ {
+		msg := types.NewMessage([][]byte{make([]byte, 88)})
+		copy(msg.Get(0), fmt.Sprintf("test%v", i))
+		if _, err = block.PushMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := block.Stats()
+	if exp, act := 20, stats.Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", exp, act)
+	}
+	if stats.Oldest.IsZero() {
+		t.Error("Oldest message time not set")
+	}
+
+	msgs, err := block.PeekMessages(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 5, len(msgs); exp != act {
+		t.Fatalf("Wrong count of peeked messages: %v != %v", exp, act)
+	}
+	for i, msg := range msgs {
+		exp := fmt.Sprintf("test%v", i)
+		if act := string(msg.Get(0)[:len(exp)]); exp != act {
+			t.Errorf("Wrong peeked message: %v != %v", exp, act)
+		}
+	}
+
+	// The message currently being read should survive a purge.
+	var msg types.Message
+	if msg, err = block.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	var purged int
+	if purged, err = block.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 19, purged; exp != act {
+		t.Errorf("Wrong count of purged messages: %v != %v", exp, act)
+	}
+	if exp, act := 1, block.Stats().Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", exp, act)
+	}
+	if _, err = block.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 0, block.Stats().Bytes; exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err = block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("foo%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if msg, err = block.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo0", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", exp, act)
+	}
+	if _, err = block.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	if purged, err = block.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, purged; exp != act {
+		t.Errorf("Wrong count of purged messages: %v != %v", exp, act)
+	}
+	if exp, act := 0, block.Stats().Bytes; exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package impl
+
+import (
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// Dequeue orderings of the priority buffer.
+const (
+	// PriorityStrict always reads from the highest priority queue that has
+	// messages.
+	PriorityStrict = "strict"
+
+	// PriorityWeighted reads from each queue in proportion to its weight.
+	PriorityWeighted = "weighted"
+)
+
+// PriorityQueue contains the limit in bytes and the dequeue weight of a single
+// queue within a priority buffer.
+type PriorityQueue struct {
+	Limit  int
+	Weight int
+}
+
+// Priority is a buffer consisting of multiple memory based queues ordered
+// from the highest priority to the lowest. Each message is written to the
+// queue chosen by a selector function, and messages are read from the queues
+// according to either a strict or weighted-fair ordering.
+type Priority struct {
+	queues   []*Memory
+	weights  []int
+	credits  []int
+	strict   bool
+	selector func(msg types.Message) int
+
+	// reading is the index of the queue that the message currently being read
+	// came from, or -1 if no message is being read.
+	reading int
+
+	closed bool
+
+	cond *sync.Cond
+}
+
+// NewPriority creates a new priority buffer. The selector function returns
+// the index of the queue that a message should be written to, where an
+// index out of bounds results in the lowest priority queue being chosen.
+func NewPriority(
+	ordering string,
+	queues []PriorityQueue,
+	selector func(msg types.Message) int,
+	stats metrics.Type,
+) (*Priority, error) {
+	var strict bool
+	switch ordering {
+	case PriorityStrict:
+		strict = true
+	case PriorityWeighted:
+	default:
+		return nil, types.ErrInvalidPriorityOrdering
+	}
+	if len(queues) == 0 {
+		return nil, types.ErrNoPriorityQueues
+	}
+
+	p := &Priority{
+		strict:   strict,
+		selector: selector,
+		reading:  -1,
+		cond:     sync.NewCond(&sync.Mutex{}),
+	}
+	for _, q := range queues {
+		memConf := NewMemoryConfig()
+		memConf.Limit = q.Limit
+
+		weight := q.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+
+		p.queues = append(p.queues, NewMemory(memConf, stats))
+		p.weights = append(p.weights, weight)
+		p.credits = append(p.credits, weight)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// backlog returns the combined backlog of all queues.
+func (p *Priority) backlog() int {
+	total := 0
+	for _, q := range p.queues {
+		total += q.Stats().Bytes
+	}
+	return total
+}
+
+// nextQueue returns the index of the queue that should be read from next, or
+// -1 if all queues are empty.
+func (p *Priority) nextQueue() int {
+	if p.reading >= 0 {
+		return p.reading
+	}
+
+	first := -1
+	for i, q := range p.queues {
+		if q.Stats().Bytes == 0 {
+			continue
+		}
+		if p.strict || p.credits[i] > 0 {
+			return i
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+
+	// Every queue with messages has spent its credits, therefore begin a new
+	// round.
+	if first >= 0 {
+		copy(p.credits, p.weights)
+	}
+	return first
+}
+
+// CloseOnceEmpty closes the buffer once the backlog reaches 0.
+func (p *Priority) CloseOnceEmpty() {
+	p.cond.L.Lock()
+	for p.backlog() > 0 && !p.closed {
+		p.cond.Wait()
+	}
+	p.cond.L.Unlock()
+	p.Close()
+}
+
+// Close unblocks any blocked calls and closes all queues.
+func (p *Priority) Close() {
+	p.cond.L.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.cond.L.Unlock()
+
+	for _, q := range p.queues {
+		q.Close()
+	}
+}
+
+// ShiftMessage removes the message currently being read. Returns the backlog
+// count.
+func (p *Priority) ShiftMessage() (int, error) {
+	p.cond.L.Lock()
+	defer func() {
+		p.cond.Broadcast()
+		p.cond.L.Unlock()
+	}()
+
+	if p.reading < 0 {
+		return p.backlog(), nil
+	}
+
+	i := p.reading
+	p.reading = -1
+	if p.credits[i] > 0 {
+		p.credits[i]--
+	}
+
+	_, err := p.queues[i].ShiftMessage()
+	return p.backlog(), err
+}
+
+// NextMessage reads the next message according to the dequeue ordering, this
+// call blocks until there's something to read.
+func (p *Priority) NextMessage() (types.Message, error) {
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+
+	for !p.closed {
+		if i := p.nextQueue(); i >= 0 {
+			p.reading = i
+			return p.queues[i].NextMessage()
+		}
+		p.cond.Wait()
+	}
+	return nil, types.ErrTypeClosed
+}
+
+// PushMessage writes a new message to the queue chosen by the selector,
+// returns the backlog count.
+func (p *Priority) PushMessage(msg types.Message) (int, error) {
+	i := p.selector(msg)
+	if i < 0 || i >= len(p.queues) {
+		i = len(p.queues) - 1
+	}
+	q := p.queues[i]
+
+	if len(msg.Bytes())+4 > q.config.Limit {
+		return 0, types.ErrMessageTooLarge
+	}
+
+	p.cond.L.Lock()
+	defer func() {
+		p.cond.Broadcast()
+		p.cond.L.Unlock()
+	}()
+
+	// Queues are written to without blocking so that readers of other queues
+	// are able to continue whilst this queue is full.
+	for !p.closed {
+		_, written, err := q.tryPushMessage(msg)
+		if err != nil {
+			return 0, err
+		}
+		if written {
+			return p.backlog(), nil
+		}
+		p.cond.Wait()
+	}
+	return 0, types.ErrTypeClosed
+}
+
+//------------------------------------------------------------------------------
+
+// Stats returns a snapshot of the current contents of all queues.
+func (p *Priority) Stats() Stats {
+	stats := Stats{}
+	for _, q := range p.queues {
+		qStats := q.Stats()
+		stats.Bytes += qStats.Bytes
+		stats.Count += qStats.Count
+		if !qStats.Oldest.IsZero() &&
+			(stats.Oldest.IsZero() || qStats.Oldest.Before(stats.Oldest)) {
+			stats.Oldest = qStats.Oldest
+		}
+	}
+	return stats
+}
+
+// PeekMessages returns up to n of the oldest messages without removing them,
+// starting with the highest priority queue.
+func (p *Priority) PeekMessages(n int) ([]types.Message, error) {
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+
+	msgs := []types.Message{}
+	for _, q := range p.queues {
+		if len(msgs) >= n {
+			break
+		}
+		qMsgs, err := q.PeekMessages(n - len(msgs))
+		msgs = append(msgs, qMsgs...)
+		if err != nil {
+			return msgs, err
+		}
+	}
+	return msgs, nil
+}
+
+// Purge removes all stored messages from every queue and returns the number
+// removed.
+func (p *Priority) Purge() (int, error) {
+	p.cond.L.Lock()
+	defer func() {
+		p.cond.Broadcast()
+		p.cond.L.Unlock()
+	}()
+
+	total := 0
+	for _, q := range p.queues {
+		purged, err := q.Purge()
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+//------------------------------------------------------------------------------
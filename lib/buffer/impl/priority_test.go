@@ -0,0 +1,236 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package impl
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+// prefixSelector selects the queue of a message by the prefix of its first
+// part.
+func prefixSelector(msg types.Message) int {
+	switch {
+	case strings.HasPrefix(string(msg.Get(0)), "alert"):
+		return 0
+	case strings.HasPrefix(string(msg.Get(0)), "warn"):
+		return 1
+	}
+	return -1
+}
+
+func pushAll(t *testing.T, b Buffer, contents ...string) {
+	for _, c := range contents {
+		if _, err := b.PushMessage(types.NewMessage([][]byte{[]byte(c)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readAll(t *testing.T, b Buffer, n int) []string {
+	res := []string{}
+	for i := 0; i < n; i++ {
+		msg, err := b.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, string(msg.Get(0)))
+		if _, err = b.ShiftMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res
+}
+
+func TestPriorityInterface(t *testing.T) {
+	b := &Priority{}
+	if c := Inspectable(b); c == nil {
+		t.Error("Priority does not satisfy the Inspectable interface")
+	}
+}
+
+func TestPriorityBadConfig(t *testing.T) {
+	queues := []PriorityQueue{{Limit: 1000, Weight: 1}}
+	if _, err := NewPriority("nope", queues, prefixSelector, metrics.DudType{}); err != types.ErrInvalidPriorityOrdering {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+	if _, err := NewPriority(PriorityStrict, nil, prefixSelector, metrics.DudType{}); err != types.ErrNoPriorityQueues {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+}
+
+func TestPriorityStrict(t *testing.T) {
+	b, err := NewPriority(PriorityStrict, []PriorityQueue{
+		{Limit: 1000, Weight: 1},
+		{Limit: 1000, Weight: 1},
+		{Limit: 1000, Weight: 1},
+	}, prefixSelector, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	pushAll(t, b, "bulk1", "bulk2", "warn1", "alert1", "bulk3", "alert2", "warn2")
+
+	if exp, act := 7, b.Stats().Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", exp, act)
+	}
+
+	exp := []string{"alert1", "alert2", "warn1", "warn2", "bulk1", "bulk2", "bulk3"}
+	if act := readAll(t, b, len(exp)); strings.Join(exp, ",") != strings.Join(act, ",") {
+		t.Errorf("Wrong order of messages: %v != %v", exp, act)
+	}
+}
+
+func TestPriorityPendingMessage(t *testing.T) {
+	b, err := NewPriority(PriorityStrict, []PriorityQueue{
+		{Limit: 1000, Weight: 1},
+		{Limit: 1000, Weight: 1},
+	}, prefixSelector, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	pushAll(t, b, "bulk1")
+	if _, err = b.NextMessage(); err != nil {
+		t.Fatal(err)
+	}
+	pushAll(t, b, "alert1")
+
+	// Reading again before a shift must return the same message.
+	msg, err := b.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "bulk1", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", exp, act)
+	}
+	if _, err = b.ShiftMessage(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"alert1"}
+	if act := readAll(t, b, 1); strings.Join(exp, ",") != strings.Join(act, ",") {
+		t.Errorf("Wrong order of messages: %v != %v", exp, act)
+	}
+}
+
+func TestPriorityWeighted(t *testing.T) {
+	b, err := NewPriority(PriorityWeighted, []PriorityQueue{
+		{Limit: 1000, Weight: 3},
+		{Limit: 1000, Weight: 1},
+		{Limit: 1000, Weight: 1},
+	}, prefixSelector, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	pushAll(t, b,
+		"bulk1", "bulk2", "bulk3",
+		"alert1", "alert2", "alert3", "alert4", "alert5",
+		"warn1",
+	)
+
+	exp := []string{
+		"alert1", "alert2", "alert3", "warn1", "bulk1",
+		"alert4", "alert5", "bulk2", "bulk3",
+	}
+	if act := readAll(t, b, len(exp)); strings.Join(exp, ",") != strings.Join(act, ",") {
+		t.Errorf("Wrong order of messages: %v != %v", exp, act)
+	}
+}
+
+func TestPriorityFullQueue(t *testing.T) {
+	b, err := NewPriority(PriorityStrict, []PriorityQueue{
+		{Limit: 1000, Weight: 1},
+		{Limit: 60, Weight: 1},
+	}, prefixSelector, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if _, err = b.PushMessage(types.NewMessage([][]byte{make([]byte, 100)})); err != types.ErrMessageTooLarge {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+
+	pushAll(t, b, "bulk1-long-message")
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pushAll(t, b, "bulk2", "bulk3")
+	}()
+
+	<-time.After(time.Millisecond * 50)
+
+	// A full queue must not prevent other queues from being written to.
+	pushAll(t, b, "alert1")
+
+	exp := []string{"alert1", "bulk1-long-message"}
+	if act := readAll(t, b, len(exp)); strings.Join(exp, ",") != strings.Join(act, ",") {
+		t.Errorf("Wrong order of messages: %v != %v", exp, act)
+	}
+
+	wg.Wait()
+
+	exp = []string{"bulk2", "bulk3"}
+	if act := readAll(t, b, len(exp)); strings.Join(exp, ",") != strings.Join(act, ",") {
+		t.Errorf("Wrong order of messages: %v != %v", exp, act)
+	}
+}
+
+func TestPriorityCloseOnceEmpty(t *testing.T) {
+	b, err := NewPriority(PriorityStrict, []PriorityQueue{
+		{Limit: 1000, Weight: 1},
+		{Limit: 1000, Weight: 1},
+	}, prefixSelector, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushAll(t, b, "bulk1", "alert1")
+
+	closed := make(chan struct{})
+	go func() {
+		b.CloseOnceEmpty()
+		close(closed)
+	}()
+
+	readAll(t, b, 2)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for close")
+	}
+
+	if _, err = b.NextMessage(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package impl
+
+import "time"
+
+//------------------------------------------------------------------------------
+
+// pushTracker records the push times of messages stored within a buffer in
+// the order that they were written, which allows buffers to report message
+// counts and ages without altering their storage format.
+type pushTracker struct {
+	times []time.Time
+}
+
+// push records a newly written message.
+func (p *pushTracker) push() {
+	p.times = append(p.times, time.Now())
+}
+
+// shift removes the record of the oldest message.
+func (p *pushTracker) shift() {
+	if len(p.times) > 0 {
+		p.times[0] = time.Time{}
+		p.times = p.times[1:]
+	}
+}
+
+// truncate removes all records except for the oldest n.
+func (p *pushTracker) truncate(n int) {
+	if n < len(p.times) {
+		p.times = append([]time.Time(nil), p.times[:n]...)
+	}
+}
+
+// count returns the number of tracked messages.
+func (p *pushTracker) count() int {
+	return len(p.times)
+}
+
+// oldest returns the push time of the oldest tracked message, or a zero time
+// if there are none.
+func (p *pushTracker) oldest() time.Time {
+	if len(p.times) == 0 {
+		return time.Time{}
+	}
+	return p.times[0]
+}
+
+//------------------------------------------------------------------------------
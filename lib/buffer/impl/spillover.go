@@ -0,0 +1,217 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package impl
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// Spillover is a buffer that stores messages in memory until it is full, at
+// which point new messages are written to a memory mapped file buffer until
+// the backlog is cleared. Messages are always read in the order they were
+// written.
+type Spillover struct {
+	mem  *Memory
+	disk *MmapBuffer
+
+	stats metrics.Type
+
+	// readDisk indicates that the message being read came from disk.
+	readDisk bool
+
+	closed bool
+
+	cond *sync.Cond
+}
+
+// NewSpillover creates a new memory based buffer that spills over to disk.
+func NewSpillover(config MemoryConfig, log log.Modular, stats metrics.Type) (*Spillover, error) {
+	if len(config.Spill.Path) == 0 {
+		return nil, errors.New("a spill directory must be specified")
+	}
+
+	disk, err := NewMmapBuffer(config.Spill, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("spill: %v", err)
+	}
+
+	return &Spillover{
+		mem:   NewMemory(config, stats),
+		disk:  disk,
+		stats: stats,
+		cond:  sync.NewCond(&sync.Mutex{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// backlog returns the combined backlog of the memory and disk buffers.
+func (s *Spillover) backlog() int {
+	return s.mem.Stats().Bytes + s.disk.Stats().Bytes
+}
+
+// CloseOnceEmpty closes the buffer once the backlog reaches 0.
+func (s *Spillover) CloseOnceEmpty() {
+	s.cond.L.Lock()
+	for s.backlog() > 0 && !s.closed {
+		s.cond.Wait()
+	}
+	s.cond.L.Unlock()
+	s.Close()
+}
+
+// Close unblocks any blocked calls and closes both underlying buffers.
+func (s *Spillover) Close() {
+	s.cond.L.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.cond.L.Unlock()
+
+	s.mem.Close()
+	s.disk.Close()
+}
+
+// ShiftMessage removes the oldest message. Returns the backlog count.
+func (s *Spillover) ShiftMessage() (int, error) {
+	s.cond.L.Lock()
+	defer func() {
+		s.cond.Broadcast()
+		s.cond.L.Unlock()
+	}()
+
+	var err error
+	if s.readDisk {
+		_, err = s.disk.ShiftMessage()
+	} else {
+		_, err = s.mem.ShiftMessage()
+	}
+	return s.backlog(), err
+}
+
+// NextMessage reads the oldest message, this call blocks until there's
+// something to read.
+func (s *Spillover) NextMessage() (types.Message, error) {
+	s.cond.L.Lock()
+	defer s.cond.L.Unlock()
+
+	for !s.closed {
+		// Messages in memory are always older than those on disk.
+		if s.mem.Stats().Bytes > 0 {
+			s.readDisk = false
+			return s.mem.NextMessage()
+		}
+		if s.disk.Stats().Bytes > 0 {
+			s.readDisk = true
+			return s.disk.NextMessage()
+		}
+		s.cond.Wait()
+	}
+	return nil, types.ErrTypeClosed
+}
+
+// PushMessage pushes a new message, returns the backlog count.
+func (s *Spillover) PushMessage(msg types.Message) (int, error) {
+	s.cond.L.Lock()
+	defer func() {
+		s.cond.Broadcast()
+		s.cond.L.Unlock()
+	}()
+
+	if s.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	// In order to preserve ordering new messages may only be written to memory
+	// whilst the disk buffer is empty.
+	if s.disk.Stats().Bytes == 0 {
+		_, written, err := s.mem.tryPushMessage(msg)
+		if err != nil {
+			return 0, err
+		}
+		if written {
+			return s.backlog(), nil
+		}
+	}
+
+	if _, err := s.disk.PushMessage(msg); err != nil {
+		return 0, err
+	}
+	s.stats.Incr("buffer.overflow.spilled", 1)
+	return s.backlog(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// Stats returns a snapshot of the current contents of the buffer.
+func (s *Spillover) Stats() Stats {
+	memStats, diskStats := s.mem.Stats(), s.disk.Stats()
+
+	stats := Stats{
+		Bytes:  memStats.Bytes + diskStats.Bytes,
+		Count:  memStats.Count + diskStats.Count,
+		Oldest: memStats.Oldest,
+	}
+	if stats.Oldest.IsZero() {
+		stats.Oldest = diskStats.Oldest
+	}
+	return stats
+}
+
+// PeekMessages returns up to n of the oldest messages without removing them.
+func (s *Spillover) PeekMessages(n int) ([]types.Message, error) {
+	s.cond.L.Lock()
+	defer s.cond.L.Unlock()
+
+	msgs, err := s.mem.PeekMessages(n)
+	if err != nil || len(msgs) >= n {
+		return msgs, err
+	}
+
+	diskMsgs, err := s.disk.PeekMessages(n - len(msgs))
+	return append(msgs, diskMsgs...), err
+}
+
+// Purge removes all stored messages from both memory and disk and returns the
+// number removed.
+func (s *Spillover) Purge() (int, error) {
+	s.cond.L.Lock()
+	defer func() {
+		s.cond.Broadcast()
+		s.cond.L.Unlock()
+	}()
+
+	memPurged, err := s.mem.Purge()
+	if err != nil {
+		return memPurged, err
+	}
+	diskPurged, err := s.disk.Purge()
+	return memPurged + diskPurged, err
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,109 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package impl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+func TestSpilloverInterface(t *testing.T) {
+	b := &Spillover{}
+	if c := Inspectable(b); c == nil {
+		t.Error("Spillover does not satisfy the Inspectable interface")
+	}
+}
+
+func TestSpilloverNoDirectory(t *testing.T) {
+	conf := NewMemoryConfig()
+	conf.Overflow = OverflowSpill
+
+	if _, err := NewSpillover(conf, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing spill directory")
+	}
+}
+
+func TestSpilloverOrdering(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUpMmapDir(dir)
+
+	conf := NewMemoryConfig()
+	conf.Limit = 100
+	conf.Overflow = OverflowSpill
+	conf.Spill.FileSize = 1000
+	conf.Spill.Path = dir
+
+	block, err := NewSpillover(conf, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer block.Close()
+
+	n := 200
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			m, rerr := block.NextMessage()
+			if rerr != nil {
+				t.Error(rerr)
+				return
+			}
+			if exp, act := fmt.Sprintf("test%v", i), string(m.Get(0)); exp != act {
+				t.Errorf("Wrong order of messages: %v != %v", exp, act)
+			}
+			if _, rerr = block.ShiftMessage(); rerr != nil {
+				t.Error(rerr)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		if _, err = block.PushMessage(types.NewMessage(
+			[][]byte{[]byte(fmt.Sprintf("test%v", i))},
+		)); err != nil {
+			t.Fatal(err)
+		}
+		if i == 50 {
+			if act := block.Stats().Count; act == 0 {
+				t.Error("Expected a backlog of messages")
+			}
+		}
+	}
+
+	wg.Wait()
+	if exp, act := 0, block.Stats().Bytes; exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package buffer
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/buffer/impl"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// RegisterEndpoints registers HTTP endpoints for inspecting and administering
+// the buffer with a manager. Nothing is registered if the underlying buffer
+// implementation cannot be inspected.
+func (m *OutputWrapper) RegisterEndpoints(mgr types.Manager) {
+	ib, ok := m.buffer.(impl.Inspectable)
+	if !ok {
+		return
+	}
+
+	mgr.RegisterEndpoint(
+		"/buffer/stats",
+		"Returns the current backlog in bytes, the number of messages and the"+
+			" age of the oldest message stored within the buffer.",
+		func(w http.ResponseWriter, r *http.Request) {
+			m.handleStats(ib, w, r)
+		},
+	)
+	mgr.RegisterEndpoint(
+		"/buffer/peek",
+		"Returns the next N messages of the buffer without removing them,"+
+			" where N is set with the query parameter `count` (default 10).",
+		func(w http.ResponseWriter, r *http.Request) {
+			m.handlePeek(ib, w, r)
+		},
+	)
+	mgr.RegisterEndpoint(
+		"/buffer/drain",
+		"POST to stop the buffer accepting new messages until the backlog is"+
+			" cleared.",
+		m.handleDrain,
+	)
+	mgr.RegisterEndpoint(
+		"/buffer/purge",
+		"POST to discard all messages currently stored within the buffer.",
+		func(w http.ResponseWriter, r *http.Request) {
+			m.handlePurge(ib, w, r)
+		},
+	)
+}
+
+//------------------------------------------------------------------------------
+
+type bufferStats struct {
+	Bytes     int    `json:"backlog_bytes"`
+	Count     int    `json:"message_count"`
+	OldestAge string `json:"oldest_age"`
+	Draining  bool   `json:"draining"`
+}
+
+func (m *OutputWrapper) getStats(ib impl.Inspectable) bufferStats {
+	s := ib.Stats()
+	age := "0s"
+	if !s.Oldest.IsZero() {
+		age = time.Since(s.Oldest).String()
+	}
+	return bufferStats{
+		Bytes:     s.Bytes,
+		Count:     s.Count,
+		OldestAge: age,
+		Draining:  m.Draining(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	resBytes, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusBadGateway)
+		return
+	}
+	w.Write(resBytes)
+}
+
+func (m *OutputWrapper) handleStats(ib impl.Inspectable, w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, m.getStats(ib))
+}
+
+func (m *OutputWrapper) handlePeek(ib impl.Inspectable, w http.ResponseWriter, r *http.Request) {
+	count := 10
+	if countStr := r.URL.Query().Get("count"); len(countStr) > 0 {
+		var err error
+		if count, err = strconv.Atoi(countStr); err != nil || count < 0 {
+			http.Error(w, "Query parameter `count` must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	msgs, err := ib.PeekMessages(count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	parts := make([][]string, len(msgs))
+	for i, msg := range msgs {
+		parts[i] = make([]string, msg.Len())
+		msg.Iter(func(j int, b []byte) {
+			parts[i][j] = string(b)
+		})
+	}
+	writeJSON(w, parts)
+}
+
+func (m *OutputWrapper) handleDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	m.Drain()
+	w.Write([]byte("OK"))
+}
+
+func (m *OutputWrapper) handlePurge(ib impl.Inspectable, w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n, err := ib.Purge()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	m.stats.Incr("buffer.purge.count", int64(n))
+	if ib.Stats().Bytes == 0 {
+		m.drained()
+	}
+
+	writeJSON(w, struct {
+		Purged int `json:"purged"`
+	}{
+		Purged: n,
+	})
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,165 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package buffer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestInspectEndpoints(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+
+	mgr := newMockManager()
+	buf, err := New(conf, mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err = buf.StartReceiving(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	sendMsg := func(content string) {
+		select {
+		case tChan <- types.NewTransaction(types.NewMessage([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	call := func(method, path string) (int, string) {
+		h, exists := mgr.endpoints[strings.Split(path, "?")[0]]
+		if !exists {
+			t.Fatalf("Endpoint %v not registered", path)
+		}
+		req := httptest.NewRequest(method, path, nil)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	sendMsg("foo")
+	sendMsg("bar")
+	sendMsg("baz")
+
+	if _, body := call("GET", "/buffer/peek?count=2"); body != `[["foo"],["bar"]]` {
+		t.Errorf("Wrong peek result: %v", body)
+	}
+
+	// Wait for the first message to be read by the output loop.
+	var tr types.Transaction
+	select {
+	case tr = <-buf.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	if code, _ := call("GET", "/buffer/purge"); code != http.StatusMethodNotAllowed {
+		t.Errorf("Wrong status code: %v != %v", code, http.StatusMethodNotAllowed)
+	}
+	if _, body := call("POST", "/buffer/purge"); body != `{"purged":2}` {
+		t.Errorf("Wrong purge result: %v", body)
+	}
+
+	var stats bufferStats
+	_, body := call("GET", "/buffer/stats")
+	if err = json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, stats.Count; exp != act {
+		t.Errorf("Wrong message count: %v != %v", exp, act)
+	}
+	if exp, act := 15, stats.Bytes; exp != act {
+		t.Errorf("Wrong backlog: %v != %v", exp, act)
+	}
+	if stats.Draining {
+		t.Error("Buffer should not be draining")
+	}
+
+	if code, _ := call("POST", "/buffer/drain"); code != http.StatusOK {
+		t.Errorf("Wrong status code: %v != %v", code, http.StatusOK)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(types.NewMessage([][]byte{[]byte("qux")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case <-resChan:
+		t.Fatal("Message accepted during drain")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	select {
+	case tr.ResponseChan <- types.NewSimpleResponse(nil):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case <-resChan:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case tr = <-buf.TransactionChan():
+		if exp, act := "qux", string(tr.Payload.Get(0)); exp != act {
+			t.Errorf("Wrong message: %v != %v", exp, act)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case tr.ResponseChan <- types.NewSimpleResponse(nil):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	buf.CloseAsync()
+	if err = buf.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
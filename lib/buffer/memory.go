@@ -22,6 +22,7 @@ package buffer
 
 import (
 	"github.com/Jeffail/benthos/lib/buffer/impl"
+	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/service/log"
 	"github.com/Jeffail/benthos/lib/util/service/metrics"
 )
@@ -34,15 +35,42 @@ func init() {
 		description: `
 The memory buffer type simply allocates a set amount of RAM for buffering
 messages. This protects the pipeline against backpressure until this buffer is
-full. The messages are lost if the service is stopped.`,
+full. The messages are lost if the service is stopped.
+
+The 'overflow' field determines what happens when the buffer is full. The
+default, 'block', applies back pressure until space is freed. The policies
+'drop_newest' and 'drop_oldest' instead discard either the incoming message or
+the oldest messages of the backlog. The policy 'spill' writes incoming messages
+to memory mapped files in the directory configured at 'spill.directory' until
+the backlog is cleared. Messages are always read in the order they were
+written.
+
+The contents of the buffer can be inspected at runtime via the HTTP endpoints
+'/buffer/stats' and '/buffer/peek'. A POST request to '/buffer/drain' stops new
+messages being accepted until the backlog is cleared, and a POST request to
+'/buffer/purge' discards all messages currently stored.`,
 	}
 }
 
 //------------------------------------------------------------------------------
 
 // NewMemory - Create a buffer held in memory.
-func NewMemory(config Config, log log.Modular, stats metrics.Type) (Type, error) {
-	return NewOutputWrapper(config, impl.NewMemory(config.Memory), stats), nil
+func NewMemory(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	var b impl.Buffer
+	switch config.Memory.Overflow {
+	case impl.OverflowBlock, impl.OverflowDropNewest, impl.OverflowDropOldest:
+		b = impl.NewMemory(config.Memory, stats)
+	case impl.OverflowSpill:
+		var err error
+		if b, err = impl.NewSpillover(config.Memory, log.NewModule(".buffer.memory"), stats); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, types.ErrInvalidOverflowPolicy
+	}
+	w := NewOutputWrapper(config, b, stats)
+	w.RegisterEndpoints(mgr)
+	return w, nil
 }
 
 //------------------------------------------------------------------------------
@@ -34,7 +34,7 @@ func TestMemoryBuffer(t *testing.T) {
 	conf := NewConfig()
 	conf.Type = "memory"
 
-	buf, err := New(conf, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	buf, err := New(conf, newMockManager(), log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
 	if err != nil {
 		t.Error(err)
 		return
@@ -22,6 +22,7 @@ package buffer
 
 import (
 	"github.com/Jeffail/benthos/lib/buffer/impl"
+	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/service/log"
 	"github.com/Jeffail/benthos/lib/util/service/metrics"
 )
@@ -41,7 +42,11 @@ directory as it fills them.
 
 When files are fully read from they will be deleted. You can disable this
 feature if you wish to preserve the data indefinitely, but the directory will
-fill up as fast as data passes through.`,
+fill up as fast as data passes through.
+
+The same inspection and administration endpoints as the memory buffer are
+available. Message counts and ages only account for messages written since the
+service was started.`,
 	}
 }
 
@@ -49,12 +54,14 @@ fill up as fast as data passes through.`,
 
 // NewMmapFile creates a buffer held in memory and persisted to file through
 // memory map.
-func NewMmapFile(config Config, log log.Modular, stats metrics.Type) (Type, error) {
+func NewMmapFile(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 	b, err := impl.NewMmapBuffer(config.Mmap, log.NewModule(".buffer.mmap_file"), stats)
 	if err != nil {
 		return nil, err
 	}
-	return NewOutputWrapper(config, b, stats), nil
+	w := NewOutputWrapper(config, b, stats)
+	w.RegisterEndpoints(mgr)
+	return w, nil
 }
 
 //------------------------------------------------------------------------------
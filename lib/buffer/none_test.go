@@ -33,7 +33,7 @@ import (
 //------------------------------------------------------------------------------
 
 func TestNoneBufferClose(t *testing.T) {
-	empty, err := NewEmpty(NewConfig(), nil, nil)
+	empty, err := NewEmpty(NewConfig(), nil, nil, nil)
 	if err != nil {
 		t.Error(err)
 		return
@@ -60,7 +60,7 @@ func TestNoneBufferBasic(t *testing.T) {
 	nThreads, nMessages := 5, 100
 
 	conf := NewConfig()
-	empty, err := NewEmpty(conf, nil, nil)
+	empty, err := NewEmpty(conf, nil, nil, nil)
 	if err != nil {
 		t.Error(err)
 		return
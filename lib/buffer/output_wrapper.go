@@ -45,6 +45,12 @@ type OutputWrapper struct {
 	responsesOut chan types.Response
 	errorsChan   chan []error
 
+	// drainChan is non-nil while the buffer is being drained, during which
+	// time no new messages are accepted. It is closed once the backlog is
+	// cleared.
+	drainChan chan struct{}
+	drainMut  sync.Mutex
+
 	closedWG sync.WaitGroup
 
 	closeChan  chan struct{}
@@ -56,7 +62,7 @@ func NewOutputWrapper(
 	conf Config,
 	buffer impl.Buffer,
 	stats metrics.Type,
-) Type {
+) *OutputWrapper {
 	m := OutputWrapper{
 		stats:        stats,
 		buffer:       buffer,
@@ -90,6 +96,18 @@ func (m *OutputWrapper) inputLoop() {
 		case <-m.closeChan:
 			return
 		}
+
+		// Hold the message back until any ongoing drain is complete.
+		m.drainMut.Lock()
+		drainChan := m.drainChan
+		m.drainMut.Unlock()
+		if drainChan != nil {
+			select {
+			case <-drainChan:
+			case <-m.closeChan:
+				return
+			}
+		}
 		backlog, err := m.buffer.PushMessage(tr.Payload)
 		if err == nil {
 			m.stats.Incr("buffer.write.count", 1)
@@ -158,6 +176,9 @@ func (m *OutputWrapper) outputLoop() {
 				backlog, _ := m.buffer.ShiftMessage()
 				m.stats.Incr("buffer.send.success", 1)
 				m.stats.Gauge("buffer.backlog", int64(backlog))
+				if backlog == 0 {
+					m.drained()
+				}
 			} else {
 				m.stats.Incr("buffer.send.error", 1)
 				if _, exists := errMap[res.Error()]; !exists {
@@ -180,6 +201,42 @@ func (m *OutputWrapper) outputLoop() {
 	}
 }
 
+// Drain stops the buffer from accepting new messages until the current backlog
+// has been cleared. Draining has no effect on buffers that cannot be inspected.
+func (m *OutputWrapper) Drain() {
+	ib, ok := m.buffer.(impl.Inspectable)
+	if !ok {
+		return
+	}
+
+	m.drainMut.Lock()
+	if m.drainChan == nil {
+		m.drainChan = make(chan struct{})
+	}
+	m.drainMut.Unlock()
+
+	if ib.Stats().Bytes == 0 {
+		m.drained()
+	}
+}
+
+// Draining returns whether the buffer is currently being drained.
+func (m *OutputWrapper) Draining() bool {
+	m.drainMut.Lock()
+	defer m.drainMut.Unlock()
+	return m.drainChan != nil
+}
+
+// drained ends a drain, if one is in progress, and resumes accepting messages.
+func (m *OutputWrapper) drained() {
+	m.drainMut.Lock()
+	if m.drainChan != nil {
+		close(m.drainChan)
+		m.drainChan = nil
+	}
+	m.drainMut.Unlock()
+}
+
 // StartReceiving assigns a messages channel for the output to read.
 func (m *OutputWrapper) StartReceiving(msgs <-chan types.Transaction) error {
 	if m.messagesIn != nil {
@@ -41,7 +41,7 @@ func TestBasicMemoryBuffer(t *testing.T) {
 	conf := NewConfig()
 	b := NewOutputWrapper(conf, impl.NewMemory(impl.MemoryConfig{
 		Limit: int(incr+15) * int(total),
-	}), metrics.DudType{})
+	}, metrics.DudType{}), metrics.DudType{})
 	if err := b.StartReceiving(tChan); err != nil {
 		t.Error(err)
 		return
@@ -215,7 +215,7 @@ func TestBufferClosing(t *testing.T) {
 	conf := NewConfig()
 	b := NewOutputWrapper(conf, impl.NewMemory(impl.MemoryConfig{
 		Limit: int(incr+15) * int(total),
-	}), metrics.DudType{})
+	}, metrics.DudType{}), metrics.DudType{})
 	if err := b.StartReceiving(tChan); err != nil {
 		t.Error(err)
 		return
@@ -283,7 +283,7 @@ func TestOutputWrapperErrProp(t *testing.T) {
 	resChan := make(chan types.Response)
 
 	conf := NewConfig()
-	b := NewOutputWrapper(conf, impl.NewMemory(impl.NewMemoryConfig()), metrics.DudType{})
+	b := NewOutputWrapper(conf, impl.NewMemory(impl.NewMemoryConfig(), metrics.DudType{}), metrics.DudType{})
 	if err := b.StartReceiving(tChan); err != nil {
 		t.Error(err)
 		return
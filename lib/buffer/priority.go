@@ -0,0 +1,174 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package buffer
+
+import (
+	"encoding/json"
+
+	"github.com/Jeffail/benthos/lib/buffer/impl"
+	"github.com/Jeffail/benthos/lib/processor/condition"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["priority"] = TypeSpec{
+		constructor: NewPriority,
+		description: `
+The priority buffer type maintains multiple memory based queues, allowing
+certain messages to bypass a backlog of others. Each queue has a condition, and
+each message is written to the first queue in the list where the condition
+passes. Messages that do not pass any condition are written to the fallback
+queue, which has the lowest priority. Messages within Benthos do not carry
+metadata, and therefore queues can only be selected by conditions on message
+contents.
+
+The 'ordering' field determines how messages are read from the queues. With
+'strict' ordering messages are always read from the highest priority queue that
+is not empty. With 'weighted' ordering each queue is read from in proportion to
+its 'weight', which prevents low priority queues from being starved entirely.
+
+Each queue has its own 'limit' in bytes. When a queue is full back pressure is
+applied to messages destined for that queue only.
+
+The same inspection and administration endpoints as the memory buffer are
+available.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// PriorityQueueConfig contains configuration fields for a single queue of a
+// priority buffer.
+type PriorityQueueConfig struct {
+	Condition condition.Config `json:"condition" yaml:"condition"`
+	Limit     int              `json:"limit" yaml:"limit"`
+	Weight    int              `json:"weight" yaml:"weight"`
+}
+
+// NewPriorityQueueConfig returns a PriorityQueueConfig with default values.
+func NewPriorityQueueConfig() PriorityQueueConfig {
+	return PriorityQueueConfig{
+		Condition: condition.NewConfig(),
+		Limit:     1024 * 1024 * 100, // 100MB
+		Weight:    1,
+	}
+}
+
+// UnmarshalJSON ensures that when parsing configs that are in a slice the
+// default values are still applied.
+func (p *PriorityQueueConfig) UnmarshalJSON(bytes []byte) error {
+	type confAlias PriorityQueueConfig
+	aliased := confAlias(NewPriorityQueueConfig())
+
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+
+	*p = PriorityQueueConfig(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a slice the
+// default values are still applied.
+func (p *PriorityQueueConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias PriorityQueueConfig
+	aliased := confAlias(NewPriorityQueueConfig())
+
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+
+	*p = PriorityQueueConfig(aliased)
+	return nil
+}
+
+// PriorityFallbackConfig contains configuration fields for the fallback queue
+// of a priority buffer.
+type PriorityFallbackConfig struct {
+	Limit  int `json:"limit" yaml:"limit"`
+	Weight int `json:"weight" yaml:"weight"`
+}
+
+// PriorityConfig contains configuration fields for the priority buffer type.
+type PriorityConfig struct {
+	Ordering string                 `json:"ordering" yaml:"ordering"`
+	Queues   []PriorityQueueConfig  `json:"queues" yaml:"queues"`
+	Fallback PriorityFallbackConfig `json:"fallback" yaml:"fallback"`
+}
+
+// NewPriorityConfig returns a PriorityConfig with default values.
+func NewPriorityConfig() PriorityConfig {
+	return PriorityConfig{
+		Ordering: impl.PriorityStrict,
+		Queues:   []PriorityQueueConfig{},
+		Fallback: PriorityFallbackConfig{
+			Limit:  1024 * 1024 * 500, // 500MB
+			Weight: 1,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewPriority creates a buffer consisting of multiple memory based queues
+// that are read from in order of priority.
+func NewPriority(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	conds := []condition.Type{}
+	queues := []impl.PriorityQueue{}
+
+	for _, qConf := range config.Priority.Queues {
+		cond, err := condition.New(qConf.Condition, mgr, log.NewModule(".buffer.priority"), stats)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		queues = append(queues, impl.PriorityQueue{
+			Limit:  qConf.Limit,
+			Weight: qConf.Weight,
+		})
+	}
+	queues = append(queues, impl.PriorityQueue{
+		Limit:  config.Priority.Fallback.Limit,
+		Weight: config.Priority.Fallback.Weight,
+	})
+
+	selector := func(msg types.Message) int {
+		for i, c := range conds {
+			if c.Check(msg) {
+				return i
+			}
+		}
+		return len(conds)
+	}
+
+	b, err := impl.NewPriority(config.Priority.Ordering, queues, selector, stats)
+	if err != nil {
+		return nil, err
+	}
+	w := NewOutputWrapper(config, b, stats)
+	w.RegisterEndpoints(mgr)
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
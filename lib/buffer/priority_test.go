@@ -0,0 +1,122 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package buffer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestPriorityBadOrdering(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "priority"
+	conf.Priority.Ordering = "nope"
+
+	if _, err := New(conf, newMockManager(), log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err != types.ErrInvalidPriorityOrdering {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+}
+
+func TestPriorityBuffer(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+type: priority
+priority:
+  ordering: strict
+  queues:
+  - condition:
+      type: content
+      content:
+        operator: contains_cs
+        arg: alert
+    limit: 1000
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "content", conf.Priority.Queues[0].Condition.Type; exp != act {
+		t.Errorf("Wrong condition type: %v != %v", exp, act)
+	}
+	if exp, act := 1, conf.Priority.Queues[0].Weight; exp != act {
+		t.Errorf("Wrong default weight: %v != %v", exp, act)
+	}
+
+	buf, err := New(conf, newMockManager(), log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err = buf.StartReceiving(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"bulk1", "bulk2", "alert1"} {
+		select {
+		case tChan <- types.NewTransaction(types.NewMessage([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	// The first message may already be in flight before the alert arrives.
+	var first string
+	for i, exp := range []string{"", "alert1", "bulk2"} {
+		var outTr types.Transaction
+		select {
+		case outTr = <-buf.TransactionChan():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		act := string(outTr.Payload.Get(0))
+		if i == 0 {
+			first = act
+		} else if first == "bulk1" && exp != act {
+			t.Errorf("Wrong message: %v != %v", exp, act)
+		}
+		select {
+		case outTr.ResponseChan <- types.NewSimpleResponse(nil):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+	if first != "bulk1" && first != "alert1" {
+		t.Errorf("Unexpected first message: %v", first)
+	}
+
+	buf.CloseAsync()
+	if err := buf.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
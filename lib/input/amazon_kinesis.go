@@ -0,0 +1,83 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["amazon_kinesis"] = TypeSpec{
+		constructor: NewAmazonKinesis,
+		description: `
+Consumes records from the shards of an Amazon Kinesis stream. Each message
+contains a batch of up to ` + "`max_batch_count`" + ` records from a single
+shard, with a record per message part.
+
+The shards of the stream are discovered when connecting and then once every
+` + "`shard_discovery_period_ms`" + `, as well as whenever a shard is finished.
+Resharding is handled by consuming shards that were closed by a split or merge
+until their end before consuming their children from the beginning, preserving
+the ordering of records with the same partition key. New shards without a
+checkpoint are consumed from the oldest record when ` + "`start_from_oldest`" + `
+is true, otherwise from the latest.
+
+### Checkpoints
+
+The sequence number of the last acknowledged record of each shard is persisted
+as a checkpoint, and consumption resumes from these checkpoints after a restart.
+By default checkpoints are stored in a local JSON file at
+` + "`checkpoint_path`" + `. If ` + "`dynamodb_table`" + ` is set checkpoints are
+stored in that DynamoDB table instead, which must have a string partition key
+named ` + "`consumer`" + ` and a string sort key named ` + "`shard_id`" + `. The
+partition key of each item is the ` + "`client_id`" + ` and the stream name
+separated by a colon.
+
+Each input consumes all shards of a stream, and multiple inputs sharing
+checkpoints are not coordinated, therefore only one input should consume a
+stream for a given ` + "`client_id`" + `.
+
+The field ` + "`endpoint`" + ` can be used to target a local stand-in such as
+[kinesalite](https://github.com/mhart/kinesalite).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAmazonKinesis creates a new AmazonKinesis input type.
+func NewAmazonKinesis(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	r, err := reader.NewAmazonKinesis(conf.AmazonKinesis, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"amazon_kinesis",
+		reader.NewPreserver(r),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -41,6 +41,36 @@ downloaded. Otherwise, the entire list of objects found when this input is
 created will be downloaded. Note that the prefix configuration is only used when
 downloading objects without SQS configured.
 
+### Polling
+
+If ` + "`poll_period_s`" + ` is greater than zero (and SQS is not configured) then
+the bucket is listed continuously, once every poll period, and only objects that
+are new since the last acknowledged object are downloaded. Objects are consumed
+in order of their last modified time and then their key.
+
+If a ` + "`checkpoint_path`" + ` is set then the key and modified time of the
+last acknowledged object are written to that file, allowing the input to resume
+from where it left off after a restart. Without a checkpoint file each restart
+begins from the start of the bucket.
+
+The last modified time of an S3 object is the time its upload began, and
+therefore objects that take a long time to upload, such as large multipart
+uploads, can appear in a listing after the checkpoint has moved beyond them, in
+which case they are skipped. Setting ` + "`lookback_s`" + ` to a duration longer
+than the slowest expected upload causes objects modified within that many
+seconds before the checkpoint to be listed again, where the keys of objects
+already acknowledged within that window are remembered (and written to the
+checkpoint file) so that they are not consumed twice.
+
+Listed objects can be filtered with ` + "`patterns`" + `, a list of glob patterns
+matched against the full object key, where an object is downloaded if it
+matches any of them. Objects last modified longer than ` + "`max_age_s`" + `
+seconds ago are ignored when it is greater than zero. These filters also apply
+when the bucket is only listed once.
+
+Listing polls the entire prefix each time, which can become costly for buckets
+with very large numbers of objects, so a narrow prefix is recommended.
+
 Here is a guide for setting up an SQS queue that receives events for new S3
 bucket objects:
 
@@ -55,11 +85,13 @@ func NewAmazonS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.
 	if len(conf.AmazonS3.Bucket) == 0 {
 		return nil, errors.New("invalid bucket (cannot be empty)")
 	}
+	r, err := reader.NewAmazonS3(conf.AmazonS3, log, stats)
+	if err != nil {
+		return nil, err
+	}
 	return NewReader(
 		"amazon_s3",
-		reader.NewPreserver(
-			reader.NewAmazonS3(conf.AmazonS3, log, stats),
-		),
+		reader.NewPreserver(r),
 		log, stats,
 	)
 }
@@ -34,7 +34,13 @@ func init() {
 		constructor: NewAmazonSQS,
 		description: `
 Receive messages from an Amazon SQS URL, only the body is extracted into
-messages.`,
+messages.
+
+Messages can be consumed in batches of up to 'max_batch_count' messages, where
+after the first receive the input continues receiving for up to
+'batch_timeout_ms' milliseconds whilst messages are available. Each batch is a
+single multiple part message and is deleted from the queue with batch delete
+requests of up to ten messages each.`,
 	}
 }
 
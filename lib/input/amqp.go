@@ -36,7 +36,14 @@ func init() {
 AMQP (0.91) is the underlying messaging protocol that is used by various message
 brokers, including RabbitMQ.
 
-Exchange type options are: direct|fanout|topic|x-custom`,
+Exchange type options are: direct|fanout|topic|x-custom
+
+Messages can be consumed in batches of up to 'max_batch_count' messages, where
+after the first message of a batch the input waits up to 'batch_timeout_ms'
+milliseconds for the batch to fill, or with a timeout of zero only adds messages
+that are immediately available. Each batch is a single multiple part message
+and is acknowledged with a single multiple ack. The 'prefetch_count' should be
+at least 'max_batch_count' in order for batches to fill.`,
 	}
 }
 
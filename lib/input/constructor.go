@@ -60,12 +60,15 @@ var Constructors = map[string]TypeSpec{}
 // we want to list it as an option.
 type Config struct {
 	Type          string                     `json:"type" yaml:"type"`
+	AmazonKinesis reader.AmazonKinesisConfig `json:"amazon_kinesis" yaml:"amazon_kinesis"`
 	AmazonS3      reader.AmazonS3Config      `json:"amazon_s3" yaml:"amazon_s3"`
 	AmazonSQS     reader.AmazonSQSConfig     `json:"amazon_sqs" yaml:"amazon_sqs"`
 	AMQP          reader.AMQPConfig          `json:"amqp" yaml:"amqp"`
 	Broker        BrokerConfig               `json:"broker" yaml:"broker"`
+	Directory     reader.DirectoryConfig     `json:"directory" yaml:"directory"`
 	Dynamic       DynamicConfig              `json:"dynamic" yaml:"dynamic"`
 	File          FileConfig                 `json:"file" yaml:"file"`
+	GCPPubSub     reader.GCPPubSubConfig     `json:"gcp_pubsub" yaml:"gcp_pubsub"`
 	HTTPClient    HTTPClientConfig           `json:"http_client" yaml:"http_client"`
 	HTTPServer    HTTPServerConfig           `json:"http_server" yaml:"http_server"`
 	Kafka         reader.KafkaConfig         `json:"kafka" yaml:"kafka"`
@@ -76,8 +79,13 @@ type Config struct {
 	NSQ           reader.NSQConfig           `json:"nsq" yaml:"nsq"`
 	RedisList     reader.RedisListConfig     `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub   reader.RedisPubSubConfig   `json:"redis_pubsub" yaml:"redis_pubsub"`
+	RedisStreams  reader.RedisStreamsConfig  `json:"redis_streams" yaml:"redis_streams"`
 	ScaleProto    reader.ScaleProtoConfig    `json:"scalability_protocols" yaml:"scalability_protocols"`
+	Socket        SocketConfig               `json:"socket" yaml:"socket"`
+	SQL           reader.SQLConfig           `json:"sql" yaml:"sql"`
 	STDIN         STDINConfig                `json:"stdin" yaml:"stdin"`
+	Syslog        SyslogConfig               `json:"syslog" yaml:"syslog"`
+	Websocket     reader.WebsocketConfig     `json:"websocket" yaml:"websocket"`
 	ZMQ4          *reader.ZMQ4Config         `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
 	Processors    []processor.Config         `json:"processors" yaml:"processors"`
 }
@@ -86,12 +94,15 @@ type Config struct {
 func NewConfig() Config {
 	return Config{
 		Type:          "stdin",
+		AmazonKinesis: reader.NewAmazonKinesisConfig(),
 		AmazonS3:      reader.NewAmazonS3Config(),
 		AmazonSQS:     reader.NewAmazonSQSConfig(),
 		AMQP:          reader.NewAMQPConfig(),
 		Broker:        NewBrokerConfig(),
+		Directory:     reader.NewDirectoryConfig(),
 		Dynamic:       NewDynamicConfig(),
 		File:          NewFileConfig(),
+		GCPPubSub:     reader.NewGCPPubSubConfig(),
 		HTTPClient:    NewHTTPClientConfig(),
 		HTTPServer:    NewHTTPServerConfig(),
 		Kafka:         reader.NewKafkaConfig(),
@@ -102,8 +113,13 @@ func NewConfig() Config {
 		NSQ:           reader.NewNSQConfig(),
 		RedisList:     reader.NewRedisListConfig(),
 		RedisPubSub:   reader.NewRedisPubSubConfig(),
+		RedisStreams:  reader.NewRedisStreamsConfig(),
 		ScaleProto:    reader.NewScaleProtoConfig(),
+		Socket:        NewSocketConfig(),
+		SQL:           reader.NewSQLConfig(),
 		STDIN:         NewSTDINConfig(),
+		Syslog:        NewSyslogConfig(),
+		Websocket:     reader.NewWebsocketConfig(),
 		ZMQ4:          reader.NewZMQ4Config(),
 		Processors:    []processor.Config{processor.NewConfig()},
 	}
@@ -0,0 +1,73 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["directory"] = TypeSpec{
+		constructor: NewDirectory,
+		description: `
+Tails all files that match the glob pattern 'path', such as
+'/var/log/app/*.log', in the manner of 'tail -F'. The pattern is matched again
+every 'poll_period_ms' milliseconds in order to find new files. Each line (or
+section of a file separated by 'delimiter') is read as a message part, with up
+to 'max_batch_count' parts from the same file read as a single message.
+
+Files are identified by their device and inode numbers and therefore a file
+that is renamed while matching the pattern continues to be read from the same
+position. A file that no longer matches the pattern, such as one that has been
+rotated, is read until its end and then closed. A file that shrinks is assumed
+to have been truncated and is read again from the start.
+
+The offset of each file is advanced as messages are acknowledged and written to
+the checkpoint file 'checkpoint_path' at most every 'checkpoint_period_ms'
+milliseconds, so that files are resumed from the same positions after a
+restart. Files that are not in the checkpoint are read from the beginning,
+unless 'start_from_beginning' is false, in which case files found when the
+input starts are read from their end.
+
+The 'finished_action' can be set to 'delete' or 'move' in order to delete files
+or move them into the directory 'move_to_dir' once they have been completely
+read and acknowledged and have not been written to for 'inactive_period_ms'
+milliseconds. The default action 'none' tails files indefinitely.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewDirectory creates a new Directory input type.
+func NewDirectory(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	d, err := reader.NewDirectory(conf.Directory, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("directory", reader.NewPreserver(d), log, stats)
+}
+
+//------------------------------------------------------------------------------
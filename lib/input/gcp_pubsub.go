@@ -0,0 +1,75 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"errors"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["gcp_pubsub"] = TypeSpec{
+		constructor: NewGCPPubSub,
+		description: `
+Consumes messages from a GCP Cloud Pub/Sub subscription using a streaming pull.
+Messages are acknowledged once they have been successfully propagated through
+the pipeline. Until then their ack deadlines are continuously extended, up to a
+total of ` + "`max_extension_ms`" + `, after which they may be redelivered.
+
+The fields ` + "`max_outstanding_messages` and `max_outstanding_bytes`" + ` limit
+the number of received messages that may be held unacknowledged at any time.
+
+Only the data of each Pub/Sub message is consumed. Messages within Benthos do
+not carry metadata, and therefore the attributes and ordering keys of received
+messages are discarded.
+
+Credentials are taken from the environment as described in
+https://cloud.google.com/docs/authentication/production. Setting the
+environment variable ` + "`PUBSUB_EMULATOR_HOST`" + ` connects this input to a
+Pub/Sub emulator instead.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGCPPubSub creates a new GCP Cloud Pub/Sub input type.
+func NewGCPPubSub(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	if len(conf.GCPPubSub.SubscriptionID) == 0 {
+		return nil, errors.New("invalid subscription (cannot be empty)")
+	}
+	r, err := reader.NewGCPPubSub(conf.GCPPubSub, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"gcp_pubsub",
+		reader.NewPreserver(r),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -59,6 +59,18 @@ line delimited list of message parts. Each part is read as an individual message
 unless multipart is set to true, in which case an empty line indicates the end
 of a message.
 
+### Server-Sent Events
+
+If you enable 'sse' then Benthos will consume the body of the response as a
+'text/event-stream', where the data of each event is read as a message. When
+multipart is set to true each data line of an event is read as a message part,
+otherwise the lines are joined as a single part. Events without data are
+ignored.
+
+The connection is reopened whenever the stream ends, and the ID of the last
+event received is sent with the header 'Last-Event-ID' in order to resume the
+stream.
+
 For more information about sending HTTP messages, including details on sending
 multipart, please read the 'docs/using_http.md' document.`,
 	}
@@ -75,6 +87,14 @@ type StreamConfig struct {
 	CustomDelim string `json:"custom_delimiter" yaml:"custom_delimiter"`
 }
 
+// SSEConfig contains fields for specifying server-sent event consumption
+// behaviour.
+type SSEConfig struct {
+	Enabled   bool `json:"enabled" yaml:"enabled"`
+	Multipart bool `json:"multipart" yaml:"multipart"`
+	MaxBuffer int  `json:"max_buffer" yaml:"max_buffer"`
+}
+
 // HTTPClientConfig is configuration for the HTTPClient output type.
 type HTTPClientConfig struct {
 	URL            string       `json:"url" yaml:"url"`
@@ -82,6 +102,7 @@ type HTTPClientConfig struct {
 	Payload        string       `json:"payload" yaml:"payload"`
 	ContentType    string       `json:"content_type" yaml:"content_type"`
 	Stream         StreamConfig `json:"stream" yaml:"stream"`
+	SSE            SSEConfig    `json:"sse" yaml:"sse"`
 	TimeoutMS      int64        `json:"timeout_ms" yaml:"timeout_ms"`
 	RetryMS        int64        `json:"retry_period_ms" yaml:"retry_period_ms"`
 	NumRetries     int          `json:"retries" yaml:"retries"`
@@ -103,6 +124,11 @@ func NewHTTPClientConfig() HTTPClientConfig {
 			MaxBuffer:   bufio.MaxScanTokenSize,
 			CustomDelim: "",
 		},
+		SSE: SSEConfig{
+			Enabled:   false,
+			Multipart: false,
+			MaxBuffer: bufio.MaxScanTokenSize,
+		},
 		TimeoutMS:      5000,
 		RetryMS:        1000,
 		NumRetries:     3,
@@ -150,78 +176,103 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 		}
 	}
 
-	if !h.conf.HTTPClient.Stream.Enabled {
+	if !h.conf.HTTPClient.Stream.Enabled && !h.conf.HTTPClient.SSE.Enabled {
 		// Timeout should be left at zero if we are streaming.
 		h.client.Timeout = time.Duration(h.conf.HTTPClient.TimeoutMS) * time.Millisecond
 		go h.loop()
 		return &h, nil
 	}
 
-	delim := "\n"
-	if len(conf.HTTPClient.Stream.CustomDelim) > 0 {
-		delim = conf.HTTPClient.Stream.CustomDelim
-	}
-
 	var resMux sync.Mutex
 	var closed bool
 	var res *http.Response
 
 	conn := false
 
-	rdr, err := reader.NewLines(
-		func() (io.Reader, error) {
-			h.stats.Incr("input.http_client.stream.constructor", 1)
+	openStream := func(header http.Header, reconnect bool) (io.Reader, error) {
+		h.stats.Incr("input.http_client.stream.constructor", 1)
+
+		resMux.Lock()
+		defer resMux.Unlock()
+
+		if conn && !reconnect {
+			return nil, io.EOF
+		}
+
+		if res != nil {
+			res.Body.Close()
+		}
+
+		var err error
+		res, err = h.doRequest(header)
+		for err != nil && !closed {
+			h.log.Errorf("HTTP stream request failed: %v\n", err)
+			h.stats.Incr("input.http_client.stream.request.error", 1)
 
+			resMux.Unlock()
+			<-time.After(time.Second)
 			resMux.Lock()
-			defer resMux.Unlock()
 
-			if conn && !conf.HTTPClient.Stream.Reconnect {
-				return nil, io.EOF
-			}
+			res, err = h.doRequest(header)
+		}
 
-			if res != nil {
-				res.Body.Close()
-			}
+		if closed {
+			return nil, io.EOF
+		}
 
-			var err error
-			res, err = h.doRequest()
-			for err != nil && !closed {
-				h.log.Errorf("HTTP stream request failed: %v\n", err)
-				h.stats.Incr("input.http_client.stream.request.error", 1)
+		conn = true
+		return res.Body, nil
+	}
 
-				resMux.Unlock()
-				<-time.After(time.Second)
-				resMux.Lock()
+	onClose := func() {
+		h.stats.Incr("input.http_client.stream.on_close", 1)
 
-				res, err = h.doRequest()
-			}
+		resMux.Lock()
+		defer resMux.Unlock()
 
-			if closed {
-				return nil, io.EOF
-			}
+		closed = true
 
-			conn = true
-			return res.Body, nil
-		},
-		func() {
-			h.stats.Incr("input.http_client.stream.on_close", 1)
+		// On shutdown we close the response body, this should end any
+		// blocked Read calls.
+		if res != nil {
+			res.Body.Close()
+			res = nil
+		}
+	}
 
-			resMux.Lock()
-			defer resMux.Unlock()
+	var rdr reader.Type
+	var err error
 
-			closed = true
+	if conf.HTTPClient.SSE.Enabled {
+		rdr, err = reader.NewSSE(
+			func(lastEventID string) (io.Reader, error) {
+				header := http.Header{}
+				header.Set("Accept", "text/event-stream")
+				if len(lastEventID) > 0 {
+					header.Set("Last-Event-ID", lastEventID)
+				}
+				return openStream(header, true)
+			},
+			onClose,
+			reader.OptSSESetMaxBuffer(conf.HTTPClient.SSE.MaxBuffer),
+			reader.OptSSESetMultipart(conf.HTTPClient.SSE.Multipart),
+		)
+	} else {
+		delim := "\n"
+		if len(conf.HTTPClient.Stream.CustomDelim) > 0 {
+			delim = conf.HTTPClient.Stream.CustomDelim
+		}
 
-			// On shutdown we close the response body, this should end any
-			// blocked Read calls.
-			if res != nil {
-				res.Body.Close()
-				res = nil
-			}
-		},
-		reader.OptLinesSetDelimiter(delim),
-		reader.OptLinesSetMaxBuffer(conf.HTTPClient.Stream.MaxBuffer),
-		reader.OptLinesSetMultipart(conf.HTTPClient.Stream.Multipart),
-	)
+		rdr, err = reader.NewLines(
+			func() (io.Reader, error) {
+				return openStream(nil, conf.HTTPClient.Stream.Reconnect)
+			},
+			onClose,
+			reader.OptLinesSetDelimiter(delim),
+			reader.OptLinesSetMaxBuffer(conf.HTTPClient.Stream.MaxBuffer),
+			reader.OptLinesSetMultipart(conf.HTTPClient.Stream.Multipart),
+		)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -235,8 +286,8 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 
 //------------------------------------------------------------------------------
 
-// createRequest creates an HTTP request out of a single message.
-func (h *HTTPClient) createRequest() (req *http.Request, err error) {
+// createRequest creates an HTTP request with optional headers.
+func (h *HTTPClient) createRequest(header http.Header) (req *http.Request, err error) {
 	var body io.Reader
 	if len(h.conf.HTTPClient.Payload) > 0 {
 		body = bytes.NewBufferString(h.conf.HTTPClient.Payload)
@@ -254,17 +305,20 @@ func (h *HTTPClient) createRequest() (req *http.Request, err error) {
 	if contentType := h.conf.HTTPClient.ContentType; len(contentType) > 0 {
 		req.Header.Add("Content-Type", contentType)
 	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
 	err = h.conf.HTTPClient.Config.Sign(req)
 	return
 }
 
-func (h *HTTPClient) doRequest() (*http.Response, error) {
+func (h *HTTPClient) doRequest(header http.Header) (*http.Response, error) {
 	var req *http.Request
 	var res *http.Response
 	var err error
 
-	if req, err = h.createRequest(); err != nil {
+	if req, err = h.createRequest(header); err != nil {
 		return nil, err
 	}
 
@@ -277,7 +331,7 @@ func (h *HTTPClient) doRequest() (*http.Response, error) {
 
 	i, j := 0, h.conf.HTTPClient.NumRetries
 	for i < j && err != nil && atomic.LoadInt32(&h.running) == 1 {
-		req, _ = h.createRequest()
+		req, _ = h.createRequest(header)
 		select {
 		case <-time.After(time.Duration(h.conf.HTTPClient.RetryMS) * time.Millisecond):
 		case <-h.closeChan:
@@ -366,7 +420,7 @@ func (h *HTTPClient) loop() {
 			var res *http.Response
 			var err error
 
-			if res, err = h.doRequest(); err != nil {
+			if res, err = h.doRequest(nil); err != nil {
 				if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
 					// Hate this ^
 					h.stats.Incr("input.http_client.request.timed_out", 1)
@@ -2,6 +2,7 @@ package input
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/textproto"
 	"os"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -637,6 +639,76 @@ func TestHTTPClientStreamGETRecover(t *testing.T) {
 	}
 }
 
+func TestHTTPClientSSE(t *testing.T) {
+	var reqLastIDs []string
+	var reqMut sync.Mutex
+
+	tserve := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exp, act := "text/event-stream", r.Header.Get("Accept"); exp != act {
+			t.Errorf("Wrong accept header: %v != %v", act, exp)
+		}
+
+		reqMut.Lock()
+		reqLastIDs = append(reqLastIDs, r.Header.Get("Last-Event-ID"))
+		n := len(reqLastIDs)
+		reqMut.Unlock()
+
+		// Each stream contains two events and then ends.
+		w.Header().Add("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: %v\ndata: foo%v\n\n", n*2-1, n)
+		fmt.Fprintf(w, "id: %v\ndata: bar%v\ndata: baz%v\n\n", n*2, n, n)
+	}))
+	defer tserve.Close()
+
+	conf := NewConfig()
+	conf.HTTPClient.URL = tserve.URL + "/testsse"
+	conf.HTTPClient.SSE.Enabled = true
+	conf.HTTPClient.SSE.Multipart = true
+
+	h, err := NewHTTPClient(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		for _, exp := range [][]string{
+			{fmt.Sprintf("foo%v", i)},
+			{fmt.Sprintf("bar%v", i), fmt.Sprintf("baz%v", i)},
+		} {
+			var ts types.Transaction
+			select {
+			case ts = <-h.TransactionChan():
+				var act []string
+				for _, p := range ts.Payload.GetAll() {
+					act = append(act, string(p))
+				}
+				if !reflect.DeepEqual(exp, act) {
+					t.Errorf("Wrong message: %v != %v", act, exp)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Action timed out")
+			}
+
+			select {
+			case ts.ResponseChan <- types.NewSimpleResponse(nil):
+			case <-time.After(time.Second):
+				t.Fatal("Action timed out")
+			}
+		}
+	}
+
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	reqMut.Lock()
+	if exp, act := []string{"", "2", "4"}, reqLastIDs[:3]; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong last event IDs: %v != %v", act, exp)
+	}
+	reqMut.Unlock()
+}
+
 func BenchmarkHTTPClientGETMultipart(b *testing.B) {
 	parts := []string{
 		"Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat.",
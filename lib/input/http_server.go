@@ -21,12 +21,15 @@
 package input
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -46,7 +49,23 @@ Receive messages POSTed over HTTP(S). HTTP 2.0 is supported when using TLS,
 which is enabled when key and cert files are specified.
 
 You can leave the 'address' config field blank in order to use the default
-service, but this will ignore TLS options.`,
+service, but this will ignore TLS options.
+
+### Synchronous Responses
+
+By default a request receives an empty 200 response once the message has
+reached its destination. When 'sync_response.enabled' is set the processed
+message is instead returned as the response body, which requires the output to
+be of the type 'sync_response' and the buffer to be 'none'. Messages with
+multiple parts are returned as a multipart response.
+
+The status code and headers of responses are set with the fields
+'sync_response.status_code' and 'sync_response.headers'. When
+'sync_response.envelope' is set the first part of each message is removed and
+parsed as a JSON object of the form
+'{"status_code":201,"headers":{"Content-Type":"application/json"}}', where any
+fields present override the configured values for that response. Processors
+such as 'insert_part' and 'set_json' can be used to build this part.`,
 	}
 }
 
@@ -54,11 +73,21 @@ service, but this will ignore TLS options.`,
 
 // HTTPServerConfig is configuration for the HTTPServer input type.
 type HTTPServerConfig struct {
-	Address   string `json:"address" yaml:"address"`
-	Path      string `json:"path" yaml:"path"`
-	TimeoutMS int64  `json:"timeout_ms" yaml:"timeout_ms"`
-	CertFile  string `json:"cert_file" yaml:"cert_file"`
-	KeyFile   string `json:"key_file" yaml:"key_file"`
+	Address      string                       `json:"address" yaml:"address"`
+	Path         string                       `json:"path" yaml:"path"`
+	TimeoutMS    int64                        `json:"timeout_ms" yaml:"timeout_ms"`
+	CertFile     string                       `json:"cert_file" yaml:"cert_file"`
+	KeyFile      string                       `json:"key_file" yaml:"key_file"`
+	SyncResponse HTTPServerSyncResponseConfig `json:"sync_response" yaml:"sync_response"`
+}
+
+// HTTPServerSyncResponseConfig is configuration for the synchronous responses
+// of the HTTPServer input type.
+type HTTPServerSyncResponseConfig struct {
+	Enabled    bool              `json:"enabled" yaml:"enabled"`
+	StatusCode int               `json:"status_code" yaml:"status_code"`
+	Headers    map[string]string `json:"headers" yaml:"headers"`
+	Envelope   bool              `json:"envelope" yaml:"envelope"`
 }
 
 // NewHTTPServerConfig creates a new HTTPServerConfig with default values.
@@ -69,6 +98,14 @@ func NewHTTPServerConfig() HTTPServerConfig {
 		TimeoutMS: 5000,
 		CertFile:  "",
 		KeyFile:   "",
+		SyncResponse: HTTPServerSyncResponseConfig{
+			Enabled:    false,
+			StatusCode: http.StatusOK,
+			Headers: map[string]string{
+				"Content-Type": "application/octet-stream",
+			},
+			Envelope: false,
+		},
 	}
 }
 
@@ -207,6 +244,9 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.stats.Incr("input.http_server.send.success", 1)
+		if h.conf.HTTPServer.SyncResponse.Enabled {
+			h.writeSyncResponse(w, res)
+		}
 	case <-time.After(time.Millisecond * time.Duration(h.conf.HTTPServer.TimeoutMS)):
 		h.stats.Incr("input.http_server.send.timeout", 1)
 		http.Error(w, "Request timed out", http.StatusRequestTimeout)
@@ -223,6 +263,76 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// syncResponseEnvelope is the structure of the first part of response messages
+// when the sync response envelope is enabled.
+type syncResponseEnvelope struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
+}
+
+// writeSyncResponse writes the message carried by a response, if any, as the
+// body of an HTTP response.
+func (h *HTTPServer) writeSyncResponse(w http.ResponseWriter, res types.Response) {
+	conf := h.conf.HTTPServer.SyncResponse
+
+	var parts [][]byte
+	if mRes, ok := res.(types.MessageResponse); ok && mRes.Message() != nil {
+		parts = mRes.Message().GetAll()
+	}
+
+	statusCode := conf.StatusCode
+	headers := map[string]string{}
+	for k, v := range conf.Headers {
+		headers[k] = v
+	}
+
+	if conf.Envelope && len(parts) > 0 {
+		env := syncResponseEnvelope{}
+		if err := json.Unmarshal(parts[0], &env); err != nil {
+			h.stats.Incr("input.http_server.sync_response.envelope.error", 1)
+			h.log.Errorf("Failed to parse response envelope: %v\n", err)
+			http.Error(w, "Internal server error", http.StatusBadGateway)
+			return
+		}
+		if env.StatusCode > 0 {
+			statusCode = env.StatusCode
+		}
+		for k, v := range env.Headers {
+			headers[k] = v
+		}
+		parts = parts[1:]
+	}
+
+	body := &bytes.Buffer{}
+	if len(parts) > 1 {
+		writer := multipart.NewWriter(body)
+		for _, p := range parts {
+			part, err := writer.CreatePart(textproto.MIMEHeader{
+				"Content-Type": []string{"application/octet-stream"},
+			})
+			if err == nil {
+				_, err = part.Write(p)
+			}
+			if err != nil {
+				h.log.Errorf("Failed to write multipart response: %v\n", err)
+				http.Error(w, "Internal server error", http.StatusBadGateway)
+				return
+			}
+		}
+		writer.Close()
+		headers["Content-Type"] = "multipart/mixed; boundary=" + writer.Boundary()
+	} else if len(parts) == 1 {
+		body.Write(parts[0])
+	}
+
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(statusCode)
+	w.Write(body.Bytes())
+	h.stats.Incr("input.http_server.sync_response.success", 1)
+}
+
 //------------------------------------------------------------------------------
 
 func (h *HTTPServer) loop() {
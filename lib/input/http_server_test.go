@@ -23,7 +23,12 @@ package input
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -214,3 +219,133 @@ func TestHTTPTimeout(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHTTPSyncResponse(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1245"
+	conf.HTTPServer.SyncResponse.Enabled = true
+	conf.HTTPServer.SyncResponse.StatusCode = http.StatusAccepted
+	conf.HTTPServer.SyncResponse.Headers["X-Foo"] = "foo"
+
+	h, err := NewHTTPServer(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.CloseAsync()
+
+	go func() {
+		ts := <-h.TransactionChan()
+		ts.ResponseChan <- types.NewMessageResponse(types.NewMessage([][]byte{
+			append([]byte("processed "), ts.Payload.Get(0)...),
+		}))
+	}()
+
+	req := httptest.NewRequest("POST", "/post", bytes.NewBufferString("hello world"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	h.(*HTTPServer).postHandler(rec, req)
+
+	if exp, act := http.StatusAccepted, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	if exp, act := "processed hello world", rec.Body.String(); exp != act {
+		t.Errorf("Wrong response body: %v != %v", act, exp)
+	}
+	if exp, act := "foo", rec.Header().Get("X-Foo"); exp != act {
+		t.Errorf("Wrong header: %v != %v", act, exp)
+	}
+	if exp, act := "application/octet-stream", rec.Header().Get("Content-Type"); exp != act {
+		t.Errorf("Wrong content type: %v != %v", act, exp)
+	}
+}
+
+func TestHTTPSyncResponseMultipart(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1247"
+	conf.HTTPServer.SyncResponse.Enabled = true
+
+	h, err := NewHTTPServer(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.CloseAsync()
+
+	go func() {
+		ts := <-h.TransactionChan()
+		ts.ResponseChan <- types.NewMessageResponse(types.NewMessage([][]byte{
+			[]byte("foo"), []byte("bar"),
+		}))
+	}()
+
+	req := httptest.NewRequest("POST", "/post", bytes.NewBufferString("hello world"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	h.(*HTTPServer).postHandler(rec, req)
+
+	mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "multipart/mixed", mediaType; exp != act {
+		t.Errorf("Wrong content type: %v != %v", act, exp)
+	}
+
+	var parts []string
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	for {
+		p, pErr := mr.NextPart()
+		if pErr == io.EOF {
+			break
+		}
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		b, _ := ioutil.ReadAll(p)
+		parts = append(parts, string(b))
+	}
+	if exp, act := "[foo bar]", fmt.Sprintf("%v", parts); exp != act {
+		t.Errorf("Wrong response parts: %v != %v", act, exp)
+	}
+}
+
+func TestHTTPSyncResponseEnvelope(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1246"
+	conf.HTTPServer.SyncResponse.Enabled = true
+	conf.HTTPServer.SyncResponse.Envelope = true
+
+	h, err := NewHTTPServer(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.CloseAsync()
+
+	go func() {
+		ts := <-h.TransactionChan()
+		ts.ResponseChan <- types.NewMessageResponse(types.NewMessage([][]byte{
+			[]byte(`{"status_code":201,"headers":{"Content-Type":"application/json"}}`),
+			[]byte(`{"result":"created"}`),
+		}))
+	}()
+
+	req := httptest.NewRequest("POST", "/post", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.(*HTTPServer).postHandler(rec, req)
+
+	if exp, act := http.StatusCreated, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	if exp, act := `{"result":"created"}`, rec.Body.String(); exp != act {
+		t.Errorf("Wrong response body: %v != %v", act, exp)
+	}
+	if exp, act := "application/json", rec.Header().Get("Content-Type"); exp != act {
+		t.Errorf("Wrong content type: %v != %v", act, exp)
+	}
+}
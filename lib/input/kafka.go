@@ -21,6 +21,12 @@
 package input
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
 	"github.com/Jeffail/benthos/lib/input/reader"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/service/log"
@@ -36,7 +42,40 @@ func init() {
 Connects to a kafka (0.8+) server. Offsets are managed within kafka as per the
 consumer group (set via config). Only one partition per input is supported, if
 you wish to balance partitions across a consumer group look at the
-'kafka_balanced' input type instead.`,
+'kafka_balanced' input type instead.
+
+When the consumer group has no committed offset for the partition the input
+starts from the offset 'start_from_offset' if it is not -1, otherwise from the
+first message at or after the unix timestamp in milliseconds
+'start_from_timestamp_ms' if it is not 0, otherwise from either the oldest or
+newest offset as per 'start_from_oldest'. Starting from a timestamp requires a
+'target_version' of at least 0.10.1.0.
+
+Offsets are committed after a message is acknowledged once either
+'commit_batch_size' messages have been acknowledged since the last commit or
+'commit_period_ms' milliseconds have passed since it, and are also committed
+when the input closes. The field 'max_processing_period_ms' is the maximum time
+a message is expected to take to be processed, after which the consumer of the
+partition may pause fetching.
+
+Messages can be consumed in batches of up to 'max_batch_count' messages, where
+after the first message of a batch the input waits up to 'batch_timeout_ms'
+milliseconds for the batch to fill, or with a timeout of zero only adds messages
+that are immediately available. Each batch is a single multiple part message,
+and acknowledging it commits the highest offset of the batch.
+
+### Resetting Offsets
+
+The offset of the consumer group can be reset for a running input with a POST
+request to the endpoint
+` + "`/kafka/{consumer_group}/{topic}/{partition}/reset_offsets`" + `, with one
+of the following query parameters specifying the new offset:
+
+- ` + "`position`" + `: either ` + "`oldest`" + ` or ` + "`newest`" + `.
+- ` + "`timestamp_ms`" + `: the first message at or after a unix timestamp.
+- ` + "`offset`" + `: an explicit offset.
+
+The input then resumes consuming from the new offset.`,
 	}
 }
 
@@ -48,7 +87,78 @@ func NewKafka(conf Config, mgr types.Manager, log log.Modular, stats metrics.Typ
 	if err != nil {
 		return nil, err
 	}
+	mgr.RegisterEndpoint(
+		fmt.Sprintf(
+			"/kafka/%v/%v/%v/reset_offsets",
+			conf.Kafka.ConsumerGroup, conf.Kafka.Topic, conf.Kafka.Partition,
+		),
+		"Reset the offset of a kafka input consumer group for a partition.",
+		kafkaResetOffsetsHandler(k.ResetOffsets),
+	)
 	return NewReader("kafka", reader.NewPreserver(k), log, stats)
 }
 
 //------------------------------------------------------------------------------
+
+// parseKafkaOffsetTarget parses an offset target from exactly one of the query
+// parameters position, timestamp_ms or offset.
+func parseKafkaOffsetTarget(query url.Values) (reader.KafkaOffsetTarget, error) {
+	var target reader.KafkaOffsetTarget
+	var err error
+
+	found := 0
+	if pos := query.Get("position"); len(pos) > 0 {
+		found++
+		switch pos {
+		case "oldest":
+			target = reader.NewKafkaOffsetTargetOldest()
+		case "newest":
+			target = reader.NewKafkaOffsetTargetNewest()
+		default:
+			return target, fmt.Errorf("position not recognised: %v", pos)
+		}
+	}
+	if ts := query.Get("timestamp_ms"); len(ts) > 0 {
+		found++
+		var ms int64
+		if ms, err = strconv.ParseInt(ts, 10, 64); err != nil || ms < 0 {
+			return target, fmt.Errorf("invalid timestamp_ms: %v", ts)
+		}
+		target = reader.NewKafkaOffsetTargetTimestamp(ms)
+	}
+	if off := query.Get("offset"); len(off) > 0 {
+		found++
+		var offset int64
+		if offset, err = strconv.ParseInt(off, 10, 64); err != nil || offset < 0 {
+			return target, fmt.Errorf("invalid offset: %v", off)
+		}
+		target = reader.NewKafkaOffsetTargetOffset(offset)
+	}
+	if found != 1 {
+		return target, errors.New("expected exactly one of position, timestamp_ms or offset")
+	}
+	return target, nil
+}
+
+// kafkaResetOffsetsHandler returns a handler that resets offsets to a target
+// parsed from the query parameters of POST requests.
+func kafkaResetOffsetsHandler(
+	reset func(target reader.KafkaOffsetTarget) error,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
+			return
+		}
+		target, err := parseKafkaOffsetTarget(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = reset(target); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reset offsets: %v", err), http.StatusBadGateway)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -21,6 +21,8 @@
 package input
 
 import (
+	"fmt"
+
 	"github.com/Jeffail/benthos/lib/input/reader"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/service/log"
@@ -35,7 +37,35 @@ func init() {
 		description: `
 Connects to a kafka (0.9+) server. Offsets are managed within kafka as per the
 consumer group (set via config), and partitions are automatically balanced
-across any members of the consumer group.`,
+across any members of the consumer group.
+
+When the consumer group has no committed offsets for partitions the input
+starts from the offset 'start_from_offset' if it is not -1, otherwise from the
+first message at or after the unix timestamp in milliseconds
+'start_from_timestamp_ms' if it is not 0, otherwise from either the oldest or
+newest offset as per 'start_from_oldest'. Starting from a timestamp requires a
+'target_version' of at least 0.10.1.0.
+
+Offsets are marked when messages are acknowledged and marked offsets are
+committed every 'commit_period_ms' milliseconds, and also each time
+'commit_batch_size' messages have been acknowledged. The field
+'max_processing_period_ms' is the maximum time a message is expected to take to
+be processed, after which the consumer of a partition may pause fetching.
+
+Messages can be consumed in batches of up to 'max_batch_count' messages, where
+after the first message of a batch the input waits up to 'batch_timeout_ms'
+milliseconds for the batch to fill, or with a timeout of zero only adds messages
+that are immediately available. Each batch is a single multiple part message,
+and acknowledging it marks the highest offset of each partition within it.
+
+### Resetting Offsets
+
+The offsets of the consumer group can be reset for all partitions of a topic
+with a POST request to the endpoint
+` + "`/kafka_balanced/{consumer_group}/{topic}/reset_offsets`" + `, with the
+same query parameters as the 'kafka' input. The input leaves the consumer group
+before committing the new offsets, and Kafka rejects them whilst other members
+of the group are active, therefore all other members must be stopped first.`,
 	}
 }
 
@@ -47,6 +77,19 @@ func NewKafkaBalanced(conf Config, mgr types.Manager, log log.Modular, stats met
 	if err != nil {
 		return nil, err
 	}
+	for _, topic := range conf.KafkaBalanced.Topics {
+		topic := topic
+		mgr.RegisterEndpoint(
+			fmt.Sprintf(
+				"/kafka_balanced/%v/%v/reset_offsets",
+				conf.KafkaBalanced.ConsumerGroup, topic,
+			),
+			"Reset the offsets of a kafka_balanced input consumer group for a topic.",
+			kafkaResetOffsetsHandler(func(target reader.KafkaOffsetTarget) error {
+				return k.ResetOffsets(topic, target)
+			}),
+		)
+	}
 	return NewReader("kafka_balanced", reader.NewPreserver(k), log, stats)
 }
 
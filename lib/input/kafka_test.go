@@ -0,0 +1,122 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaParseOffsetTarget(t *testing.T) {
+	tests := map[string]reader.KafkaOffsetTarget{
+		"position=oldest":   reader.NewKafkaOffsetTargetOldest(),
+		"position=newest":   reader.NewKafkaOffsetTargetNewest(),
+		"timestamp_ms=1000": reader.NewKafkaOffsetTargetTimestamp(1000),
+		"offset=0":          reader.NewKafkaOffsetTargetOffset(0),
+		"offset=53":         reader.NewKafkaOffsetTargetOffset(53),
+	}
+
+	for query, exp := range tests {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		act, err := parseKafkaOffsetTarget(values)
+		if err != nil {
+			t.Errorf("Failed to parse '%v': %v", query, err)
+			continue
+		}
+		if act != exp {
+			t.Errorf("Wrong target for '%v': %v != %v", query, act, exp)
+		}
+	}
+
+	badTests := []string{
+		"",
+		"position=middle",
+		"timestamp_ms=nope",
+		"offset=-5",
+		"position=oldest&offset=5",
+	}
+
+	for _, query := range badTests {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = parseKafkaOffsetTarget(values); err == nil {
+			t.Errorf("Expected error from '%v'", query)
+		}
+	}
+}
+
+func TestKafkaResetOffsetsHandler(t *testing.T) {
+	var targets []reader.KafkaOffsetTarget
+	var resetErr error
+
+	handler := kafkaResetOffsetsHandler(func(target reader.KafkaOffsetTarget) error {
+		targets = append(targets, target)
+		return resetErr
+	})
+
+	tests := []struct {
+		method string
+		query  string
+		err    error
+		status int
+	}{
+		{method: "GET", query: "offset=5", status: http.StatusMethodNotAllowed},
+		{method: "POST", query: "offset=nope", status: http.StatusBadRequest},
+		{method: "POST", query: "offset=5", status: http.StatusOK},
+		{method: "POST", query: "position=oldest", err: errors.New("nope"), status: http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		resetErr = test.err
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(test.method, "/reset_offsets?"+test.query, nil))
+		if exp, act := test.status, w.Code; exp != act {
+			t.Errorf("Wrong status for %v '%v': %v != %v", test.method, test.query, act, exp)
+		}
+	}
+
+	exp := []reader.KafkaOffsetTarget{
+		reader.NewKafkaOffsetTargetOffset(5),
+		reader.NewKafkaOffsetTargetOldest(),
+	}
+	if len(targets) != len(exp) {
+		t.Fatalf("Wrong count of resets: %v != %v", len(targets), len(exp))
+	}
+	for i, target := range targets {
+		if target != exp[i] {
+			t.Errorf("Wrong target: %v != %v", target, exp[i])
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,422 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+//------------------------------------------------------------------------------
+
+// AmazonKinesisConfig is configuration values for the input type.
+type AmazonKinesisConfig struct {
+	sess.Config            `json:",inline" yaml:",inline"`
+	Stream                 string `json:"stream" yaml:"stream"`
+	ClientID               string `json:"client_id" yaml:"client_id"`
+	StartFromOldest        bool   `json:"start_from_oldest" yaml:"start_from_oldest"`
+	CheckpointPath         string `json:"checkpoint_path" yaml:"checkpoint_path"`
+	DynamoDBTable          string `json:"dynamodb_table" yaml:"dynamodb_table"`
+	MaxBatchCount          int64  `json:"max_batch_count" yaml:"max_batch_count"`
+	PollPeriodMS           int64  `json:"poll_period_ms" yaml:"poll_period_ms"`
+	ShardDiscoveryPeriodMS int64  `json:"shard_discovery_period_ms" yaml:"shard_discovery_period_ms"`
+}
+
+// NewAmazonKinesisConfig creates a new Config with default values.
+func NewAmazonKinesisConfig() AmazonKinesisConfig {
+	return AmazonKinesisConfig{
+		Config:                 sess.NewConfig(),
+		Stream:                 "",
+		ClientID:               "benthos_consumer",
+		StartFromOldest:        true,
+		CheckpointPath:         "./benthos_kinesis_checkpoint.json",
+		DynamoDBTable:          "",
+		MaxBatchCount:          100,
+		PollPeriodMS:           1000,
+		ShardDiscoveryPeriodMS: 60000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// kinesisShard is a shard that is currently being consumed. A shard is parked
+// once the last records of a closed shard have been read, until they are
+// acknowledged.
+type kinesisShard struct {
+	id         string
+	hasParents bool
+	iterator   string
+	parked     bool
+}
+
+// AmazonKinesis is a benthos reader.Type implementation that reads records
+// from the shards of an Amazon Kinesis stream.
+type AmazonKinesis struct {
+	conf AmazonKinesisConfig
+
+	kinesis      *kinesis.Kinesis
+	checkpointer kinesisCheckpointer
+	checkpoints  map[string]kinesisCheckpoint
+	pending      map[string]kinesisCheckpoint
+
+	shards        []*kinesisShard
+	nextShard     int
+	lastDiscovery time.Time
+	rediscover    bool
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewAmazonKinesis creates a new Amazon Kinesis stream reader.Type.
+func NewAmazonKinesis(
+	conf AmazonKinesisConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*AmazonKinesis, error) {
+	if len(conf.Stream) == 0 {
+		return nil, errors.New("invalid stream (cannot be empty)")
+	}
+	if conf.MaxBatchCount <= 0 || conf.MaxBatchCount > 10000 {
+		return nil, errors.New("invalid max_batch_count (must be between 1 and 10000)")
+	}
+	return &AmazonKinesis{
+		conf:        conf,
+		checkpoints: map[string]kinesisCheckpoint{},
+		pending:     map[string]kinesisCheckpoint{},
+		closeChan:   make(chan struct{}),
+		log:         log.NewModule(".input.amazon_kinesis"),
+		stats:       stats,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target Kinesis stream,
+// loads the checkpoints of its shards and discovers the shards to consume.
+func (a *AmazonKinesis) Connect() error {
+	if a.kinesis != nil {
+		return nil
+	}
+
+	sess, err := a.conf.GetSession()
+	if err != nil {
+		return err
+	}
+
+	var checkpointer kinesisCheckpointer
+	if len(a.conf.DynamoDBTable) > 0 {
+		checkpointer = newKinesisDynamoDBCheckpointer(
+			dynamodb.New(sess), a.conf.DynamoDBTable,
+			fmt.Sprintf("%v:%v", a.conf.ClientID, a.conf.Stream),
+		)
+	} else if len(a.conf.CheckpointPath) > 0 {
+		checkpointer = newKinesisFileCheckpointer(a.conf.CheckpointPath)
+	}
+
+	checkpoints := map[string]kinesisCheckpoint{}
+	if checkpointer != nil {
+		if checkpoints, err = checkpointer.Load(); err != nil {
+			return err
+		}
+	}
+
+	a.kinesis = kinesis.New(sess)
+	a.checkpointer = checkpointer
+	a.checkpoints = checkpoints
+	a.shards = nil
+
+	if err = a.discoverShards(); err != nil {
+		a.kinesis = nil
+		return err
+	}
+
+	a.log.Infof("Receiving Amazon Kinesis records from stream: %v\n", a.conf.Stream)
+	return nil
+}
+
+// discoverShards lists the shards of the stream and selects those that are
+// ready to be consumed. A shard is ready when it has not been finished and
+// each of its parents that still exists has been finished, which preserves the
+// ordering of records across a split or merge.
+func (a *AmazonKinesis) discoverShards() error {
+	var shards []*kinesis.Shard
+	input := &kinesis.ListShardsInput{
+		StreamName: aws.String(a.conf.Stream),
+	}
+	for {
+		res, err := a.kinesis.ListShards(input)
+		if err != nil {
+			return fmt.Errorf("failed to list shards: %v", err)
+		}
+		shards = append(shards, res.Shards...)
+		if res.NextToken == nil {
+			break
+		}
+		input = &kinesis.ListShardsInput{
+			NextToken: res.NextToken,
+		}
+	}
+
+	existing := map[string]*kinesisShard{}
+	for _, s := range a.shards {
+		existing[s.id] = s
+	}
+	listed := map[string]struct{}{}
+	for _, s := range shards {
+		listed[aws.StringValue(s.ShardId)] = struct{}{}
+	}
+
+	var active []*kinesisShard
+	for _, s := range shards {
+		id := aws.StringValue(s.ShardId)
+		if a.checkpoints[id].Closed {
+			continue
+		}
+
+		ready, hasParents := true, false
+		for _, parent := range []*string{s.ParentShardId, s.AdjacentParentShardId} {
+			parentID := aws.StringValue(parent)
+			if len(parentID) == 0 {
+				continue
+			}
+			hasParents = true
+			if _, exists := listed[parentID]; exists && !a.checkpoints[parentID].Closed {
+				ready = false
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		if shard, exists := existing[id]; exists {
+			active = append(active, shard)
+		} else {
+			active = append(active, &kinesisShard{
+				id:         id,
+				hasParents: hasParents,
+			})
+		}
+	}
+
+	if len(active) != len(a.shards) {
+		a.log.Infof("Consuming %v shards of stream: %v\n", len(active), a.conf.Stream)
+	}
+
+	a.shards = active
+	if a.nextShard >= len(a.shards) {
+		a.nextShard = 0
+	}
+	a.lastDiscovery = time.Now()
+	a.rediscover = false
+	return nil
+}
+
+// getIterator obtains an iterator for a shard, starting after its checkpoint.
+func (a *AmazonKinesis) getIterator(shard *kinesisShard) error {
+	input := &kinesis.GetShardIteratorInput{
+		StreamName: aws.String(a.conf.Stream),
+		ShardId:    aws.String(shard.id),
+	}
+	if seq := a.checkpoints[shard.id].SequenceNumber; len(seq) > 0 {
+		input.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+		input.StartingSequenceNumber = aws.String(seq)
+	} else if shard.hasParents || a.conf.StartFromOldest {
+		// Shards created by resharding are always consumed from the start.
+		input.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeTrimHorizon)
+	} else {
+		input.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeLatest)
+	}
+
+	res, err := a.kinesis.GetShardIterator(input)
+	if err != nil {
+		return fmt.Errorf("failed to obtain iterator for shard %v: %v", shard.id, err)
+	}
+	shard.iterator = aws.StringValue(res.ShardIterator)
+	return nil
+}
+
+// finishShard marks a shard as completely consumed.
+func (a *AmazonKinesis) finishShard(shardID string, cp kinesisCheckpoint) {
+	cp.Closed = true
+	a.storeCheckpoint(shardID, cp)
+	a.rediscover = true
+	a.log.Infof("Finished consuming closed shard: %v\n", shardID)
+}
+
+func (a *AmazonKinesis) storeCheckpoint(shardID string, cp kinesisCheckpoint) {
+	a.checkpoints[shardID] = cp
+	if a.checkpointer == nil {
+		return
+	}
+	if err := a.checkpointer.Store(shardID, cp); err != nil {
+		a.log.Errorf("Failed to store checkpoint of shard %v: %v\n", shardID, err)
+	}
+}
+
+// readShard attempts to read a batch of records from a shard, returning a nil
+// message if none were available.
+func (a *AmazonKinesis) readShard(shard *kinesisShard) (types.Message, error) {
+	if shard.parked {
+		return nil, nil
+	}
+	if len(shard.iterator) == 0 {
+		if err := a.getIterator(shard); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := a.kinesis.GetRecords(&kinesis.GetRecordsInput{
+		ShardIterator: aws.String(shard.iterator),
+		Limit:         aws.Int64(a.conf.MaxBatchCount),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case kinesis.ErrCodeExpiredIteratorException:
+				shard.iterator = ""
+				return nil, nil
+			case kinesis.ErrCodeProvisionedThroughputExceededException:
+				a.stats.Incr("input.amazon_kinesis.throttled", 1)
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to read records from shard %v: %v", shard.id, err)
+	}
+
+	// A nil next iterator means the shard has been closed by resharding and
+	// every record has been read.
+	closed := res.NextShardIterator == nil
+	shard.iterator = aws.StringValue(res.NextShardIterator)
+
+	if len(res.Records) == 0 {
+		if closed {
+			if cp, exists := a.pending[shard.id]; exists {
+				// Records read previously are yet to be acknowledged.
+				cp.Closed = true
+				a.pending[shard.id] = cp
+				shard.parked = true
+			} else {
+				a.finishShard(shard.id, a.checkpoints[shard.id])
+			}
+		}
+		return nil, nil
+	}
+
+	parts := make([][]byte, len(res.Records))
+	for i, r := range res.Records {
+		parts[i] = r.Data
+	}
+	a.pending[shard.id] = kinesisCheckpoint{
+		SequenceNumber: aws.StringValue(res.Records[len(res.Records)-1].SequenceNumber),
+		Closed:         closed,
+	}
+	shard.parked = closed
+	return types.NewMessage(parts), nil
+}
+
+// Read attempts to read a new message from the shards of the target stream,
+// where each message contains a batch of records from a single shard.
+func (a *AmazonKinesis) Read() (types.Message, error) {
+	if a.kinesis == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	if a.rediscover || time.Since(a.lastDiscovery) >= time.Duration(a.conf.ShardDiscoveryPeriodMS)*time.Millisecond {
+		if err := a.discoverShards(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < len(a.shards); i++ {
+		shard := a.shards[a.nextShard]
+		a.nextShard = (a.nextShard + 1) % len(a.shards)
+
+		msg, err := a.readShard(shard)
+		if err != nil {
+			return nil, err
+		}
+		if msg != nil {
+			return msg, nil
+		}
+	}
+
+	select {
+	case <-time.After(time.Duration(a.conf.PollPeriodMS) * time.Millisecond):
+	case <-a.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+	return nil, types.ErrTimeout
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not. Successfully propagated records advance the
+// checkpoints of their shards, otherwise the shards are read again from their
+// checkpoints.
+func (a *AmazonKinesis) Acknowledge(err error) error {
+	if err != nil {
+		for _, shard := range a.shards {
+			if _, exists := a.pending[shard.id]; exists {
+				shard.iterator = ""
+				shard.parked = false
+			}
+		}
+	} else {
+		for shardID, cp := range a.pending {
+			if cp.Closed {
+				a.finishShard(shardID, cp)
+			} else {
+				a.storeCheckpoint(shardID, cp)
+			}
+		}
+	}
+	a.pending = map[string]kinesisCheckpoint{}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (a *AmazonKinesis) CloseAsync() {
+	a.closedOnce.Do(func() {
+		close(a.closeChan)
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs. If the timeout occurs then an error is returned.
+func (a *AmazonKinesis) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
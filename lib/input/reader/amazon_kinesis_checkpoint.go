@@ -0,0 +1,168 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+//------------------------------------------------------------------------------
+
+// kinesisCheckpoint is the position of a consumer within a single shard.
+type kinesisCheckpoint struct {
+	SequenceNumber string `json:"sequence_number"`
+	Closed         bool   `json:"closed"`
+}
+
+// kinesisCheckpointer persists the checkpoints of each shard of a stream.
+type kinesisCheckpointer interface {
+	// Load returns all stored checkpoints keyed by shard ID.
+	Load() (map[string]kinesisCheckpoint, error)
+
+	// Store persists the checkpoint of a shard.
+	Store(shardID string, cp kinesisCheckpoint) error
+}
+
+//------------------------------------------------------------------------------
+
+// kinesisFileCheckpointer stores checkpoints of all shards in a local JSON file.
+type kinesisFileCheckpointer struct {
+	path        string
+	checkpoints map[string]kinesisCheckpoint
+}
+
+func newKinesisFileCheckpointer(path string) *kinesisFileCheckpointer {
+	return &kinesisFileCheckpointer{
+		path:        path,
+		checkpoints: map[string]kinesisCheckpoint{},
+	}
+}
+
+func (k *kinesisFileCheckpointer) Load() (map[string]kinesisCheckpoint, error) {
+	cpBytes, err := ioutil.ReadFile(k.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]kinesisCheckpoint{}, nil
+		}
+		return nil, err
+	}
+	checkpoints := map[string]kinesisCheckpoint{}
+	if err = json.Unmarshal(cpBytes, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %v", err)
+	}
+	k.checkpoints = checkpoints
+
+	result := make(map[string]kinesisCheckpoint, len(checkpoints))
+	for id, cp := range checkpoints {
+		result[id] = cp
+	}
+	return result, nil
+}
+
+func (k *kinesisFileCheckpointer) Store(shardID string, cp kinesisCheckpoint) error {
+	k.checkpoints[shardID] = cp
+	cpBytes, err := json.Marshal(k.checkpoints)
+	if err != nil {
+		return err
+	}
+	tmpPath := k.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, cpBytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, k.path)
+}
+
+//------------------------------------------------------------------------------
+
+// kinesisDynamoDBCheckpointer stores checkpoints as items of a DynamoDB table
+// with a string partition key "consumer" and a string sort key "shard_id".
+type kinesisDynamoDBCheckpointer struct {
+	client   *dynamodb.DynamoDB
+	table    string
+	consumer string
+}
+
+func newKinesisDynamoDBCheckpointer(
+	client *dynamodb.DynamoDB,
+	table, consumer string,
+) *kinesisDynamoDBCheckpointer {
+	return &kinesisDynamoDBCheckpointer{
+		client:   client,
+		table:    table,
+		consumer: consumer,
+	}
+}
+
+func (k *kinesisDynamoDBCheckpointer) Load() (map[string]kinesisCheckpoint, error) {
+	checkpoints := map[string]kinesisCheckpoint{}
+	err := k.client.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(k.table),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("consumer = :consumer"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":consumer": {S: aws.String(k.consumer)},
+		},
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			shardID := item["shard_id"]
+			if shardID == nil || shardID.S == nil {
+				continue
+			}
+			var cp kinesisCheckpoint
+			if seq := item["sequence_number"]; seq != nil {
+				cp.SequenceNumber = aws.StringValue(seq.S)
+			}
+			if closed := item["closed"]; closed != nil {
+				cp.Closed = aws.BoolValue(closed.BOOL)
+			}
+			checkpoints[*shardID.S] = cp
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoints: %v", err)
+	}
+	return checkpoints, nil
+}
+
+func (k *kinesisDynamoDBCheckpointer) Store(shardID string, cp kinesisCheckpoint) error {
+	item := map[string]*dynamodb.AttributeValue{
+		"consumer": {S: aws.String(k.consumer)},
+		"shard_id": {S: aws.String(shardID)},
+		"closed":   {BOOL: aws.Bool(cp.Closed)},
+	}
+	if len(cp.SequenceNumber) > 0 {
+		item["sequence_number"] = &dynamodb.AttributeValue{S: aws.String(cp.SequenceNumber)}
+	}
+	_, err := k.client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(k.table),
+		Item:      item,
+	})
+	return err
+}
+
+//------------------------------------------------------------------------------
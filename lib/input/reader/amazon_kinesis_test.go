@@ -0,0 +1,434 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+//------------------------------------------------------------------------------
+
+type kinesisTestRecord struct {
+	Data           []byte
+	PartitionKey   string
+	SequenceNumber string
+}
+
+type kinesisTestShard struct {
+	ShardId       string
+	ParentShardId string `json:",omitempty"`
+	records       []kinesisTestRecord
+	closed        bool
+}
+
+// kinesisTestServer is a minimal Kinesis stand-in that supports listing shards
+// one page at a time and reading their records through shard iterators of the
+// form "<shard>/<index>".
+type kinesisTestServer struct {
+	sync.Mutex
+	shards []*kinesisTestShard
+	seq    int
+}
+
+func (k *kinesisTestServer) getShard(id string) *kinesisTestShard {
+	for _, s := range k.shards {
+		if s.ShardId == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (k *kinesisTestServer) addShard(id, parent string) {
+	k.Lock()
+	k.shards = append(k.shards, &kinesisTestShard{
+		ShardId:       id,
+		ParentShardId: parent,
+	})
+	k.Unlock()
+}
+
+func (k *kinesisTestServer) put(shardID string, data ...string) {
+	k.Lock()
+	s := k.getShard(shardID)
+	for _, d := range data {
+		k.seq++
+		s.records = append(s.records, kinesisTestRecord{
+			Data:           []byte(d),
+			PartitionKey:   d,
+			SequenceNumber: fmt.Sprintf("%05d", k.seq),
+		})
+	}
+	k.Unlock()
+}
+
+func (k *kinesisTestServer) closeShard(shardID string) {
+	k.Lock()
+	k.getShard(shardID).closed = true
+	k.Unlock()
+}
+
+func (k *kinesisTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.Lock()
+	defer k.Unlock()
+
+	var req struct {
+		StreamName             string
+		NextToken              string
+		ShardId                string
+		ShardIteratorType      string
+		StartingSequenceNumber string
+		ShardIterator          string
+		Limit                  int
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var res interface{}
+	switch r.Header.Get("X-Amz-Target") {
+	case "Kinesis_20131202.ListShards":
+		index := 0
+		if len(req.NextToken) > 0 {
+			index, _ = strconv.Atoi(req.NextToken)
+		}
+		page := struct {
+			Shards    []*kinesisTestShard
+			NextToken string `json:",omitempty"`
+		}{
+			Shards: k.shards[index : index+1],
+		}
+		if index+1 < len(k.shards) {
+			page.NextToken = strconv.Itoa(index + 1)
+		}
+		res = page
+	case "Kinesis_20131202.GetShardIterator":
+		s := k.getShard(req.ShardId)
+		index := 0
+		switch req.ShardIteratorType {
+		case "LATEST":
+			index = len(s.records)
+		case "AFTER_SEQUENCE_NUMBER":
+			for i, record := range s.records {
+				if record.SequenceNumber == req.StartingSequenceNumber {
+					index = i + 1
+				}
+			}
+		}
+		res = map[string]string{
+			"ShardIterator": fmt.Sprintf("%v/%v", s.ShardId, index),
+		}
+	case "Kinesis_20131202.GetRecords":
+		iter := strings.Split(req.ShardIterator, "/")
+		s := k.getShard(iter[0])
+		index, _ := strconv.Atoi(iter[1])
+		end := index + req.Limit
+		if end > len(s.records) {
+			end = len(s.records)
+		}
+		records := struct {
+			Records           []kinesisTestRecord
+			NextShardIterator string `json:",omitempty"`
+		}{
+			Records: s.records[index:end],
+		}
+		if !s.closed || end < len(s.records) {
+			records.NextShardIterator = fmt.Sprintf("%v/%v", s.ShardId, end)
+		}
+		res = records
+	default:
+		http.Error(w, "unexpected target", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(res)
+}
+
+//------------------------------------------------------------------------------
+
+func newKinesisTestReader(t *testing.T, conf AmazonKinesisConfig) *AmazonKinesis {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	a, err := NewAmazonKinesis(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func readKinesisTestMessage(t *testing.T, a *AmazonKinesis, ack error) []string {
+	msg, err := a.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []string
+	for _, part := range msg.GetAll() {
+		parts = append(parts, string(part))
+	}
+	if err = a.Acknowledge(ack); err != nil {
+		t.Fatal(err)
+	}
+	return parts
+}
+
+func TestAmazonKinesisResharding(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_kinesis_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	k := &kinesisTestServer{}
+	k.addShard("shard-0", "")
+	k.put("shard-0", "a", "b")
+
+	server := httptest.NewServer(k)
+	defer server.Close()
+
+	conf := NewAmazonKinesisConfig()
+	conf.Endpoint = server.URL
+	conf.Credentials.ID = "id"
+	conf.Credentials.Secret = "secret"
+	conf.Stream = "foo"
+	conf.CheckpointPath = filepath.Join(tmpDir, "checkpoint.json")
+	conf.PollPeriodMS = 1
+
+	a := newKinesisTestReader(t, conf)
+
+	if exp, act := "[a b]", fmt.Sprintf("%v", readKinesisTestMessage(t, a, types.ErrTimeout)); exp != act {
+		t.Errorf("Wrong parts: %v != %v", act, exp)
+	}
+	if exp, act := "[a b]", fmt.Sprintf("%v", readKinesisTestMessage(t, a, nil)); exp != act {
+		t.Errorf("Wrong parts after failed ack: %v != %v", act, exp)
+	}
+	if _, err = a.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTimeout)
+	}
+
+	// Split shard-0 into two children.
+	k.put("shard-0", "c")
+	k.closeShard("shard-0")
+	k.addShard("shard-1", "shard-0")
+	k.addShard("shard-2", "shard-0")
+	k.put("shard-1", "d")
+	k.put("shard-2", "e")
+
+	// The closed parent is not read again while its last records are yet to
+	// be acknowledged.
+	msg, err := a.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "c", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong parts from closed parent: %v != %v", act, exp)
+	}
+	if _, err = a.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTimeout)
+	}
+	if err = a.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var children []string
+	children = append(children, readKinesisTestMessage(t, a, nil)...)
+	children = append(children, readKinesisTestMessage(t, a, nil)...)
+	sort.Strings(children)
+	if exp, act := "[d e]", fmt.Sprintf("%v", children); exp != act {
+		t.Errorf("Wrong parts from children: %v != %v", act, exp)
+	}
+
+	if _, err = a.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTimeout)
+	}
+	a.CloseAsync()
+	if err = a.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	cpBytes, err := ioutil.ReadFile(conf.CheckpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoints := map[string]kinesisCheckpoint{}
+	if err = json.Unmarshal(cpBytes, &checkpoints); err != nil {
+		t.Fatal(err)
+	}
+	expCheckpoints := map[string]kinesisCheckpoint{
+		"shard-0": {SequenceNumber: "00003", Closed: true},
+		"shard-1": {SequenceNumber: "00004"},
+		"shard-2": {SequenceNumber: "00005"},
+	}
+	if exp, act := fmt.Sprintf("%v", expCheckpoints), fmt.Sprintf("%v", checkpoints); exp != act {
+		t.Errorf("Wrong checkpoints: %v != %v", act, exp)
+	}
+
+	// Resume from the checkpoints.
+	k.put("shard-1", "f")
+
+	a = newKinesisTestReader(t, conf)
+	defer func() {
+		a.CloseAsync()
+		if err = a.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if exp, act := "[f]", fmt.Sprintf("%v", readKinesisTestMessage(t, a, nil)); exp != act {
+		t.Errorf("Wrong parts after restart: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonKinesisBadConfig(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := NewAmazonKinesisConfig()
+	if _, err := NewAmazonKinesis(conf, logger, metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty stream")
+	}
+
+	conf.Stream = "foo"
+	conf.MaxBatchCount = 0
+	if _, err := NewAmazonKinesis(conf, logger, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad max_batch_count")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// dynamoDBTestServer is a minimal DynamoDB stand-in that supports PutItem and
+// Query on a table with the partition key "consumer".
+type dynamoDBTestServer struct {
+	sync.Mutex
+	items map[string]map[string]map[string]interface{}
+}
+
+func (d *dynamoDBTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+
+	var req struct {
+		Item                      map[string]map[string]interface{}
+		ExpressionAttributeValues map[string]map[string]interface{}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var res interface{}
+	switch r.Header.Get("X-Amz-Target") {
+	case "DynamoDB_20120810.PutItem":
+		key := fmt.Sprintf("%v/%v", req.Item["consumer"]["S"], req.Item["shard_id"]["S"])
+		d.items[key] = req.Item
+		res = map[string]interface{}{}
+	case "DynamoDB_20120810.Query":
+		consumer := req.ExpressionAttributeValues[":consumer"]["S"]
+		items := []map[string]map[string]interface{}{}
+		for _, item := range d.items {
+			if item["consumer"]["S"] == consumer {
+				items = append(items, item)
+			}
+		}
+		res = map[string]interface{}{
+			"Items": items,
+			"Count": len(items),
+		}
+	default:
+		http.Error(w, "unexpected target", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(res)
+}
+
+func TestAmazonKinesisDynamoDBCheckpointer(t *testing.T) {
+	d := &dynamoDBTestServer{
+		items: map[string]map[string]map[string]interface{}{},
+	}
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("eu-west-1").
+		WithEndpoint(server.URL).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dynamodb.New(sess)
+
+	cp := newKinesisDynamoDBCheckpointer(client, "checkpoints", "foo:bar")
+	if err = cp.Store("shard-0", kinesisCheckpoint{SequenceNumber: "1", Closed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cp.Store("shard-1", kinesisCheckpoint{SequenceNumber: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cp.Store("shard-1", kinesisCheckpoint{SequenceNumber: "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoints, err := newKinesisDynamoDBCheckpointer(client, "checkpoints", "foo:bar").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]kinesisCheckpoint{
+		"shard-0": {SequenceNumber: "1", Closed: true},
+		"shard-1": {SequenceNumber: "3"},
+	}
+	if exp, act := fmt.Sprintf("%v", exp), fmt.Sprintf("%v", checkpoints); exp != act {
+		t.Errorf("Wrong checkpoints: %v != %v", act, exp)
+	}
+
+	if checkpoints, err = newKinesisDynamoDBCheckpointer(client, "checkpoints", "baz:bar").Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 0 {
+		t.Errorf("Unexpected checkpoints for other consumer: %v", checkpoints)
+	}
+}
+
+//------------------------------------------------------------------------------
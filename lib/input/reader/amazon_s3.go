@@ -21,16 +21,22 @@
 package reader
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
 	"github.com/Jeffail/benthos/lib/util/service/log"
 	"github.com/Jeffail/benthos/lib/util/service/metrics"
 	"github.com/Jeffail/gabs"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -39,42 +45,41 @@ import (
 
 //------------------------------------------------------------------------------
 
-// AmazonAWSCredentialsConfig contains configuration params for AWS credentials.
-type AmazonAWSCredentialsConfig struct {
-	ID     string `json:"id" yaml:"id"`
-	Secret string `json:"secret" yaml:"secret"`
-	Token  string `json:"token" yaml:"token"`
-}
-
 // AmazonS3Config is configuration values for the input type.
 type AmazonS3Config struct {
-	Region         string                     `json:"region" yaml:"region"`
-	Bucket         string                     `json:"bucket" yaml:"bucket"`
-	Prefix         string                     `json:"prefix" yaml:"prefix"`
-	DeleteObjects  bool                       `json:"delete_objects" yaml:"delete_objects"`
-	SQSURL         string                     `json:"sqs_url" yaml:"sqs_url"`
-	SQSBodyPath    string                     `json:"sqs_body_path" yaml:"sqs_body_path"`
-	SQSMaxMessages int64                      `json:"sqs_max_messages" yaml:"sqs_max_messages"`
-	Credentials    AmazonAWSCredentialsConfig `json:"credentials" yaml:"credentials"`
-	TimeoutS       int64                      `json:"timeout_s" yaml:"timeout_s"`
+	sess.Config    `json:",inline" yaml:",inline"`
+	Bucket         string   `json:"bucket" yaml:"bucket"`
+	Prefix         string   `json:"prefix" yaml:"prefix"`
+	Patterns       []string `json:"patterns" yaml:"patterns"`
+	MaxAgeS        int64    `json:"max_age_s" yaml:"max_age_s"`
+	PollPeriodS    int64    `json:"poll_period_s" yaml:"poll_period_s"`
+	CheckpointPath string   `json:"checkpoint_path" yaml:"checkpoint_path"`
+	LookbackS      int64    `json:"lookback_s" yaml:"lookback_s"`
+	DeleteObjects  bool     `json:"delete_objects" yaml:"delete_objects"`
+	SQSURL         string   `json:"sqs_url" yaml:"sqs_url"`
+	SQSBodyPath    string   `json:"sqs_body_path" yaml:"sqs_body_path"`
+	SQSMaxMessages int64    `json:"sqs_max_messages" yaml:"sqs_max_messages"`
+	ForcePathStyle bool     `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	TimeoutS       int64    `json:"timeout_s" yaml:"timeout_s"`
 }
 
 // NewAmazonS3Config creates a new Config with default values.
 func NewAmazonS3Config() AmazonS3Config {
 	return AmazonS3Config{
-		Region:         "eu-west-1",
+		Config:         sess.NewConfig(),
 		Bucket:         "",
 		Prefix:         "",
+		Patterns:       []string{},
+		MaxAgeS:        0,
+		PollPeriodS:    0,
+		CheckpointPath: "",
+		LookbackS:      0,
 		DeleteObjects:  false,
 		SQSURL:         "",
 		SQSBodyPath:    "Records.s3.object.key",
 		SQSMaxMessages: 10,
-		Credentials: AmazonAWSCredentialsConfig{
-			ID:     "",
-			Secret: "",
-			Token:  "",
-		},
-		TimeoutS: 5,
+		ForcePathStyle: false,
+		TimeoutS:       5,
 	}
 }
 
@@ -82,9 +87,64 @@ func NewAmazonS3Config() AmazonS3Config {
 
 type objKey struct {
 	s3Key     string
+	modified  time.Time
 	sqsHandle *sqs.DeleteMessageBatchRequestEntry
 }
 
+// s3Checkpoint is the position of the last acknowledged object of a bucket
+// listing, where objects are ordered by their modified time and then by key.
+// Seen contains the keys and modified times of acknowledged objects within the
+// lookback window of the checkpoint.
+type s3Checkpoint struct {
+	LastKey      string               `json:"last_key"`
+	LastModified time.Time            `json:"last_modified"`
+	Seen         map[string]time.Time `json:"seen,omitempty"`
+}
+
+// before returns true if an object is at or before the checkpoint.
+func (c s3Checkpoint) before(key string, modified time.Time) bool {
+	if modified.Equal(c.LastModified) {
+		return key <= c.LastKey
+	}
+	return modified.Before(c.LastModified)
+}
+
+// consumed returns true if an object has already been acknowledged. Objects
+// modified within the lookback window of the checkpoint are only considered
+// consumed if they have been seen, as their modified time reflects when their
+// upload began and they might therefore appear in a listing after the
+// checkpoint has moved beyond them.
+func (c s3Checkpoint) consumed(key string, modified time.Time, lookback time.Duration) bool {
+	if lookback > 0 && !modified.Before(c.LastModified.Add(-lookback)) {
+		_, seen := c.Seen[key]
+		return seen
+	}
+	return c.before(key, modified)
+}
+
+// advance moves the checkpoint to an acknowledged object if it is beyond the
+// current position, and records it as seen when a lookback window is set.
+func (c *s3Checkpoint) advance(key string, modified time.Time, lookback time.Duration) {
+	if !c.before(key, modified) {
+		c.LastKey = key
+		c.LastModified = modified
+	}
+	if lookback <= 0 {
+		return
+	}
+	if c.Seen == nil {
+		c.Seen = map[string]time.Time{}
+	}
+	c.Seen[key] = modified
+
+	minModified := c.LastModified.Add(-lookback)
+	for k, m := range c.Seen {
+		if m.Before(minModified) {
+			delete(c.Seen, k)
+		}
+	}
+}
+
 // AmazonS3 is a benthos reader.Type implementation that reads messages from an
 // Amazon S3 bucket.
 type AmazonS3 struct {
@@ -95,6 +155,12 @@ type AmazonS3 struct {
 	readKeys   []objKey
 	targetKeys []objKey
 
+	checkpoint s3Checkpoint
+	lastList   time.Time
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
 	session    *session.Session
 	s3         *s3.S3
 	downloader *s3manager.Downloader
@@ -109,17 +175,23 @@ func NewAmazonS3(
 	conf AmazonS3Config,
 	log log.Modular,
 	stats metrics.Type,
-) *AmazonS3 {
-	var path []string
+) (*AmazonS3, error) {
+	var bodyPath []string
 	if len(conf.SQSBodyPath) > 0 {
-		path = strings.Split(conf.SQSBodyPath, ".")
+		bodyPath = strings.Split(conf.SQSBodyPath, ".")
+	}
+	for _, pattern := range conf.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%v': %v", pattern, err)
+		}
 	}
 	return &AmazonS3{
 		conf:        conf,
-		sqsBodyPath: path,
+		sqsBodyPath: bodyPath,
+		closeChan:   make(chan struct{}),
 		log:         log.NewModule(".input.amazon_s3"),
 		stats:       stats,
-	}
+	}, nil
 }
 
 // Connect attempts to establish a connection to the target S3 bucket and any
@@ -129,19 +201,9 @@ func (a *AmazonS3) Connect() error {
 		return nil
 	}
 
-	awsConf := aws.NewConfig()
-	if len(a.conf.Region) > 0 {
-		awsConf = awsConf.WithRegion(a.conf.Region)
-	}
-	if len(a.conf.Credentials.ID) > 0 {
-		awsConf = awsConf.WithCredentials(credentials.NewStaticCredentials(
-			a.conf.Credentials.ID,
-			a.conf.Credentials.Secret,
-			a.conf.Credentials.Token,
-		))
-	}
-
-	sess, err := session.NewSession(awsConf)
+	sess, err := a.conf.GetSession(func(c *aws.Config) {
+		c.S3ForcePathStyle = aws.Bool(a.conf.ForcePathStyle)
+	})
 	if err != nil {
 		return err
 	}
@@ -150,20 +212,11 @@ func (a *AmazonS3) Connect() error {
 	dler := s3manager.NewDownloader(sess)
 
 	if len(a.conf.SQSURL) == 0 {
-		listInput := &s3.ListObjectsInput{
-			Bucket: aws.String(a.conf.Bucket),
-		}
-		if len(a.conf.Prefix) > 0 {
-			listInput.Prefix = aws.String(a.conf.Prefix)
+		if err = a.loadCheckpoint(); err != nil {
+			return err
 		}
-		objList, err := sThree.ListObjects(listInput)
-		if err != nil {
-			return fmt.Errorf("failed to list objects: %v", err)
-		}
-		for _, obj := range objList.Contents {
-			a.targetKeys = append(a.targetKeys, objKey{
-				s3Key: *obj.Key,
-			})
+		if a.targetKeys, err = a.listObjects(sThree); err != nil {
+			return err
 		}
 	} else {
 		a.sqs = sqs.New(sess)
@@ -177,6 +230,130 @@ func (a *AmazonS3) Connect() error {
 	return nil
 }
 
+// loadCheckpoint reads the checkpoint file, if configured and present.
+func (a *AmazonS3) loadCheckpoint() error {
+	if len(a.conf.CheckpointPath) == 0 {
+		return nil
+	}
+	cpBytes, err := ioutil.ReadFile(a.conf.CheckpointPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err = json.Unmarshal(cpBytes, &a.checkpoint); err != nil {
+		return fmt.Errorf("failed to parse checkpoint file: %v", err)
+	}
+	a.log.Infof("Resuming bucket listing after key '%v' modified at %v\n", a.checkpoint.LastKey, a.checkpoint.LastModified)
+	return nil
+}
+
+// writeCheckpoint writes the checkpoint file, if configured.
+func (a *AmazonS3) writeCheckpoint() error {
+	if len(a.conf.CheckpointPath) == 0 {
+		return nil
+	}
+	cpBytes, err := json.Marshal(a.checkpoint)
+	if err != nil {
+		return err
+	}
+	tmpPath := a.conf.CheckpointPath + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, cpBytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, a.conf.CheckpointPath)
+}
+
+// matchesPatterns returns true if an object key matches any of the configured
+// patterns, or if there are no patterns.
+func (a *AmazonS3) matchesPatterns(key string) bool {
+	if len(a.conf.Patterns) == 0 {
+		return true
+	}
+	for _, pattern := range a.conf.Patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// listObjects lists the objects of the bucket under the prefix that are beyond
+// the checkpoint and match the filters, ordered by modified time and key.
+func (a *AmazonS3) listObjects(client *s3.S3) ([]objKey, error) {
+	a.lastList = time.Now()
+
+	listInput := &s3.ListObjectsInput{
+		Bucket: aws.String(a.conf.Bucket),
+	}
+	if len(a.conf.Prefix) > 0 {
+		listInput.Prefix = aws.String(a.conf.Prefix)
+	}
+
+	var minModified time.Time
+	if a.conf.MaxAgeS > 0 {
+		minModified = time.Now().Add(-time.Duration(a.conf.MaxAgeS) * time.Second)
+	}
+
+	lookback := time.Duration(a.conf.LookbackS) * time.Second
+	pending := map[string]struct{}{}
+	for _, k := range a.readKeys {
+		pending[k.s3Key] = struct{}{}
+	}
+
+	var keys []objKey
+	if err := client.ListObjectsPages(listInput, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			modified := aws.TimeValue(obj.LastModified)
+			if _, exists := pending[key]; exists {
+				continue
+			}
+			if a.checkpoint.consumed(key, modified, lookback) ||
+				modified.Before(minModified) ||
+				!a.matchesPatterns(key) {
+				continue
+			}
+			keys = append(keys, objKey{
+				s3Key:    key,
+				modified: modified,
+			})
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list objects: %v", err)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].modified.Equal(keys[j].modified) {
+			return keys[i].s3Key < keys[j].s3Key
+		}
+		return keys[i].modified.Before(keys[j].modified)
+	})
+	return keys, nil
+}
+
+// pollObjects lists the bucket for new objects once the poll period has passed
+// since the previous listing.
+func (a *AmazonS3) pollObjects() error {
+	period := time.Duration(a.conf.PollPeriodS) * time.Second
+	if remaining := period - time.Since(a.lastList); remaining > 0 {
+		select {
+		case <-time.After(remaining):
+		case <-a.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+
+	keys, err := a.listObjects(a.s3)
+	if err != nil {
+		return err
+	}
+	a.targetKeys = keys
+	return nil
+}
+
 func (a *AmazonS3) readSQSEvents() error {
 	var dudMessageHandles []*sqs.DeleteMessageBatchRequestEntry
 
@@ -256,6 +433,10 @@ func (a *AmazonS3) Read() (types.Message, error) {
 			if err := a.readSQSEvents(); err != nil {
 				return nil, err
 			}
+		} else if a.conf.PollPeriodS > 0 {
+			if err := a.pollObjects(); err != nil {
+				return nil, err
+			}
 		} else {
 			// If we aren't using SQS but exhausted our targets we are done.
 			return nil, types.ErrTypeClosed
@@ -312,6 +493,15 @@ func (a *AmazonS3) Acknowledge(err error) error {
 				Entries:  deleteHandles,
 			})
 		}
+		if a.sqs == nil && len(a.readKeys) > 0 {
+			lookback := time.Duration(a.conf.LookbackS) * time.Second
+			for _, key := range a.readKeys {
+				a.checkpoint.advance(key.s3Key, key.modified, lookback)
+			}
+			if err := a.writeCheckpoint(); err != nil {
+				a.log.Errorf("Failed to write checkpoint file: %v\n", err)
+			}
+		}
 		a.readKeys = nil
 	} else {
 		a.targetKeys = append(a.readKeys, a.targetKeys...)
@@ -322,6 +512,9 @@ func (a *AmazonS3) Acknowledge(err error) error {
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonS3) CloseAsync() {
+	a.closedOnce.Do(func() {
+		close(a.closeChan)
+	})
 }
 
 // WaitForClose will block until either the reader is closed or a specified
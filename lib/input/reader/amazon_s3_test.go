@@ -0,0 +1,336 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+type s3TestObject struct {
+	body     []byte
+	modified time.Time
+}
+
+// s3TestServer is a minimal S3 compatible stand-in that supports listing and
+// downloading the objects of a single bucket "foo" with path style URLs.
+type s3TestServer struct {
+	sync.Mutex
+	objects map[string]s3TestObject
+}
+
+func newS3TestServer() *s3TestServer {
+	return &s3TestServer{
+		objects: map[string]s3TestObject{},
+	}
+}
+
+func (s *s3TestServer) put(key, body string, modified time.Time) {
+	s.Lock()
+	s.objects[key] = s3TestObject{
+		body:     []byte(body),
+		modified: modified.UTC().Truncate(time.Second),
+	}
+	s.Unlock()
+}
+
+type s3TestContents struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	Size         int    `xml:"Size"`
+}
+
+type s3TestListResult struct {
+	XMLName     xml.Name         `xml:"ListBucketResult"`
+	Name        string           `xml:"Name"`
+	Prefix      string           `xml:"Prefix"`
+	IsTruncated bool             `xml:"IsTruncated"`
+	Contents    []s3TestContents `xml:"Contents"`
+}
+
+func (s *s3TestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if r.Method != "GET" {
+		http.Error(w, "method not supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/foo"), "/")
+	if len(key) == 0 {
+		prefix := r.URL.Query().Get("prefix")
+		result := s3TestListResult{Name: "foo", Prefix: prefix}
+		for k, obj := range s.objects {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			result.Contents = append(result.Contents, s3TestContents{
+				Key:          k,
+				LastModified: obj.modified.Format(time.RFC3339),
+				Size:         len(obj.body),
+			})
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+		return
+	}
+
+	obj, exists := s.objects[key]
+	if !exists {
+		http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+		return
+	}
+	w.Write(obj.body)
+}
+
+func newS3TestReader(t *testing.T, conf AmazonS3Config) *AmazonS3 {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	a, err := NewAmazonS3(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func newS3TestConfig(url string) AmazonS3Config {
+	conf := NewAmazonS3Config()
+	conf.Bucket = "foo"
+	conf.Endpoint = url
+	conf.ForcePathStyle = true
+	conf.Credentials.ID = "id"
+	conf.Credentials.Secret = "secret"
+	return conf
+}
+
+func readS3TestMessage(t *testing.T, a *AmazonS3, exp string) {
+	msg, err := a.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := string(msg.Get(0)); act != exp {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+	if err = a.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestAmazonS3BadPattern(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Patterns = []string{"[foo"}
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	if _, err := NewAmazonS3(conf, logger, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad pattern")
+	}
+}
+
+func TestAmazonS3ListFilters(t *testing.T) {
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	now := time.Now()
+	s.put("bar/old.txt", "old", now.Add(-time.Hour*2))
+	s.put("bar/second.txt", "second", now.Add(-time.Minute))
+	s.put("bar/first.txt", "first", now.Add(-time.Minute*2))
+	s.put("bar/ignored.log", "ignored", now)
+	s.put("baz/other.txt", "other", now)
+
+	conf := newS3TestConfig(server.URL)
+	conf.Prefix = "bar/"
+	conf.Patterns = []string{"bar/*.txt"}
+	conf.MaxAgeS = 3600
+
+	a := newS3TestReader(t, conf)
+	defer func() {
+		a.CloseAsync()
+		if err := a.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	readS3TestMessage(t, a, "first")
+	readS3TestMessage(t, a, "second")
+
+	if _, err := a.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestAmazonS3PollCheckpoint(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_s3_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	now := time.Now()
+	s.put("b", "first", now.Add(-time.Minute*2))
+	s.put("a", "second", now.Add(-time.Minute))
+	s.put("c", "third", now.Add(-time.Minute))
+
+	conf := newS3TestConfig(server.URL)
+	conf.PollPeriodS = 1
+	conf.CheckpointPath = filepath.Join(tmpDir, "checkpoint.json")
+
+	a := newS3TestReader(t, conf)
+
+	readS3TestMessage(t, a, "first")
+	readS3TestMessage(t, a, "second")
+	readS3TestMessage(t, a, "third")
+
+	if _, err = a.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTimeout)
+	}
+
+	s.put("d", "fourth", now)
+	readS3TestMessage(t, a, "fourth")
+
+	a.CloseAsync()
+	if err = a.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+	if _, err = a.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTypeClosed)
+	}
+
+	cpBytes, err := ioutil.ReadFile(conf.CheckpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp s3Checkpoint
+	if err = json.Unmarshal(cpBytes, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "d", cp.LastKey; exp != act {
+		t.Errorf("Wrong checkpoint key: %v != %v", act, exp)
+	}
+	if exp, act := now.UTC().Truncate(time.Second), cp.LastModified; !exp.Equal(act) {
+		t.Errorf("Wrong checkpoint modified time: %v != %v", act, exp)
+	}
+
+	s.put("e", "fifth", now)
+	s.put("0", "missed", now.Add(-time.Minute))
+
+	a = newS3TestReader(t, conf)
+	defer func() {
+		a.CloseAsync()
+		if err = a.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	readS3TestMessage(t, a, "fifth")
+}
+
+func TestAmazonS3PollLookback(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_s3_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	now := time.Now()
+	s.put("a", "first", now.Add(-time.Minute*2))
+	s.put("b", "second", now)
+
+	conf := newS3TestConfig(server.URL)
+	conf.PollPeriodS = 1
+	conf.LookbackS = 600
+	conf.CheckpointPath = filepath.Join(tmpDir, "checkpoint.json")
+
+	a := newS3TestReader(t, conf)
+
+	readS3TestMessage(t, a, "first")
+	readS3TestMessage(t, a, "second")
+
+	// An object that finished uploading after the checkpoint moved beyond it.
+	s.put("c", "late", now.Add(-time.Minute))
+	s.put("d", "too late", now.Add(-time.Hour))
+	readS3TestMessage(t, a, "late")
+
+	a.CloseAsync()
+	if err = a.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	cpBytes, err := ioutil.ReadFile(conf.CheckpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp s3Checkpoint
+	if err = json.Unmarshal(cpBytes, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "b", cp.LastKey; exp != act {
+		t.Errorf("Wrong checkpoint key: %v != %v", act, exp)
+	}
+	if exp, act := 3, len(cp.Seen); exp != act {
+		t.Errorf("Wrong count of seen keys: %v != %v", act, exp)
+	}
+
+	// Seen objects are not consumed again after a restart.
+	s.put("e", "fourth", now)
+	a = newS3TestReader(t, conf)
+	defer func() {
+		a.CloseAsync()
+		if err = a.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	readS3TestMessage(t, a, "fourth")
+	if _, err = a.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTimeout)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -24,10 +24,10 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
 	"github.com/Jeffail/benthos/lib/util/service/log"
 	"github.com/Jeffail/benthos/lib/util/service/metrics"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
 )
@@ -36,26 +36,28 @@ import (
 
 // AmazonSQSConfig is configuration values for the input type.
 type AmazonSQSConfig struct {
-	Region      string                     `json:"region" yaml:"region"`
-	URL         string                     `json:"url" yaml:"url"`
-	Credentials AmazonAWSCredentialsConfig `json:"credentials" yaml:"credentials"`
-	TimeoutS    int64                      `json:"timeout_s" yaml:"timeout_s"`
+	sess.Config    `json:",inline" yaml:",inline"`
+	URL            string `json:"url" yaml:"url"`
+	TimeoutS       int64  `json:"timeout_s" yaml:"timeout_s"`
+	MaxBatchCount  int    `json:"max_batch_count" yaml:"max_batch_count"`
+	BatchTimeoutMS int    `json:"batch_timeout_ms" yaml:"batch_timeout_ms"`
 }
 
 // NewAmazonSQSConfig creates a new Config with default values.
 func NewAmazonSQSConfig() AmazonSQSConfig {
 	return AmazonSQSConfig{
-		Region: "eu-west-1",
-		URL:    "",
-		Credentials: AmazonAWSCredentialsConfig{
-			ID:     "",
-			Secret: "",
-			Token:  "",
-		},
-		TimeoutS: 5,
+		Config:         sess.NewConfig(),
+		URL:            "",
+		TimeoutS:       5,
+		MaxBatchCount:  1,
+		BatchTimeoutMS: 0,
 	}
 }
 
+// sqsMaxBatchCount is the maximum number of messages that SQS will receive or
+// delete within a single request.
+const sqsMaxBatchCount = 10
+
 //------------------------------------------------------------------------------
 
 // AmazonSQS is a benthos reader.Type implementation that reads messages from an
@@ -91,19 +93,7 @@ func (a *AmazonSQS) Connect() error {
 		return nil
 	}
 
-	awsConf := aws.NewConfig()
-	if len(a.conf.Region) > 0 {
-		awsConf = awsConf.WithRegion(a.conf.Region)
-	}
-	if len(a.conf.Credentials.ID) > 0 {
-		awsConf = awsConf.WithCredentials(credentials.NewStaticCredentials(
-			a.conf.Credentials.ID,
-			a.conf.Credentials.Secret,
-			a.conf.Credentials.Token,
-		))
-	}
-
-	sess, err := session.NewSession(awsConf)
+	sess, err := a.conf.GetSession()
 	if err != nil {
 		return err
 	}
@@ -121,31 +111,55 @@ func (a *AmazonSQS) Read() (types.Message, error) {
 		return nil, types.ErrNotConnected
 	}
 
-	output, err := a.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
-		QueueUrl:            aws.String(a.conf.URL),
-		MaxNumberOfMessages: aws.Int64(1),
-		WaitTimeSeconds:     aws.Int64(a.conf.TimeoutS),
-	})
-	if err != nil {
-		return nil, err
-	}
-
 	msg := types.NewMessage(nil)
 
-	if len(output.Messages) == 0 {
-		return nil, types.ErrTimeout
-	}
+	// The first receive waits for messages, subsequent receives only fill the
+	// batch with messages that are immediately available until the batch
+	// timeout.
+	waitSeconds := a.conf.TimeoutS
+	deadline := time.Now().Add(time.Duration(a.conf.BatchTimeoutMS) * time.Millisecond)
+
+	received := 0
+	for {
+		count := a.conf.MaxBatchCount - received
+		if count > sqsMaxBatchCount {
+			count = sqsMaxBatchCount
+		} else if count < 1 {
+			count = 1
+		}
+
+		output, err := a.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(a.conf.URL),
+			MaxNumberOfMessages: aws.Int64(int64(count)),
+			WaitTimeSeconds:     aws.Int64(waitSeconds),
+		})
+		if err != nil {
+			if received > 0 {
+				break
+			}
+			return nil, err
+		}
 
-	for _, sqsMsg := range output.Messages {
-		if sqsMsg.ReceiptHandle != nil {
-			a.pendingHandles = append(a.pendingHandles, &sqs.DeleteMessageBatchRequestEntry{
-				Id:            sqsMsg.MessageId,
-				ReceiptHandle: sqsMsg.ReceiptHandle,
-			})
+		for _, sqsMsg := range output.Messages {
+			if sqsMsg.ReceiptHandle != nil {
+				a.pendingHandles = append(a.pendingHandles, &sqs.DeleteMessageBatchRequestEntry{
+					Id:            sqsMsg.MessageId,
+					ReceiptHandle: sqsMsg.ReceiptHandle,
+				})
+			}
+
+			if sqsMsg.Body != nil {
+				msg.Append([]byte(*sqsMsg.Body))
+			}
 		}
 
-		if sqsMsg.Body != nil {
-			msg.Append([]byte(*sqsMsg.Body))
+		received += len(output.Messages)
+		if len(output.Messages) == 0 || received >= a.conf.MaxBatchCount ||
+			(received > 0 && time.Now().After(deadline)) {
+			break
+		}
+		if received > 0 {
+			waitSeconds = 0
 		}
 	}
 
@@ -159,11 +173,30 @@ func (a *AmazonSQS) Read() (types.Message, error) {
 // Acknowledge confirms whether or not our unacknowledged messages have been
 // successfully propagated or not.
 func (a *AmazonSQS) Acknowledge(err error) error {
-	if _, err := a.sqs.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
-		QueueUrl: aws.String(a.conf.URL),
-		Entries:  a.pendingHandles,
-	}); err != nil {
-		return err
+	if err != nil {
+		// The messages are resent and remain pending.
+		return nil
+	}
+
+	for len(a.pendingHandles) > 0 {
+		entries := a.pendingHandles
+		if len(entries) > sqsMaxBatchCount {
+			entries = entries[:sqsMaxBatchCount]
+		}
+
+		res, err := a.sqs.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(a.conf.URL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return err
+		}
+		if len(res.Failed) > 0 {
+			a.stats.Incr("input.amazon_sqs.delete.failed", int64(len(res.Failed)))
+			a.log.Errorf("Failed to delete %v messages\n", len(res.Failed))
+		}
+
+		a.pendingHandles = a.pendingHandles[len(entries):]
 	}
 
 	a.pendingHandles = nil
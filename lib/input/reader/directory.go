@@ -0,0 +1,525 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// DirectoryConfig is configuration for the Directory input type.
+type DirectoryConfig struct {
+	Path               string `json:"path" yaml:"path"`
+	Delimiter          string `json:"delimiter" yaml:"delimiter"`
+	MaxBuffer          int    `json:"max_buffer" yaml:"max_buffer"`
+	MaxBatchCount      int    `json:"max_batch_count" yaml:"max_batch_count"`
+	StartFromBeginning bool   `json:"start_from_beginning" yaml:"start_from_beginning"`
+	PollPeriodMS       int    `json:"poll_period_ms" yaml:"poll_period_ms"`
+	CheckpointPath     string `json:"checkpoint_path" yaml:"checkpoint_path"`
+	CheckpointPeriodMS int    `json:"checkpoint_period_ms" yaml:"checkpoint_period_ms"`
+	FinishedAction     string `json:"finished_action" yaml:"finished_action"`
+	MoveToDir          string `json:"move_to_dir" yaml:"move_to_dir"`
+	InactivePeriodMS   int    `json:"inactive_period_ms" yaml:"inactive_period_ms"`
+}
+
+// NewDirectoryConfig creates a new DirectoryConfig with default values.
+func NewDirectoryConfig() DirectoryConfig {
+	return DirectoryConfig{
+		Path:               "",
+		Delimiter:          "",
+		MaxBuffer:          bufio.MaxScanTokenSize,
+		MaxBatchCount:      1,
+		StartFromBeginning: true,
+		PollPeriodMS:       1000,
+		CheckpointPath:     "./benthos_directory_checkpoint.json",
+		CheckpointPeriodMS: 1000,
+		FinishedAction:     "none",
+		MoveToDir:          "",
+		InactivePeriodMS:   60000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// dirCheckpoint is the persisted state of a file.
+type dirCheckpoint struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+// dirFile is a file that is being tailed.
+type dirFile struct {
+	id   string
+	path string
+	file *os.File
+
+	// Data read from the file that is yet to be consumed into messages, the
+	// offset of the start of that data within the file, and the offset of data
+	// that has been acknowledged.
+	buf        []byte
+	readOffset int64
+	ackOffset  int64
+
+	lastData time.Time
+	eof      bool
+	rotated  bool
+}
+
+// Directory is an input type that tails files matching a glob pattern,
+// following new and rotated files and checkpointing the offsets of each file.
+type Directory struct {
+	mut sync.Mutex
+
+	conf  DirectoryConfig
+	delim []byte
+
+	connected   bool
+	closed      bool
+	initialScan bool
+	lastScan    time.Time
+
+	files      map[string]*dirFile
+	order      []string
+	next       int
+	checkpoint map[string]dirCheckpoint
+
+	lastCheckpoint  time.Time
+	checkpointDirty bool
+
+	// The read offsets of files with messages that are yet to be
+	// acknowledged.
+	pending map[string]int64
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
+	stats metrics.Type
+	log   log.Modular
+}
+
+// NewDirectory creates a new Directory input type.
+func NewDirectory(conf DirectoryConfig, log log.Modular, stats metrics.Type) (*Directory, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("a path must be specified")
+	}
+	if _, err := filepath.Match(conf.Path, ""); err != nil {
+		return nil, fmt.Errorf("invalid path pattern: %v", err)
+	}
+	switch conf.FinishedAction {
+	case "none", "delete":
+	case "move":
+		if len(conf.MoveToDir) == 0 {
+			return nil, errors.New("a move_to_dir must be specified for the move finished action")
+		}
+	default:
+		return nil, fmt.Errorf("finished action not recognised: %v", conf.FinishedAction)
+	}
+
+	delim := []byte("\n")
+	if len(conf.Delimiter) > 0 {
+		delim = []byte(conf.Delimiter)
+	}
+	if conf.MaxBatchCount < 1 {
+		conf.MaxBatchCount = 1
+	}
+	if conf.MaxBuffer < 1 {
+		conf.MaxBuffer = bufio.MaxScanTokenSize
+	}
+
+	return &Directory{
+		conf:        conf,
+		delim:       delim,
+		initialScan: true,
+		files:       map[string]*dirFile{},
+		pending:     map[string]int64{},
+		checkpoint:  map[string]dirCheckpoint{},
+		closeChan:   make(chan struct{}),
+		stats:       stats,
+		log:         log.NewModule(".input.directory"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect loads the checkpoint file and opens the files that match the path.
+func (d *Directory) Connect() error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.closed {
+		return types.ErrTypeClosed
+	}
+	if d.connected {
+		return nil
+	}
+
+	if len(d.conf.CheckpointPath) > 0 {
+		cpBytes, err := ioutil.ReadFile(d.conf.CheckpointPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			if err = json.Unmarshal(cpBytes, &d.checkpoint); err != nil {
+				return fmt.Errorf("failed to parse checkpoint file: %v", err)
+			}
+		}
+	}
+
+	d.scan()
+	d.connected = true
+
+	d.log.Infof("Tailing files matching path: %v\n", d.conf.Path)
+	return nil
+}
+
+// openFile begins tailing a newly discovered file.
+func (d *Directory) openFile(id, path string, info os.FileInfo) {
+	offset := int64(0)
+	if cp, exists := d.checkpoint[id]; exists && cp.Offset <= info.Size() {
+		offset = cp.Offset
+	} else if d.initialScan && !d.conf.StartFromBeginning {
+		offset = info.Size()
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		d.log.Errorf("Failed to open file '%v': %v\n", path, err)
+		return
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		d.log.Errorf("Failed to seek file '%v': %v\n", path, err)
+		file.Close()
+		return
+	}
+
+	d.files[id] = &dirFile{
+		id:         id,
+		path:       path,
+		file:       file,
+		readOffset: offset,
+		ackOffset:  offset,
+		lastData:   time.Now(),
+	}
+	d.order = append(d.order, id)
+	d.checkpointDirty = true
+	d.stats.Incr("input.directory.file.opened", 1)
+	d.log.Infof("Tailing file '%v' from offset %v\n", path, offset)
+}
+
+// scan matches the path against the file system, opening new files, detecting
+// truncated files and marking files that no longer match as rotated.
+func (d *Directory) scan() {
+	d.lastScan = time.Now()
+
+	paths, err := filepath.Glob(d.conf.Path)
+	if err != nil {
+		d.log.Errorf("Failed to match path: %v\n", err)
+		return
+	}
+
+	seen := map[string]struct{}{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		id := dirFileID(path, info)
+		seen[id] = struct{}{}
+
+		f, exists := d.files[id]
+		if !exists {
+			d.openFile(id, path, info)
+			continue
+		}
+		f.path = path
+
+		// A file that shrinks has been truncated and is read from the start.
+		if info.Size() < f.readOffset+int64(len(f.buf)) {
+			if _, err = f.file.Seek(0, io.SeekStart); err != nil {
+				d.log.Errorf("Failed to seek file '%v': %v\n", path, err)
+				continue
+			}
+			d.log.Warnf("File '%v' was truncated, reading from the start\n", path)
+			f.buf = nil
+			f.readOffset = 0
+			f.ackOffset = 0
+			f.eof = false
+			delete(d.pending, id)
+			d.checkpointDirty = true
+		}
+	}
+
+	for id, f := range d.files {
+		_, exists := seen[id]
+		f.rotated = !exists
+	}
+	d.initialScan = false
+}
+
+// readParts consumes up to the maximum batch count of delimited parts from a
+// file. The final part of a file without a trailing delimiter is only consumed
+// once the file is about to be finished.
+func (d *Directory) readParts(f *dirFile) [][]byte {
+	var parts [][]byte
+	chunk := make([]byte, 32*1024)
+
+	consume := func(n, skip int) {
+		if n > 0 {
+			part := make([]byte, n)
+			copy(part, f.buf[:n])
+			parts = append(parts, part)
+		}
+		f.buf = f.buf[n+skip:]
+		f.readOffset += int64(n + skip)
+	}
+
+	for len(parts) < d.conf.MaxBatchCount {
+		if i := bytes.Index(f.buf, d.delim); i >= 0 {
+			consume(i, len(d.delim))
+			continue
+		}
+		if len(f.buf) >= d.conf.MaxBuffer {
+			consume(len(f.buf), 0)
+			continue
+		}
+
+		n, err := f.file.Read(chunk)
+		if n > 0 {
+			f.buf = append(f.buf, chunk[:n]...)
+			f.lastData = time.Now()
+			f.eof = false
+			continue
+		}
+		if err != nil && err != io.EOF {
+			d.log.Errorf("Failed to read file '%v': %v\n", f.path, err)
+		}
+		f.eof = true
+		if len(f.buf) > 0 && d.finishing(f) {
+			consume(len(f.buf), 0)
+		}
+		break
+	}
+	return parts
+}
+
+// finishing returns true if a file will not receive further data, which is
+// when it has been rotated or when it has been inactive for the inactive
+// period and a finished action is set.
+func (d *Directory) finishing(f *dirFile) bool {
+	if f.rotated {
+		return true
+	}
+	return d.conf.FinishedAction != "none" &&
+		time.Since(f.lastData) >= time.Duration(d.conf.InactivePeriodMS)*time.Millisecond
+}
+
+// finishFiles closes files that have been completely read and acknowledged and
+// will not receive further data, applying the finished action to inactive
+// files that still match the path.
+func (d *Directory) finishFiles() {
+	var order []string
+	for _, id := range d.order {
+		f := d.files[id]
+		if !f.eof || len(f.buf) > 0 || f.readOffset != f.ackOffset || !d.finishing(f) {
+			order = append(order, id)
+			continue
+		}
+
+		if !f.rotated {
+			// Only act on the path if it still refers to the same file.
+			info, err := os.Stat(f.path)
+			if err != nil || dirFileID(f.path, info) != id {
+				f.rotated = true
+			}
+		}
+		if !f.rotated {
+			var err error
+			switch d.conf.FinishedAction {
+			case "delete":
+				err = os.Remove(f.path)
+			case "move":
+				err = os.Rename(f.path, filepath.Join(d.conf.MoveToDir, filepath.Base(f.path)))
+			}
+			if err != nil {
+				d.log.Errorf("Failed to %v finished file '%v': %v\n", d.conf.FinishedAction, f.path, err)
+				order = append(order, id)
+				continue
+			}
+		}
+
+		f.file.Close()
+		delete(d.files, id)
+		d.checkpointDirty = true
+		d.stats.Incr("input.directory.file.finished", 1)
+		d.log.Infof("Finished reading file '%v'\n", f.path)
+	}
+	d.order = order
+}
+
+// writeCheckpoint writes the acknowledged offsets of all files to the
+// checkpoint file when they have changed and either the checkpoint period has
+// passed or force is true.
+func (d *Directory) writeCheckpoint(force bool) {
+	if len(d.conf.CheckpointPath) == 0 || !d.checkpointDirty {
+		return
+	}
+	if !force && time.Since(d.lastCheckpoint) < time.Duration(d.conf.CheckpointPeriodMS)*time.Millisecond {
+		return
+	}
+
+	checkpoint := make(map[string]dirCheckpoint, len(d.files))
+	for id, f := range d.files {
+		checkpoint[id] = dirCheckpoint{Path: f.path, Offset: f.ackOffset}
+	}
+	cpBytes, err := json.Marshal(checkpoint)
+	if err == nil {
+		tmpPath := d.conf.CheckpointPath + ".tmp"
+		if err = ioutil.WriteFile(tmpPath, cpBytes, 0644); err == nil {
+			err = os.Rename(tmpPath, d.conf.CheckpointPath)
+		}
+	}
+	if err != nil {
+		d.log.Errorf("Failed to write checkpoint file: %v\n", err)
+		d.stats.Incr("input.directory.checkpoint.error", 1)
+		return
+	}
+
+	d.checkpoint = checkpoint
+	d.checkpointDirty = false
+	d.lastCheckpoint = time.Now()
+}
+
+// Read attempts to read a message of delimited parts from the next file with
+// available data. If no file has data the path is scanned again after the poll
+// period.
+func (d *Directory) Read() (types.Message, error) {
+	d.mut.Lock()
+	if d.closed {
+		d.mut.Unlock()
+		return nil, types.ErrTypeClosed
+	}
+	if !d.connected {
+		d.mut.Unlock()
+		return nil, types.ErrNotConnected
+	}
+
+	if time.Since(d.lastScan) >= time.Duration(d.conf.PollPeriodMS)*time.Millisecond {
+		d.scan()
+	}
+
+	for range d.order {
+		if d.next >= len(d.order) {
+			d.next = 0
+		}
+		f := d.files[d.order[d.next]]
+		d.next++
+
+		if parts := d.readParts(f); len(parts) > 0 {
+			d.pending[f.id] = f.readOffset
+			d.mut.Unlock()
+			return types.NewMessage(parts), nil
+		}
+	}
+
+	d.finishFiles()
+	d.writeCheckpoint(false)
+	d.mut.Unlock()
+
+	select {
+	case <-time.After(time.Duration(d.conf.PollPeriodMS) * time.Millisecond):
+	case <-d.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+
+	d.mut.Lock()
+	if !d.closed {
+		d.scan()
+	}
+	d.mut.Unlock()
+	return nil, types.ErrTimeout
+}
+
+// Acknowledge advances the acknowledged offsets of the files of all messages
+// read since the last acknowledgement once they have been successfully
+// propagated.
+func (d *Directory) Acknowledge(err error) error {
+	if err != nil {
+		return nil
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	for id, offset := range d.pending {
+		if f, exists := d.files[id]; exists {
+			f.ackOffset = offset
+			d.checkpointDirty = true
+		}
+	}
+	d.pending = map[string]int64{}
+	d.writeCheckpoint(false)
+	return nil
+}
+
+// CloseAsync shuts down the Directory input, writing a final checkpoint and
+// closing all files.
+func (d *Directory) CloseAsync() {
+	d.closedOnce.Do(func() {
+		close(d.closeChan)
+	})
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.closed {
+		return
+	}
+	d.closed = true
+
+	d.writeCheckpoint(true)
+	for _, f := range d.files {
+		f.file.Close()
+	}
+	d.files = map[string]*dirFile{}
+	d.order = nil
+}
+
+// WaitForClose blocks until the Directory input has closed down.
+func (d *Directory) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
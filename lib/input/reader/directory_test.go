@@ -0,0 +1,314 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func newDirectoryTestReader(t *testing.T, conf DirectoryConfig) *Directory {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	d, err := NewDirectory(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func readDirectoryParts(t *testing.T, d *Directory, count int) []string {
+	var parts []string
+	deadline := time.Now().Add(time.Second * 5)
+	for len(parts) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for parts, received: %q", parts)
+		}
+		msg, err := d.Read()
+		if err == types.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range msg.GetAll() {
+			parts = append(parts, string(p))
+		}
+		if err = d.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return parts
+}
+
+func appendToFile(t *testing.T, path, content string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirectoryBadConfig(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	confs := []DirectoryConfig{NewDirectoryConfig(), NewDirectoryConfig(), NewDirectoryConfig()}
+	confs[1].Path = "[.log"
+	confs[2].Path = "*.log"
+	confs[2].FinishedAction = "move"
+
+	for i, conf := range confs {
+		if _, err := NewDirectory(conf, logger, metrics.DudType{}); err == nil {
+			t.Errorf("Expected error from config %v", i)
+		}
+	}
+}
+
+func TestDirectoryTailAndRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_directory_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "app.log")
+	appendToFile(t, logPath, "foo\nbar\nba")
+
+	conf := NewDirectoryConfig()
+	conf.Path = filepath.Join(dir, "*.log")
+	conf.PollPeriodMS = 1
+	conf.CheckpointPath = filepath.Join(dir, "checkpoint.json")
+	conf.CheckpointPeriodMS = 0
+
+	d := newDirectoryTestReader(t, conf)
+
+	if exp, act := []string{"foo", "bar"}, readDirectoryParts(t, d, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong parts: %q != %q", act, exp)
+	}
+
+	// An incomplete line is not read until it is delimited.
+	if _, err = d.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTimeout)
+	}
+
+	appendToFile(t, logPath, "z\n")
+	if exp, act := []string{"baz"}, readDirectoryParts(t, d, 1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong parts: %q != %q", act, exp)
+	}
+
+	// Rotate the file, where the remainder of the old file is still read.
+	if err = os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendToFile(t, logPath+".1", "qux\nquz")
+	appendToFile(t, logPath, "new\n")
+
+	act := readDirectoryParts(t, d, 3)
+	sort.Strings(act)
+	if exp := []string{"new", "qux", "quz"}; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong parts: %q != %q", act, exp)
+	}
+
+	// Unacknowledged parts are not checkpointed.
+	appendToFile(t, logPath, "unacked\n")
+	msg, err := d.Read()
+	for err == types.ErrTimeout {
+		msg, err = d.Read()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "unacked", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong part: %v != %v", act, exp)
+	}
+	d.CloseAsync()
+
+	if _, err = d.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+
+	appendToFile(t, logPath, "after\n")
+
+	d = newDirectoryTestReader(t, conf)
+	defer d.CloseAsync()
+
+	if exp, act := []string{"unacked", "after"}, readDirectoryParts(t, d, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong parts: %q != %q", act, exp)
+	}
+}
+
+func TestDirectoryAcknowledgeMultipleFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_directory_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	appendToFile(t, filepath.Join(dir, "a.log"), "foo\n")
+	appendToFile(t, filepath.Join(dir, "b.log"), "bar\n")
+
+	conf := NewDirectoryConfig()
+	conf.Path = filepath.Join(dir, "*.log")
+	conf.PollPeriodMS = 1
+	conf.StartFromBeginning = true
+
+	d := newDirectoryTestReader(t, conf)
+	defer d.CloseAsync()
+
+	// Read a message from each file before acknowledging both at once.
+	var parts []string
+	for len(parts) < 2 {
+		msg, rerr := d.Read()
+		if rerr == types.ErrTimeout {
+			continue
+		}
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		parts = append(parts, string(msg.Get(0)))
+	}
+	sort.Strings(parts)
+	if exp := []string{"bar", "foo"}; !reflect.DeepEqual(exp, parts) {
+		t.Errorf("Wrong parts: %q != %q", parts, exp)
+	}
+
+	if err = d.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	for _, f := range d.files {
+		if exp, act := int64(4), f.ackOffset; exp != act {
+			t.Errorf("Wrong acknowledged offset of '%v': %v != %v", f.path, act, exp)
+		}
+	}
+}
+
+func TestDirectoryTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_directory_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "app.log")
+	appendToFile(t, logPath, "foo\nbar\n")
+
+	conf := NewDirectoryConfig()
+	conf.Path = filepath.Join(dir, "*.log")
+	conf.PollPeriodMS = 1
+	conf.MaxBatchCount = 10
+	conf.CheckpointPath = ""
+
+	d := newDirectoryTestReader(t, conf)
+	defer d.CloseAsync()
+
+	if exp, act := []string{"foo", "bar"}, readDirectoryParts(t, d, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong parts: %q != %q", act, exp)
+	}
+
+	if err = ioutil.WriteFile(logPath, []byte("baz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"baz"}, readDirectoryParts(t, d, 1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong parts: %q != %q", act, exp)
+	}
+}
+
+func TestDirectoryFinishedActions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_directory_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inDir := filepath.Join(dir, "in")
+	doneDir := filepath.Join(dir, "done")
+	for _, d := range []string{inDir, doneDir} {
+		if err = os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	appendToFile(t, filepath.Join(inDir, "a.json"), "foo\nbar")
+	appendToFile(t, filepath.Join(inDir, "b.json"), "baz\n")
+
+	conf := NewDirectoryConfig()
+	conf.Path = filepath.Join(inDir, "*.json")
+	conf.PollPeriodMS = 1
+	conf.CheckpointPath = ""
+	conf.FinishedAction = "move"
+	conf.MoveToDir = doneDir
+	conf.InactivePeriodMS = 10
+
+	d := newDirectoryTestReader(t, conf)
+	defer d.CloseAsync()
+
+	act := readDirectoryParts(t, d, 3)
+	sort.Strings(act)
+	if exp := []string{"bar", "baz", "foo"}; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong parts: %q != %q", act, exp)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		if _, err = d.Read(); err != types.ErrTimeout {
+			t.Fatalf("Wrong error: %v != %v", err, types.ErrTimeout)
+		}
+		remaining, _ := filepath.Glob(conf.Path)
+		if len(remaining) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for files to be moved: %v", remaining)
+		}
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(doneDir, "a.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar", string(content); exp != act {
+		t.Errorf("Wrong moved file content: %v != %v", act, exp)
+	}
+	if _, err = os.Stat(filepath.Join(doneDir, "b.json")); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,224 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// GCPPubSubConfig contains configuration values for the input type.
+type GCPPubSubConfig struct {
+	ProjectID              string `json:"project" yaml:"project"`
+	SubscriptionID         string `json:"subscription" yaml:"subscription"`
+	MaxOutstandingMessages int    `json:"max_outstanding_messages" yaml:"max_outstanding_messages"`
+	MaxOutstandingBytes    int    `json:"max_outstanding_bytes" yaml:"max_outstanding_bytes"`
+	MaxExtensionMS         int64  `json:"max_extension_ms" yaml:"max_extension_ms"`
+}
+
+// NewGCPPubSubConfig creates a new Config with default values.
+func NewGCPPubSubConfig() GCPPubSubConfig {
+	return GCPPubSubConfig{
+		ProjectID:              "",
+		SubscriptionID:         "",
+		MaxOutstandingMessages: pubsub.DefaultReceiveSettings.MaxOutstandingMessages,
+		MaxOutstandingBytes:    pubsub.DefaultReceiveSettings.MaxOutstandingBytes,
+		MaxExtensionMS:         int64(pubsub.DefaultReceiveSettings.MaxExtension / time.Millisecond),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GCPPubSub is a benthos reader.Type implementation that reads messages from
+// a GCP Cloud Pub/Sub subscription.
+type GCPPubSub struct {
+	conf GCPPubSubConfig
+
+	client    *pubsub.Client
+	msgsChan  chan *pubsub.Message
+	subCancel func()
+	subDone   chan struct{}
+	cMut      sync.Mutex
+
+	unAckMsgs []*pubsub.Message
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewGCPPubSub creates a new GCP pubsub reader.Type.
+func NewGCPPubSub(
+	conf GCPPubSubConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*GCPPubSub, error) {
+	return &GCPPubSub{
+		conf:      conf,
+		closeChan: make(chan struct{}),
+		log:       log.NewModule(".input.gcp_pubsub"),
+		stats:     stats,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target subscription.
+func (c *GCPPubSub) Connect() error {
+	c.cMut.Lock()
+	defer c.cMut.Unlock()
+
+	if c.msgsChan != nil {
+		return nil
+	}
+
+	client, err := pubsub.NewClient(context.Background(), c.conf.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	sub := client.Subscription(c.conf.SubscriptionID)
+	sub.ReceiveSettings.MaxOutstandingMessages = c.conf.MaxOutstandingMessages
+	sub.ReceiveSettings.MaxOutstandingBytes = c.conf.MaxOutstandingBytes
+	sub.ReceiveSettings.MaxExtension = time.Duration(c.conf.MaxExtensionMS) * time.Millisecond
+
+	subCtx, subCancel := context.WithCancel(context.Background())
+	msgsChan := make(chan *pubsub.Message)
+	subDone := make(chan struct{})
+
+	go func() {
+		// Receive uses a streaming pull and keeps extending the ack deadline
+		// of messages until they are acked, nacked or reach MaxExtension.
+		rerr := sub.Receive(subCtx, func(ctx context.Context, m *pubsub.Message) {
+			select {
+			case msgsChan <- m:
+			case <-ctx.Done():
+				m.Nack()
+			}
+		})
+		if rerr != nil && rerr != context.Canceled {
+			c.log.Errorf("Subscription error: %v\n", rerr)
+		}
+		close(msgsChan)
+		close(subDone)
+	}()
+
+	c.client = client
+	c.msgsChan = msgsChan
+	c.subCancel = subCancel
+	c.subDone = subDone
+
+	c.log.Infof("Receiving GCP Cloud Pub/Sub messages from subscription: %v\n", c.conf.SubscriptionID)
+	return nil
+}
+
+func (c *GCPPubSub) disconnect() {
+	c.cMut.Lock()
+	defer c.cMut.Unlock()
+
+	if c.msgsChan == nil {
+		return
+	}
+	c.subCancel()
+	<-c.subDone
+	c.client.Close()
+
+	c.client = nil
+	c.msgsChan = nil
+	c.subCancel = nil
+	c.subDone = nil
+}
+
+// Read attempts to read a new message from the target subscription.
+func (c *GCPPubSub) Read() (types.Message, error) {
+	c.cMut.Lock()
+	msgsChan := c.msgsChan
+	c.cMut.Unlock()
+
+	if msgsChan == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	var gMsg *pubsub.Message
+	var open bool
+	select {
+	case gMsg, open = <-msgsChan:
+		if !open {
+			c.disconnect()
+			return nil, types.ErrNotConnected
+		}
+	case <-c.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+
+	c.unAckMsgs = append(c.unAckMsgs, gMsg)
+	return types.NewMessage([][]byte{gMsg.Data}), nil
+}
+
+// Acknowledge instructs whether messages read since the last Acknowledge call
+// were successfully propagated. Unsuccessful messages are nacked so that they
+// are redelivered.
+func (c *GCPPubSub) Acknowledge(err error) error {
+	for _, m := range c.unAckMsgs {
+		if err == nil {
+			m.Ack()
+		} else {
+			m.Nack()
+		}
+	}
+	c.unAckMsgs = nil
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (c *GCPPubSub) CloseAsync() {
+	c.closedOnce.Do(func() {
+		close(c.closeChan)
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs. If the timeout occurs then an error is returned.
+func (c *GCPPubSub) WaitForClose(timeout time.Duration) error {
+	closed := make(chan struct{})
+	go func() {
+		c.disconnect()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
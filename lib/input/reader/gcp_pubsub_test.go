@@ -0,0 +1,123 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build integration
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+// These tests require a Pub/Sub emulator, which can be started with:
+//
+//   gcloud beta emulators pubsub start --host-port=localhost:8085
+//   export PUBSUB_EMULATOR_HOST=localhost:8085
+
+func setupGCPPubSubTest(t *testing.T, id string) (*pubsub.Client, *pubsub.Topic) {
+	if len(os.Getenv("PUBSUB_EMULATOR_HOST")) == 0 {
+		t.Skip("PUBSUB_EMULATOR_HOST is not set")
+	}
+
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "benthos-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := client.CreateTopic(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.CreateSubscription(ctx, id, pubsub.SubscriptionConfig{
+		Topic:       topic,
+		AckDeadline: time.Second * 10,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return client, topic
+}
+
+func teardownGCPPubSubTest(client *pubsub.Client, topic *pubsub.Topic, id string) {
+	ctx := context.Background()
+	client.Subscription(id).Delete(ctx)
+	topic.Stop()
+	topic.Delete(ctx)
+	client.Close()
+}
+
+func TestGCPPubSubReadAck(t *testing.T) {
+	id := "benthos_test_input_read"
+	client, topic := setupGCPPubSubTest(t, id)
+	defer teardownGCPPubSubTest(client, topic, id)
+
+	ctx := context.Background()
+	if _, err := topic.Publish(ctx, &pubsub.Message{
+		Data: []byte("hello world"),
+	}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewGCPPubSubConfig()
+	conf.ProjectID = "benthos-test"
+	conf.SubscriptionID = id
+
+	r, err := NewGCPPubSub(conf, log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"}), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nacked messages should be redelivered.
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+	if err = r.Acknowledge(errors.New("foo")); err != nil {
+		t.Error(err)
+	}
+
+	if msg, err = r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(msg.Get(0)); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+	if err = r.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
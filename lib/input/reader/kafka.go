@@ -35,23 +35,39 @@ import (
 
 // KafkaConfig is configuration for the Kafka input type.
 type KafkaConfig struct {
-	Addresses       []string `json:"addresses" yaml:"addresses"`
-	ClientID        string   `json:"client_id" yaml:"client_id"`
-	ConsumerGroup   string   `json:"consumer_group" yaml:"consumer_group"`
-	Topic           string   `json:"topic" yaml:"topic"`
-	Partition       int32    `json:"partition" yaml:"partition"`
-	StartFromOldest bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	Addresses             []string `json:"addresses" yaml:"addresses"`
+	ClientID              string   `json:"client_id" yaml:"client_id"`
+	ConsumerGroup         string   `json:"consumer_group" yaml:"consumer_group"`
+	Topic                 string   `json:"topic" yaml:"topic"`
+	Partition             int32    `json:"partition" yaml:"partition"`
+	StartFromOldest       bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	StartFromOffset       int64    `json:"start_from_offset" yaml:"start_from_offset"`
+	StartFromTimestampMS  int64    `json:"start_from_timestamp_ms" yaml:"start_from_timestamp_ms"`
+	CommitPeriodMS        int      `json:"commit_period_ms" yaml:"commit_period_ms"`
+	CommitBatchSize       int      `json:"commit_batch_size" yaml:"commit_batch_size"`
+	MaxProcessingPeriodMS int      `json:"max_processing_period_ms" yaml:"max_processing_period_ms"`
+	MaxBatchCount         int      `json:"max_batch_count" yaml:"max_batch_count"`
+	BatchTimeoutMS        int      `json:"batch_timeout_ms" yaml:"batch_timeout_ms"`
+	TargetVersion         string   `json:"target_version" yaml:"target_version"`
 }
 
 // NewKafkaConfig creates a new KafkaConfig with default values.
 func NewKafkaConfig() KafkaConfig {
 	return KafkaConfig{
-		Addresses:       []string{"localhost:9092"},
-		ClientID:        "benthos_kafka_input",
-		ConsumerGroup:   "benthos_consumer_group",
-		Topic:           "benthos_stream",
-		Partition:       0,
-		StartFromOldest: true,
+		Addresses:             []string{"localhost:9092"},
+		ClientID:              "benthos_kafka_input",
+		ConsumerGroup:         "benthos_consumer_group",
+		Topic:                 "benthos_stream",
+		Partition:             0,
+		StartFromOldest:       true,
+		StartFromOffset:       -1,
+		StartFromTimestampMS:  0,
+		CommitPeriodMS:        1000,
+		CommitBatchSize:       1,
+		MaxProcessingPeriodMS: 100,
+		MaxBatchCount:         1,
+		BatchTimeoutMS:        0,
+		TargetVersion:         "",
 	}
 }
 
@@ -65,7 +81,16 @@ type Kafka struct {
 
 	sMut sync.Mutex
 
-	offset int64
+	offset      int64
+	nextOffset  int64
+	nextCount   int
+	pendingAcks int
+	lastCommit  time.Time
+	resetting   bool
+
+	version       sarama.KafkaVersion
+	initialTarget KafkaOffsetTarget
+	commitPeriod  time.Duration
 
 	addresses []string
 	conf      KafkaConfig
@@ -78,10 +103,12 @@ func NewKafka(
 	conf KafkaConfig, log log.Modular, stats metrics.Type,
 ) (*Kafka, error) {
 	k := Kafka{
-		offset: 0,
-		conf:   conf,
-		stats:  stats,
-		log:    log.NewModule(".input.kafka"),
+		offset:       0,
+		version:      sarama.NewConfig().Version,
+		commitPeriod: time.Duration(conf.CommitPeriodMS) * time.Millisecond,
+		conf:         conf,
+		stats:        stats,
+		log:          log.NewModule(".input.kafka"),
 	}
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
@@ -90,6 +117,18 @@ func NewKafka(
 			}
 		}
 	}
+	if len(conf.TargetVersion) > 0 {
+		var err error
+		if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
+			return nil, err
+		}
+	}
+	k.initialTarget = newKafkaInitialOffsetTarget(
+		conf.StartFromOffset, conf.StartFromTimestampMS, conf.StartFromOldest,
+	)
+	if err := k.initialTarget.checkVersion(k.version); err != nil {
+		return nil, err
+	}
 	return &k, nil
 }
 
@@ -100,21 +139,30 @@ func NewKafka(
 func (k *Kafka) closeClients() {
 	k.sMut.Lock()
 	defer k.sMut.Unlock()
+	k.closeClientsLocked()
+}
 
+// closeClientsLocked closes the kafka clients after committing any outstanding
+// offset, and must be called with sMut held.
+func (k *Kafka) closeClientsLocked() {
 	if k.partConsumer != nil {
 		// NOTE: Needs draining before destroying.
 		k.partConsumer.AsyncClose()
-		defer func() {
-			// Drain both channels
-			for range k.partConsumer.Messages() {
-			}
-			for range k.partConsumer.Errors() {
-			}
 
-			k.partConsumer = nil
-		}()
+		// Drain both channels
+		for range k.partConsumer.Messages() {
+		}
+		for range k.partConsumer.Errors() {
+		}
+
+		k.partConsumer = nil
 	}
 	if k.coordinator != nil {
+		if k.pendingAcks > 0 {
+			if err := k.commit(); err != nil {
+				k.log.Errorf("Failed to commit offset: %v\n", err)
+			}
+		}
 		k.coordinator.Close()
 		k.coordinator = nil
 	}
@@ -124,6 +172,64 @@ func (k *Kafka) closeClients() {
 	}
 }
 
+// commit commits the offset of the last acknowledged message, and must be
+// called with sMut held.
+func (k *Kafka) commit() error {
+	if err := commitKafkaOffsets(
+		k.coordinator, 0, k.conf.ConsumerGroup, k.conf.Topic,
+		map[int32]int64{k.conf.Partition: k.offset},
+	); err != nil {
+		return err
+	}
+	k.pendingAcks = 0
+	k.lastCommit = time.Now()
+	return nil
+}
+
+// readKafkaBatch appends messages from a channel to a batch until it reaches a
+// maximum count, the timeout elapses or the channel is closed. With a timeout
+// of zero only messages that are immediately available are appended.
+func readKafkaBatch(
+	c <-chan *sarama.ConsumerMessage,
+	batch []*sarama.ConsumerMessage,
+	maxCount int,
+	timeout time.Duration,
+) []*sarama.ConsumerMessage {
+	if len(batch) >= maxCount {
+		return batch
+	}
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	for len(batch) < maxCount {
+		if timedOut == nil {
+			select {
+			case data, open := <-c:
+				if !open {
+					return batch
+				}
+				batch = append(batch, data)
+			default:
+				return batch
+			}
+			continue
+		}
+		select {
+		case data, open := <-c:
+			if !open {
+				return batch
+			}
+			batch = append(batch, data)
+		case <-timedOut:
+			return batch
+		}
+	}
+	return batch
+}
+
 //------------------------------------------------------------------------------
 
 // Connect establishes a Kafka connection.
@@ -144,8 +250,10 @@ func (k *Kafka) Connect() error {
 
 	config := sarama.NewConfig()
 	config.ClientID = k.conf.ClientID
+	config.Version = k.version
 	config.Net.DialTimeout = time.Second
 	config.Consumer.Return.Errors = true
+	config.Consumer.MaxProcessingTime = time.Duration(k.conf.MaxProcessingPeriodMS) * time.Millisecond
 
 	k.client, err = sarama.NewClient(k.addresses, config)
 	if err != nil {
@@ -163,14 +271,22 @@ func (k *Kafka) Connect() error {
 		return err
 	}
 
-	offsetReq := sarama.OffsetFetchRequest{}
-	offsetReq.ConsumerGroup = k.conf.ConsumerGroup
-	offsetReq.AddPartition(k.conf.Topic, k.conf.Partition)
-
-	if offsetRes, err := k.coordinator.FetchOffset(&offsetReq); err == nil {
-		offsetBlock := offsetRes.Blocks[k.conf.Topic][k.conf.Partition]
-		if offsetBlock.Err == sarama.ErrNoError {
-			k.offset = offsetBlock.Offset
+	k.offset = -1
+	if offsets, err := fetchKafkaOffsets(
+		k.coordinator, 0, k.conf.ConsumerGroup, k.conf.Topic,
+		[]int32{k.conf.Partition},
+	); err == nil {
+		k.offset = offsets[k.conf.Partition]
+	}
+	if k.offset < 0 {
+		k.log.Infof(
+			"No offset committed for topic %s, partition %v, resolving initial offset\n",
+			k.conf.Topic, k.conf.Partition,
+		)
+		if k.offset, err = k.initialTarget.resolve(
+			k.client, k.conf.Topic, k.conf.Partition,
+		); err != nil {
+			return err
 		}
 	}
 
@@ -205,6 +321,10 @@ func (k *Kafka) Connect() error {
 		return err
 	}
 
+	k.nextOffset = k.offset
+	k.pendingAcks = 0
+	k.resetting = false
+	k.lastCommit = time.Now()
 	k.partConsumer = partConsumer
 	k.log.Infof("Receiving Kafka messages from addresses: %s\n", k.addresses)
 
@@ -232,48 +352,60 @@ func (k *Kafka) Read() (types.Message, error) {
 		return nil, types.ErrNotConnected
 	}
 
+	var batch []*sarama.ConsumerMessage
 	data, open := <-partConsumer.Messages()
+	if open {
+		batch = append(batch, data)
+		batch = readKafkaBatch(
+			partConsumer.Messages(), batch, k.conf.MaxBatchCount,
+			time.Duration(k.conf.BatchTimeoutMS)*time.Millisecond,
+		)
+	}
+
+	k.sMut.Lock()
+	defer k.sMut.Unlock()
+
+	// If the consumer was closed by an offset reset then any message read is
+	// discarded and we reconnect from the new offset.
+	if k.resetting && k.partConsumer != partConsumer {
+		return nil, types.ErrNotConnected
+	}
 	if !open {
 		return nil, types.ErrTypeClosed
 	}
-	k.offset = data.Offset + 1
-	return types.NewMessage([][]byte{data.Value}), nil
+	msg := types.NewMessage(nil)
+	for _, data := range batch {
+		msg.Append(data.Value)
+	}
+	k.nextOffset = batch[len(batch)-1].Offset + 1
+	k.nextCount = len(batch)
+	return msg, nil
 }
 
-// Acknowledge instructs whether the current offset should be committed.
+// Acknowledge instructs whether the offset of the last read message should be
+// committed. Offsets are committed once either the commit batch size or the
+// commit period has been reached.
 func (k *Kafka) Acknowledge(err error) error {
 	if err != nil {
 		return nil
 	}
 
-	var coordinator *sarama.Broker
-
 	k.sMut.Lock()
-	coordinator = k.coordinator
-	k.sMut.Unlock()
+	defer k.sMut.Unlock()
 
-	if coordinator == nil {
+	if k.coordinator == nil {
 		return types.ErrNotConnected
 	}
 
-	commitReq := sarama.OffsetCommitRequest{}
-	commitReq.ConsumerGroup = k.conf.ConsumerGroup
-	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, "")
-
-	commitRes, err := coordinator.CommitOffset(&commitReq)
-	if err == nil {
-		err = commitRes.Errors[k.conf.Topic][k.conf.Partition]
-		if err == sarama.ErrNoError {
-			err = nil
-		}
+	k.offset = k.nextOffset
+	k.pendingAcks += k.nextCount
+	if k.pendingAcks < k.conf.CommitBatchSize && time.Since(k.lastCommit) < k.commitPeriod {
+		return nil
 	}
 
-	if err != nil {
+	if err = k.commit(); err != nil {
 		k.log.Errorf("Failed to commit offset: %v\n", err)
 
-		k.sMut.Lock()
-		defer k.sMut.Unlock()
-
 		// Attempt to reconnect
 		if newCoord, err := k.client.Coordinator(k.conf.ConsumerGroup); err != nil {
 			k.log.Errorf("Failed to create new coordinator: %v\n", err)
@@ -286,6 +418,40 @@ func (k *Kafka) Acknowledge(err error) error {
 	return nil
 }
 
+// ResetOffsets commits a new offset for the consumed partition and reconnects
+// in order to resume consuming from it.
+func (k *Kafka) ResetOffsets(target KafkaOffsetTarget) error {
+	if err := target.checkVersion(k.version); err != nil {
+		return err
+	}
+
+	k.sMut.Lock()
+	defer k.sMut.Unlock()
+
+	if k.client == nil || k.coordinator == nil {
+		return types.ErrNotConnected
+	}
+
+	offset, err := target.resolve(k.client, k.conf.Topic, k.conf.Partition)
+	if err != nil {
+		return err
+	}
+
+	k.offset = offset
+	k.nextOffset = offset
+	if err = k.commit(); err != nil {
+		return err
+	}
+
+	k.log.Infof(
+		"Reset offset of topic %s, partition %v to %v\n",
+		k.conf.Topic, k.conf.Partition, offset,
+	)
+	k.resetting = true
+	k.closeClientsLocked()
+	return nil
+}
+
 // CloseAsync shuts down the Kafka input and stops processing requests.
 func (k *Kafka) CloseAsync() {
 	go k.closeClients()
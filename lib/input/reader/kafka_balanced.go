@@ -21,6 +21,7 @@
 package reader
 
 import (
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -36,21 +37,37 @@ import (
 
 // KafkaBalancedConfig is configuration for the KafkaBalanced input type.
 type KafkaBalancedConfig struct {
-	Addresses       []string `json:"addresses" yaml:"addresses"`
-	ClientID        string   `json:"client_id" yaml:"client_id"`
-	ConsumerGroup   string   `json:"consumer_group" yaml:"consumer_group"`
-	Topics          []string `json:"topics" yaml:"topics"`
-	StartFromOldest bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	Addresses             []string `json:"addresses" yaml:"addresses"`
+	ClientID              string   `json:"client_id" yaml:"client_id"`
+	ConsumerGroup         string   `json:"consumer_group" yaml:"consumer_group"`
+	Topics                []string `json:"topics" yaml:"topics"`
+	StartFromOldest       bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	StartFromOffset       int64    `json:"start_from_offset" yaml:"start_from_offset"`
+	StartFromTimestampMS  int64    `json:"start_from_timestamp_ms" yaml:"start_from_timestamp_ms"`
+	CommitPeriodMS        int      `json:"commit_period_ms" yaml:"commit_period_ms"`
+	CommitBatchSize       int      `json:"commit_batch_size" yaml:"commit_batch_size"`
+	MaxProcessingPeriodMS int      `json:"max_processing_period_ms" yaml:"max_processing_period_ms"`
+	MaxBatchCount         int      `json:"max_batch_count" yaml:"max_batch_count"`
+	BatchTimeoutMS        int      `json:"batch_timeout_ms" yaml:"batch_timeout_ms"`
+	TargetVersion         string   `json:"target_version" yaml:"target_version"`
 }
 
 // NewKafkaBalancedConfig creates a new KafkaBalancedConfig with default values.
 func NewKafkaBalancedConfig() KafkaBalancedConfig {
 	return KafkaBalancedConfig{
-		Addresses:       []string{"localhost:9092"},
-		ClientID:        "benthos_kafka_input",
-		ConsumerGroup:   "benthos_consumer_group",
-		Topics:          []string{"benthos_stream"},
-		StartFromOldest: true,
+		Addresses:             []string{"localhost:9092"},
+		ClientID:              "benthos_kafka_input",
+		ConsumerGroup:         "benthos_consumer_group",
+		Topics:                []string{"benthos_stream"},
+		StartFromOldest:       true,
+		StartFromOffset:       -1,
+		StartFromTimestampMS:  0,
+		CommitPeriodMS:        1000,
+		CommitBatchSize:       1,
+		MaxProcessingPeriodMS: 100,
+		MaxBatchCount:         1,
+		BatchTimeoutMS:        0,
+		TargetVersion:         "",
 	}
 }
 
@@ -61,6 +78,12 @@ type KafkaBalanced struct {
 	consumer *cluster.Consumer
 	cMut     sync.Mutex
 
+	lastMsgs    []*sarama.ConsumerMessage
+	pendingAcks int
+
+	version       sarama.KafkaVersion
+	initialTarget KafkaOffsetTarget
+
 	addresses []string
 	conf      KafkaBalancedConfig
 	stats     metrics.Type
@@ -72,9 +95,10 @@ func NewKafkaBalanced(
 	conf KafkaBalancedConfig, log log.Modular, stats metrics.Type,
 ) (*KafkaBalanced, error) {
 	k := KafkaBalanced{
-		conf:  conf,
-		stats: stats,
-		log:   log.NewModule(".input.kafka_balanced"),
+		version: cluster.NewConfig().Version,
+		conf:    conf,
+		stats:   stats,
+		log:     log.NewModule(".input.kafka_balanced"),
 	}
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
@@ -83,6 +107,18 @@ func NewKafkaBalanced(
 			}
 		}
 	}
+	if len(conf.TargetVersion) > 0 {
+		var err error
+		if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
+			return nil, err
+		}
+	}
+	k.initialTarget = newKafkaInitialOffsetTarget(
+		conf.StartFromOffset, conf.StartFromTimestampMS, conf.StartFromOldest,
+	)
+	if err := k.initialTarget.checkVersion(k.version); err != nil {
+		return nil, err
+	}
 	return &k, nil
 }
 
@@ -92,6 +128,12 @@ func NewKafkaBalanced(
 func (k *KafkaBalanced) closeClients() {
 	k.cMut.Lock()
 	defer k.cMut.Unlock()
+	k.closeClientsLocked()
+}
+
+// closeClientsLocked closes the kafka clients, and must be called with cMut
+// held.
+func (k *KafkaBalanced) closeClientsLocked() {
 	if k.consumer != nil {
 		k.consumer.Close()
 
@@ -107,6 +149,74 @@ func (k *KafkaBalanced) closeClients() {
 	}
 }
 
+// newConfig returns the client configuration of the consumer.
+func (k *KafkaBalanced) newConfig() *cluster.Config {
+	config := cluster.NewConfig()
+	config.ClientID = k.conf.ClientID
+	config.Version = k.version
+	config.Net.DialTimeout = time.Second
+	config.Consumer.Return.Errors = true
+	config.Consumer.MaxProcessingTime = time.Duration(k.conf.MaxProcessingPeriodMS) * time.Millisecond
+	config.Group.Return.Notifications = true
+
+	if k.conf.CommitPeriodMS > 0 {
+		config.Consumer.Offsets.CommitInterval = time.Duration(k.conf.CommitPeriodMS) * time.Millisecond
+	}
+	if k.conf.StartFromOldest {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	return config
+}
+
+// setOffsets commits offsets of the consumer group resolved from a target for
+// the partitions of a topic. When onlyUncommitted is true only partitions
+// without a committed offset are set.
+func (k *KafkaBalanced) setOffsets(
+	config *cluster.Config,
+	topic string,
+	target KafkaOffsetTarget,
+	onlyUncommitted bool,
+) error {
+	client, err := sarama.NewClient(k.addresses, &config.Config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	coordinator, err := client.Coordinator(k.conf.ConsumerGroup)
+	if err != nil {
+		return err
+	}
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return err
+	}
+
+	committed := map[int32]int64{}
+	if onlyUncommitted {
+		if committed, err = fetchKafkaOffsets(
+			coordinator, 1, k.conf.ConsumerGroup, topic, partitions,
+		); err != nil {
+			return err
+		}
+	}
+
+	offsets := map[int32]int64{}
+	for _, p := range partitions {
+		if onlyUncommitted && committed[p] >= 0 {
+			continue
+		}
+		if offsets[p], err = target.resolve(client, topic, p); err != nil {
+			return err
+		}
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	return commitKafkaOffsets(coordinator, 2, k.conf.ConsumerGroup, topic, offsets)
+}
+
 //------------------------------------------------------------------------------
 
 // Connect establishes a KafkaBalanced connection.
@@ -118,14 +228,16 @@ func (k *KafkaBalanced) Connect() error {
 		return nil
 	}
 
-	config := cluster.NewConfig()
-	config.ClientID = k.conf.ClientID
-	config.Net.DialTimeout = time.Second
-	config.Consumer.Return.Errors = true
-	config.Group.Return.Notifications = true
+	config := k.newConfig()
 
-	if k.conf.StartFromOldest {
-		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	// The cluster consumer only supports starting from the oldest or newest
+	// offsets, other targets are set for uncommitted partitions beforehand.
+	if k.conf.StartFromOffset >= 0 || k.conf.StartFromTimestampMS > 0 {
+		for _, topic := range k.conf.Topics {
+			if err := k.setOffsets(config, topic, k.initialTarget, true); err != nil {
+				k.log.Warnf("Failed to set initial offsets of topic %s: %v\n", topic, err)
+			}
+		}
 	}
 
 	var consumer *cluster.Consumer
@@ -161,6 +273,8 @@ func (k *KafkaBalanced) Connect() error {
 	}()
 
 	k.consumer = consumer
+	k.lastMsgs = nil
+	k.pendingAcks = 0
 	k.log.Infof("Receiving KafkaBalanced messages from addresses: %s\n", k.addresses)
 	return nil
 }
@@ -184,8 +298,28 @@ func (k *KafkaBalanced) Read() (types.Message, error) {
 		k.closeClients()
 		return nil, types.ErrNotConnected
 	}
-	consumer.MarkOffset(data, "")
-	return types.NewMessage([][]byte{data.Value}), nil
+
+	batch := readKafkaBatch(
+		consumer.Messages(), []*sarama.ConsumerMessage{data},
+		k.conf.MaxBatchCount,
+		time.Duration(k.conf.BatchTimeoutMS)*time.Millisecond,
+	)
+
+	k.cMut.Lock()
+	defer k.cMut.Unlock()
+
+	// If the consumer was closed by an offset reset then the messages are
+	// discarded and we reconnect from the new offsets.
+	if k.consumer != consumer {
+		return nil, types.ErrNotConnected
+	}
+	k.lastMsgs = batch
+
+	msg := types.NewMessage(nil)
+	for _, data := range batch {
+		msg.Append(data.Value)
+	}
+	return msg, nil
 }
 
 // Acknowledge instructs whether the current offset should be committed.
@@ -194,19 +328,57 @@ func (k *KafkaBalanced) Acknowledge(err error) error {
 		return nil
 	}
 
-	var consumer *cluster.Consumer
-
 	k.cMut.Lock()
-	if k.consumer != nil {
-		consumer = k.consumer
+	defer k.cMut.Unlock()
+
+	if k.consumer == nil {
+		return types.ErrNotConnected
 	}
-	k.cMut.Unlock()
 
-	if consumer == nil {
+	for _, data := range k.lastMsgs {
+		k.consumer.MarkOffset(data, "")
+	}
+	if k.pendingAcks += len(k.lastMsgs); k.pendingAcks < k.conf.CommitBatchSize {
+		return nil
+	}
+	k.pendingAcks = 0
+	return k.consumer.CommitOffsets()
+}
+
+// ResetOffsets closes the consumer, which leaves the consumer group, commits
+// new offsets for all partitions of a topic and then reconnects in order to
+// resume consuming from them. Kafka rejects the new offsets whilst other
+// members of the consumer group are active.
+func (k *KafkaBalanced) ResetOffsets(topic string, target KafkaOffsetTarget) error {
+	if err := target.checkVersion(k.version); err != nil {
+		return err
+	}
+
+	found := false
+	for _, t := range k.conf.Topics {
+		if t == topic {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("topic %v is not consumed by this input", topic)
+	}
+
+	k.cMut.Lock()
+	defer k.cMut.Unlock()
+
+	if k.consumer == nil {
 		return types.ErrNotConnected
 	}
+	k.closeClientsLocked()
+	k.lastMsgs = nil
+	k.pendingAcks = 0
 
-	return consumer.CommitOffsets()
+	if err := k.setOffsets(k.newConfig(), topic, target, false); err != nil {
+		return err
+	}
+	k.log.Infof("Reset offsets of topic %s\n", topic)
+	return nil
 }
 
 // CloseAsync shuts down the KafkaBalanced input and stops processing requests.
@@ -0,0 +1,172 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrKafkaTimestampVersion is returned when offsets are targeted by timestamp
+// with a Kafka version that does not support it.
+var ErrKafkaTimestampVersion = errors.New("kafka offsets by timestamp require a target version of at least 0.10.1.0")
+
+// KafkaOffsetTarget describes an offset that the partitions of a Kafka topic
+// can be set to, which is either an explicit offset or the offset of the first
+// message at or after a point in time.
+type KafkaOffsetTarget struct {
+	offset int64
+	time   int64
+}
+
+// NewKafkaOffsetTargetOldest returns a target of the oldest available offset.
+func NewKafkaOffsetTargetOldest() KafkaOffsetTarget {
+	return KafkaOffsetTarget{offset: -1, time: sarama.OffsetOldest}
+}
+
+// NewKafkaOffsetTargetNewest returns a target of the newest offset.
+func NewKafkaOffsetTargetNewest() KafkaOffsetTarget {
+	return KafkaOffsetTarget{offset: -1, time: sarama.OffsetNewest}
+}
+
+// NewKafkaOffsetTargetTimestamp returns a target of the first message with a
+// timestamp at or after a unix timestamp in milliseconds.
+func NewKafkaOffsetTargetTimestamp(ms int64) KafkaOffsetTarget {
+	return KafkaOffsetTarget{offset: -1, time: ms}
+}
+
+// NewKafkaOffsetTargetOffset returns a target of an explicit offset.
+func NewKafkaOffsetTargetOffset(offset int64) KafkaOffsetTarget {
+	return KafkaOffsetTarget{offset: offset}
+}
+
+// newKafkaInitialOffsetTarget returns the target that a consumer should start
+// from when no offset has been committed.
+func newKafkaInitialOffsetTarget(offset, timestampMS int64, fromOldest bool) KafkaOffsetTarget {
+	if offset >= 0 {
+		return NewKafkaOffsetTargetOffset(offset)
+	}
+	if timestampMS > 0 {
+		return NewKafkaOffsetTargetTimestamp(timestampMS)
+	}
+	if fromOldest {
+		return NewKafkaOffsetTargetOldest()
+	}
+	return NewKafkaOffsetTargetNewest()
+}
+
+// checkVersion returns an error if resolving the target is not supported by a
+// Kafka version.
+func (t KafkaOffsetTarget) checkVersion(version sarama.KafkaVersion) error {
+	if t.offset < 0 && t.time >= 0 && !version.IsAtLeast(sarama.V0_10_1_0) {
+		return ErrKafkaTimestampVersion
+	}
+	return nil
+}
+
+// resolve returns the offset of the target for a partition of a topic. If a
+// timestamp target is later than all messages of the partition then the newest
+// offset is returned.
+func (t KafkaOffsetTarget) resolve(client sarama.Client, topic string, partition int32) (int64, error) {
+	if t.offset >= 0 {
+		return t.offset, nil
+	}
+	offset, err := client.GetOffset(topic, partition, t.time)
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		return client.GetOffset(topic, partition, sarama.OffsetNewest)
+	}
+	return offset, nil
+}
+
+//------------------------------------------------------------------------------
+
+// fetchKafkaOffsets returns the committed offsets of a consumer group for
+// partitions of a topic, where partitions without a committed offset are set
+// to -1. Version 0 requests access offsets stored in ZooKeeper, later versions
+// access offsets stored in Kafka.
+func fetchKafkaOffsets(
+	coordinator *sarama.Broker,
+	version int16,
+	group, topic string,
+	partitions []int32,
+) (map[int32]int64, error) {
+	req := sarama.OffsetFetchRequest{}
+	req.Version = version
+	req.ConsumerGroup = group
+	for _, p := range partitions {
+		req.AddPartition(topic, p)
+	}
+
+	res, err := coordinator.FetchOffset(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := map[int32]int64{}
+	for _, p := range partitions {
+		offsets[p] = -1
+		if block := res.GetBlock(topic, p); block != nil && block.Err == sarama.ErrNoError {
+			offsets[p] = block.Offset
+		}
+	}
+	return offsets, nil
+}
+
+// commitKafkaOffsets commits offsets of a consumer group for partitions of a
+// topic. Requests of version 1 and later are made outside of a group
+// generation and are therefore rejected by Kafka whilst the group has active
+// members.
+func commitKafkaOffsets(
+	coordinator *sarama.Broker,
+	version int16,
+	group, topic string,
+	offsets map[int32]int64,
+) error {
+	req := sarama.OffsetCommitRequest{}
+	req.Version = version
+	req.ConsumerGroup = group
+	if version > 0 {
+		req.ConsumerGroupGeneration = -1
+		req.RetentionTime = -1
+	}
+	for p, offset := range offsets {
+		req.AddBlock(topic, p, offset, 0, "")
+	}
+
+	res, err := coordinator.CommitOffset(&req)
+	if err != nil {
+		return err
+	}
+	for p := range offsets {
+		if err = res.Errors[topic][p]; err != sarama.ErrNoError {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
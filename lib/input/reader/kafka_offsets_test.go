@@ -0,0 +1,94 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaInitialOffsetTarget(t *testing.T) {
+	if exp, act := NewKafkaOffsetTargetOffset(10), newKafkaInitialOffsetTarget(10, 1000, true); exp != act {
+		t.Errorf("Wrong target: %v != %v", act, exp)
+	}
+	if exp, act := NewKafkaOffsetTargetOffset(0), newKafkaInitialOffsetTarget(0, 0, false); exp != act {
+		t.Errorf("Wrong target: %v != %v", act, exp)
+	}
+	if exp, act := NewKafkaOffsetTargetTimestamp(1000), newKafkaInitialOffsetTarget(-1, 1000, true); exp != act {
+		t.Errorf("Wrong target: %v != %v", act, exp)
+	}
+	if exp, act := NewKafkaOffsetTargetOldest(), newKafkaInitialOffsetTarget(-1, 0, true); exp != act {
+		t.Errorf("Wrong target: %v != %v", act, exp)
+	}
+	if exp, act := NewKafkaOffsetTargetNewest(), newKafkaInitialOffsetTarget(-1, 0, false); exp != act {
+		t.Errorf("Wrong target: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaOffsetTargetVersion(t *testing.T) {
+	ts := NewKafkaOffsetTargetTimestamp(1000)
+	if err := ts.checkVersion(sarama.V0_10_0_0); err != ErrKafkaTimestampVersion {
+		t.Errorf("Wrong error: %v != %v", err, ErrKafkaTimestampVersion)
+	}
+	if err := ts.checkVersion(sarama.V0_10_1_0); err != nil {
+		t.Error(err)
+	}
+	if err := NewKafkaOffsetTargetOldest().checkVersion(sarama.V0_8_2_0); err != nil {
+		t.Error(err)
+	}
+	if err := NewKafkaOffsetTargetOffset(5).checkVersion(sarama.V0_8_2_0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKafkaTimestampConfigs(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := NewKafkaConfig()
+	conf.StartFromTimestampMS = 1000
+	conf.TargetVersion = "0.10.0.0"
+	if _, err := NewKafka(conf, logger, metrics.DudType{}); err != ErrKafkaTimestampVersion {
+		t.Errorf("Wrong error: %v != %v", err, ErrKafkaTimestampVersion)
+	}
+	conf.TargetVersion = "0.10.1.0"
+	if _, err := NewKafka(conf, logger, metrics.DudType{}); err != nil {
+		t.Error(err)
+	}
+
+	balancedConf := NewKafkaBalancedConfig()
+	balancedConf.StartFromTimestampMS = 1000
+	balancedConf.TargetVersion = "0.10.0.0"
+	if _, err := NewKafkaBalanced(balancedConf, logger, metrics.DudType{}); err != ErrKafkaTimestampVersion {
+		t.Errorf("Wrong error: %v != %v", err, ErrKafkaTimestampVersion)
+	}
+	balancedConf.TargetVersion = "0.10.1.0"
+	if _, err := NewKafkaBalanced(balancedConf, logger, metrics.DudType{}); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,80 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaReadBatch(t *testing.T) {
+	c := make(chan *sarama.ConsumerMessage, 10)
+	for i := 0; i < 5; i++ {
+		c <- &sarama.ConsumerMessage{Offset: int64(i)}
+	}
+
+	batch := readKafkaBatch(c, nil, 3, 0)
+	if exp, act := 3, len(batch); exp != act {
+		t.Fatalf("Wrong batch size: %v != %v", act, exp)
+	}
+	for i, data := range batch {
+		if exp, act := int64(i), data.Offset; exp != act {
+			t.Errorf("Wrong offset: %v != %v", act, exp)
+		}
+	}
+
+	// Only available messages are read without a timeout.
+	if exp, act := 2, len(readKafkaBatch(c, nil, 3, 0)); exp != act {
+		t.Errorf("Wrong batch size: %v != %v", act, exp)
+	}
+
+	// Messages arriving within the timeout are read.
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		c <- &sarama.ConsumerMessage{Offset: 5}
+	}()
+	batch = readKafkaBatch(c, []*sarama.ConsumerMessage{{Offset: 4}}, 2, time.Second)
+	if exp, act := 2, len(batch); exp != act {
+		t.Fatalf("Wrong batch size: %v != %v", act, exp)
+	}
+	if exp, act := int64(5), batch[1].Offset; exp != act {
+		t.Errorf("Wrong offset: %v != %v", act, exp)
+	}
+
+	// The timeout ends a batch.
+	if exp, act := 1, len(readKafkaBatch(
+		c, []*sarama.ConsumerMessage{{Offset: 6}}, 2, time.Millisecond*10,
+	)); exp != act {
+		t.Errorf("Wrong batch size: %v != %v", act, exp)
+	}
+
+	// A closed channel ends a batch.
+	close(c)
+	if exp, act := 0, len(readKafkaBatch(c, nil, 2, time.Second)); exp != act {
+		t.Errorf("Wrong batch size: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,346 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/go-redis/redis"
+)
+
+//------------------------------------------------------------------------------
+
+// RedisStreamsConfig is configuration for the RedisStreams input type.
+type RedisStreamsConfig struct {
+	URL             string   `json:"url" yaml:"url"`
+	Streams         []string `json:"streams" yaml:"streams"`
+	BodyKey         string   `json:"body_key" yaml:"body_key"`
+	ConsumerGroup   string   `json:"consumer_group" yaml:"consumer_group"`
+	ClientID        string   `json:"client_id" yaml:"client_id"`
+	CreateStreams   bool     `json:"create_streams" yaml:"create_streams"`
+	StartFromOldest bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	MaxBatchCount   int      `json:"max_batch_count" yaml:"max_batch_count"`
+	ClaimMinIdleMS  int      `json:"claim_min_idle_ms" yaml:"claim_min_idle_ms"`
+	TimeoutMS       int      `json:"timeout_ms" yaml:"timeout_ms"`
+}
+
+// NewRedisStreamsConfig creates a new RedisStreamsConfig with default values.
+func NewRedisStreamsConfig() RedisStreamsConfig {
+	return RedisStreamsConfig{
+		URL:             "tcp://localhost:6379",
+		Streams:         []string{"benthos_stream"},
+		BodyKey:         "body",
+		ConsumerGroup:   "benthos_group",
+		ClientID:        "benthos_consumer",
+		CreateStreams:   true,
+		StartFromOldest: true,
+		MaxBatchCount:   1,
+		ClaimMinIdleMS:  60000,
+		TimeoutMS:       5000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RedisStreams is an input type that reads Redis Streams messages as a member
+// of a consumer group.
+type RedisStreams struct {
+	client *redis.Client
+	cMut   sync.Mutex
+
+	// Entry IDs of the last read message by stream, which are acknowledged
+	// once the message is successfully propagated.
+	pendingIDs map[string][]string
+	lastClaim  time.Time
+
+	url     *url.URL
+	conf    RedisStreamsConfig
+	streams []string
+
+	stats metrics.Type
+	log   log.Modular
+}
+
+// NewRedisStreams creates a new RedisStreams input type.
+func NewRedisStreams(
+	conf RedisStreamsConfig, log log.Modular, stats metrics.Type,
+) (*RedisStreams, error) {
+	r := &RedisStreams{
+		conf:       conf,
+		pendingIDs: map[string][]string{},
+		stats:      stats,
+		log:        log.NewModule(".input.redis_streams"),
+	}
+
+	for _, s := range conf.Streams {
+		for _, splitStream := range strings.Split(s, ",") {
+			if len(splitStream) > 0 {
+				r.streams = append(r.streams, splitStream)
+			}
+		}
+	}
+
+	var err error
+	r.url, err = url.Parse(r.conf.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a connection to a Redis server and creates the consumer
+// group for each stream if it does not already exist.
+func (r *RedisStreams) Connect() error {
+	r.cMut.Lock()
+	defer r.cMut.Unlock()
+
+	if r.client != nil {
+		return nil
+	}
+
+	var pass string
+	if r.url.User != nil {
+		pass, _ = r.url.User.Password()
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     r.url.Host,
+		Network:  r.url.Scheme,
+		Password: pass,
+	})
+
+	if _, err := client.Ping().Result(); err != nil {
+		return err
+	}
+
+	start := "$"
+	if r.conf.StartFromOldest {
+		start = "0"
+	}
+	for _, s := range r.streams {
+		args := []interface{}{"xgroup", "create", s, r.conf.ConsumerGroup, start}
+		if r.conf.CreateStreams {
+			args = append(args, "mkstream")
+		}
+		cmd := redis.NewStatusCmd(args...)
+		client.Process(cmd)
+		if err := cmd.Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			client.Close()
+			return err
+		}
+	}
+
+	r.log.Infof("Receiving messages from Redis streams %v at URL: %s\n", r.streams, r.conf.URL)
+
+	r.client = client
+	r.lastClaim = time.Time{}
+	return nil
+}
+
+// entryToPart converts a stream entry into a message part, which is the value
+// of the body key when it is the only field of the entry and otherwise the JSON
+// encoding of all fields.
+func (r *RedisStreams) entryToPart(entry redis.XMessage) []byte {
+	if body, exists := entry.Values[r.conf.BodyKey]; exists && len(entry.Values) == 1 {
+		switch t := body.(type) {
+		case string:
+			return []byte(t)
+		case []byte:
+			return t
+		}
+	}
+	part, err := json.Marshal(entry.Values)
+	if err != nil {
+		r.log.Errorf("Failed to encode stream entry %v: %v\n", entry.ID, err)
+		return nil
+	}
+	return part
+}
+
+// claim takes ownership of entries of the consumer group that have remained
+// unacknowledged by any consumer for longer than the minimum idle period.
+func (r *RedisStreams) claim(client *redis.Client) ([]redis.XStream, error) {
+	minIdle := time.Duration(r.conf.ClaimMinIdleMS) * time.Millisecond
+
+	var claimed []redis.XStream
+	for _, s := range r.streams {
+		pending, err := client.XPendingExt(&redis.XPendingExtArgs{
+			Stream: s,
+			Group:  r.conf.ConsumerGroup,
+			Start:  "-",
+			End:    "+",
+			Count:  int64(r.conf.MaxBatchCount),
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, p := range pending {
+			if p.Idle >= minIdle {
+				ids = append(ids, p.Id)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		msgs, err := client.XClaim(&redis.XClaimArgs{
+			Stream:   s,
+			Group:    r.conf.ConsumerGroup,
+			Consumer: r.conf.ClientID,
+			MinIdle:  minIdle,
+			Messages: ids,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		if len(msgs) > 0 {
+			r.stats.Incr("input.redis_streams.claimed", int64(len(msgs)))
+			claimed = append(claimed, redis.XStream{Stream: s, Messages: msgs})
+		}
+	}
+	return claimed, nil
+}
+
+// Read attempts to read a batch of new entries from the streams, prioritising
+// stale entries claimed from the pending lists of the consumer group.
+func (r *RedisStreams) Read() (types.Message, error) {
+	var client *redis.Client
+
+	r.cMut.Lock()
+	client = r.client
+	r.cMut.Unlock()
+
+	if client == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	var res []redis.XStream
+	var err error
+
+	if r.conf.ClaimMinIdleMS > 0 &&
+		time.Since(r.lastClaim) >= time.Duration(r.conf.ClaimMinIdleMS)*time.Millisecond {
+		if res, err = r.claim(client); err != nil {
+			r.disconnect()
+			r.log.Errorf("Error from redis: %v\n", err)
+			return nil, types.ErrNotConnected
+		}
+		// Keep claiming until no stale entries remain.
+		if len(res) == 0 {
+			r.lastClaim = time.Now()
+		}
+	}
+
+	if len(res) == 0 {
+		streams := make([]string, 0, len(r.streams)*2)
+		streams = append(streams, r.streams...)
+		for range r.streams {
+			streams = append(streams, ">")
+		}
+		res, err = client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    r.conf.ConsumerGroup,
+			Consumer: r.conf.ClientID,
+			Streams:  streams,
+			Count:    int64(r.conf.MaxBatchCount),
+			Block:    time.Duration(r.conf.TimeoutMS) * time.Millisecond,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			r.disconnect()
+			r.log.Errorf("Error from redis: %v\n", err)
+			return nil, types.ErrNotConnected
+		}
+	}
+
+	msg := types.NewMessage(nil)
+	for _, stream := range res {
+		for _, entry := range stream.Messages {
+			r.pendingIDs[stream.Stream] = append(r.pendingIDs[stream.Stream], entry.ID)
+			msg.Append(r.entryToPart(entry))
+		}
+	}
+
+	if msg.Len() == 0 {
+		return nil, types.ErrTimeout
+	}
+	return msg, nil
+}
+
+// Acknowledge acknowledges the entries of the last read message with the
+// consumer group once the message has been successfully propagated.
+func (r *RedisStreams) Acknowledge(err error) error {
+	if err != nil {
+		return nil
+	}
+
+	var client *redis.Client
+
+	r.cMut.Lock()
+	client = r.client
+	r.cMut.Unlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	for stream, ids := range r.pendingIDs {
+		if err := client.XAck(stream, r.conf.ConsumerGroup, ids...).Err(); err != nil {
+			r.log.Errorf("Failed to acknowledge stream entries: %v\n", err)
+			return err
+		}
+		delete(r.pendingIDs, stream)
+	}
+	return nil
+}
+
+// disconnect safely closes a connection to a Redis server.
+func (r *RedisStreams) disconnect() error {
+	r.cMut.Lock()
+	defer r.cMut.Unlock()
+
+	var err error
+	if r.client != nil {
+		err = r.client.Close()
+		r.client = nil
+	}
+	return err
+}
+
+// CloseAsync shuts down the RedisStreams input and stops processing requests.
+func (r *RedisStreams) CloseAsync() {
+	r.disconnect()
+}
+
+// WaitForClose blocks until the RedisStreams input has closed down.
+func (r *RedisStreams) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,90 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/go-redis/redis"
+)
+
+//------------------------------------------------------------------------------
+
+func TestRedisStreamsEntryToPart(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	r, err := NewRedisStreams(NewRedisStreamsConfig(), logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		values map[string]interface{}
+		exp    string
+	}{
+		{
+			values: map[string]interface{}{"body": "hello world"},
+			exp:    "hello world",
+		},
+		{
+			values: map[string]interface{}{"body": "hello world", "foo": "bar"},
+			exp:    `{"body":"hello world","foo":"bar"}`,
+		},
+		{
+			values: map[string]interface{}{"foo": "bar"},
+			exp:    `{"foo":"bar"}`,
+		},
+	}
+
+	for _, test := range tests {
+		act := string(r.entryToPart(redis.XMessage{ID: "0-1", Values: test.values}))
+		if act != test.exp {
+			t.Errorf("Wrong part: %v != %v", act, test.exp)
+		}
+	}
+}
+
+func TestRedisStreamsSplitStreams(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := NewRedisStreamsConfig()
+	conf.Streams = []string{"foo,bar", "baz"}
+
+	r, err := NewRedisStreams(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"foo", "bar", "baz"}
+	if len(r.streams) != len(exp) {
+		t.Fatalf("Wrong streams: %v != %v", r.streams, exp)
+	}
+	for i, s := range exp {
+		if r.streams[i] != s {
+			t.Errorf("Wrong stream: %v != %v", r.streams[i], s)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,368 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+
+	// SQL drivers supported by the SQL input.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrSQLInvalidDriver is returned when an SQL driver is not supported.
+var ErrSQLInvalidDriver = errors.New("sql driver must be one of mysql, postgres or sqlite3")
+
+// ErrSQLDriverUnavailable is returned when the sqlite3 driver is configured but
+// Benthos was built without cgo, which the driver requires.
+var ErrSQLDriverUnavailable = errors.New("sqlite3 driver is unavailable as benthos was built without cgo")
+
+// SQLConfig is configuration for the SQL input type.
+type SQLConfig struct {
+	Driver       string `json:"driver" yaml:"driver"`
+	DSN          string `json:"dsn" yaml:"dsn"`
+	Query        string `json:"query" yaml:"query"`
+	CursorColumn string `json:"cursor_column" yaml:"cursor_column"`
+	CursorInit   string `json:"cursor_init" yaml:"cursor_init"`
+	CursorTable  string `json:"cursor_table" yaml:"cursor_table"`
+	CursorID     string `json:"cursor_id" yaml:"cursor_id"`
+	PollPeriodMS int    `json:"poll_period_ms" yaml:"poll_period_ms"`
+}
+
+// NewSQLConfig creates a new SQLConfig with default values.
+func NewSQLConfig() SQLConfig {
+	return SQLConfig{
+		Driver:       "mysql",
+		DSN:          "",
+		Query:        "SELECT id, body FROM benthos_table WHERE id > ? ORDER BY id ASC LIMIT 100",
+		CursorColumn: "id",
+		CursorInit:   "0",
+		CursorTable:  "benthos_cursors",
+		CursorID:     "benthos_sql_input",
+		PollPeriodMS: 1000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SQL is an input type that polls an SQL database with a query for rows beyond
+// a cursor, which is advanced and persisted as rows are acknowledged.
+type SQL struct {
+	db    *sql.DB
+	dbMut sync.Mutex
+
+	// The cursor value for the next query, the cursor value of the last read
+	// message that is yet to be acknowledged, and whether a cursor has
+	// previously been persisted.
+	cursor        string
+	pendingCursor *string
+	cursorStored  bool
+
+	conf SQLConfig
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
+	stats metrics.Type
+	log   log.Modular
+}
+
+// NewSQL creates a new SQL input type.
+func NewSQL(conf SQLConfig, log log.Modular, stats metrics.Type) (*SQL, error) {
+	switch conf.Driver {
+	case "mysql", "postgres":
+	case "sqlite3":
+		if !sqlite3Available {
+			return nil, ErrSQLDriverUnavailable
+		}
+	default:
+		return nil, ErrSQLInvalidDriver
+	}
+	if len(conf.CursorColumn) == 0 {
+		return nil, errors.New("a cursor column must be specified")
+	}
+	return &SQL{
+		conf:      conf,
+		cursor:    conf.CursorInit,
+		closeChan: make(chan struct{}),
+		stats:     stats,
+		log:       log.NewModule(".input.sql"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// placeholder returns the query placeholder of the nth argument of a query for
+// the configured driver.
+func (s *SQL) placeholder(n int) string {
+	if s.conf.Driver == "postgres" {
+		return fmt.Sprintf("$%v", n)
+	}
+	return "?"
+}
+
+// Connect opens a connection to the database, creates the cursor table if it
+// does not already exist and loads the persisted cursor.
+func (s *SQL) Connect() error {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	db, err := sql.Open(s.conf.Driver, s.conf.DSN)
+	if err != nil {
+		return err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+
+	if len(s.conf.CursorTable) > 0 {
+		if _, err = db.Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %v (id VARCHAR(255) PRIMARY KEY, cursor_value TEXT)",
+			s.conf.CursorTable,
+		)); err != nil {
+			db.Close()
+			return fmt.Errorf("failed to create cursor table: %v", err)
+		}
+
+		var cursor string
+		err = db.QueryRow(fmt.Sprintf(
+			"SELECT cursor_value FROM %v WHERE id = %v",
+			s.conf.CursorTable, s.placeholder(1),
+		), s.conf.CursorID).Scan(&cursor)
+		switch err {
+		case nil:
+			s.cursor = cursor
+			s.cursorStored = true
+		case sql.ErrNoRows:
+			s.cursorStored = false
+		default:
+			db.Close()
+			return fmt.Errorf("failed to load cursor: %v", err)
+		}
+	}
+	s.pendingCursor = nil
+
+	s.log.Infof("Polling %v database from cursor: %v\n", s.conf.Driver, s.cursor)
+
+	s.db = db
+	return nil
+}
+
+// wait blocks for the poll period, returning ErrTypeClosed if the input is
+// closed in the meantime.
+func (s *SQL) wait() error {
+	select {
+	case <-time.After(time.Duration(s.conf.PollPeriodMS) * time.Millisecond):
+	case <-s.closeChan:
+		return types.ErrTypeClosed
+	}
+	return nil
+}
+
+// sqlValue converts a value scanned from a row into a value that can be
+// encoded as JSON.
+func sqlValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// sqlCursorValue converts a value scanned from a row into a cursor.
+func sqlCursorValue(v interface{}) string {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Read runs the query with the current cursor and returns the resulting rows
+// as a message with a JSON object part for each row. If no rows are found the
+// query is polled again after the poll period.
+func (s *SQL) Read() (types.Message, error) {
+	s.dbMut.Lock()
+	db := s.db
+	s.dbMut.Unlock()
+
+	if db == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	msg, cursor, err := s.query(db)
+	if err != nil {
+		s.log.Errorf("Failed to query database: %v\n", err)
+		s.stats.Incr("input.sql.query.error", 1)
+		if werr := s.wait(); werr != nil {
+			return nil, werr
+		}
+		return nil, err
+	}
+	if msg.Len() == 0 {
+		if err = s.wait(); err != nil {
+			return nil, err
+		}
+		return nil, types.ErrTimeout
+	}
+
+	s.pendingCursor = &cursor
+	return msg, nil
+}
+
+// query runs the query with the current cursor and returns the resulting rows
+// along with the cursor value of the last row.
+func (s *SQL) query(db *sql.DB) (types.Message, string, error) {
+	rows, err := db.Query(s.conf.Query, s.cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, "", err
+	}
+	cursorIndex := -1
+	for i, c := range columns {
+		if c == s.conf.CursorColumn {
+			cursorIndex = i
+		}
+	}
+	if cursorIndex < 0 {
+		return nil, "", fmt.Errorf("cursor column '%v' not found in query results", s.conf.CursorColumn)
+	}
+
+	cursor := s.cursor
+	msg := types.NewMessage(nil)
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(valuePtrs...); err != nil {
+			return nil, "", err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, c := range columns {
+			row[c] = sqlValue(values[i])
+		}
+		part, err := json.Marshal(row)
+		if err != nil {
+			return nil, "", err
+		}
+		msg.Append(part)
+		cursor = sqlCursorValue(values[cursorIndex])
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+	return msg, cursor, nil
+}
+
+// Acknowledge advances the cursor beyond the rows of the last read message and
+// persists it once the message has been successfully propagated.
+func (s *SQL) Acknowledge(err error) error {
+	if err != nil || s.pendingCursor == nil {
+		return nil
+	}
+
+	s.dbMut.Lock()
+	db := s.db
+	s.dbMut.Unlock()
+
+	if db == nil {
+		return types.ErrNotConnected
+	}
+
+	// The rows have been delivered and so the cursor advances even if it
+	// cannot be persisted, in which case it is persisted by a later ack.
+	s.cursor = *s.pendingCursor
+	s.pendingCursor = nil
+
+	if len(s.conf.CursorTable) > 0 {
+		var query string
+		if s.cursorStored {
+			query = fmt.Sprintf(
+				"UPDATE %v SET cursor_value = %v WHERE id = %v",
+				s.conf.CursorTable, s.placeholder(1), s.placeholder(2),
+			)
+		} else {
+			query = fmt.Sprintf(
+				"INSERT INTO %v (cursor_value, id) VALUES (%v, %v)",
+				s.conf.CursorTable, s.placeholder(1), s.placeholder(2),
+			)
+		}
+		if _, err = db.Exec(query, s.cursor, s.conf.CursorID); err != nil {
+			s.log.Errorf("Failed to persist cursor: %v\n", err)
+			return err
+		}
+		s.cursorStored = true
+	}
+	return nil
+}
+
+// disconnect safely closes a connection to the database.
+func (s *SQL) disconnect() error {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	var err error
+	if s.db != nil {
+		err = s.db.Close()
+		s.db = nil
+	}
+	return err
+}
+
+// CloseAsync shuts down the SQL input and stops processing requests.
+func (s *SQL) CloseAsync() {
+	s.closedOnce.Do(func() {
+		close(s.closeChan)
+	})
+	s.disconnect()
+}
+
+// WaitForClose blocks until the SQL input has closed down.
+func (s *SQL) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
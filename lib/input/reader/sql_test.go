@@ -0,0 +1,175 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestSQLBadDriver(t *testing.T) {
+	conf := NewSQLConfig()
+	conf.Driver = "nope"
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	if _, err := NewSQL(conf, logger, metrics.DudType{}); err != ErrSQLInvalidDriver {
+		t.Errorf("Wrong error: %v != %v", err, ErrSQLInvalidDriver)
+	}
+}
+
+func TestSQLDriverUnavailable(t *testing.T) {
+	if sqlite3Available {
+		t.Skip("The sqlite3 driver is available")
+	}
+
+	conf := NewSQLConfig()
+	conf.Driver = "sqlite3"
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	if _, err := NewSQL(conf, logger, metrics.DudType{}); err != ErrSQLDriverUnavailable {
+		t.Errorf("Wrong error: %v != %v", err, ErrSQLDriverUnavailable)
+	}
+}
+
+func TestSQLCursor(t *testing.T) {
+	if !sqlite3Available {
+		t.Skip("The sqlite3 driver requires cgo")
+	}
+
+	dir, err := ioutil.TempDir("", "benthos_sql_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dsn := filepath.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err = db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"foo", "bar", "baz"} {
+		if _, err = db.Exec("INSERT INTO events (body) VALUES (?)", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := NewSQLConfig()
+	conf.Driver = "sqlite3"
+	conf.DSN = dsn
+	conf.Query = "SELECT id, body FROM events WHERE id > ? ORDER BY id ASC LIMIT 2"
+	conf.PollPeriodMS = 1
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	newReader := func() *SQL {
+		s, rerr := NewSQL(conf, logger, metrics.DudType{})
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		if rerr = s.Connect(); rerr != nil {
+			t.Fatal(rerr)
+		}
+		return s
+	}
+
+	s := newReader()
+
+	msg, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]byte{
+		[]byte(`{"body":"foo","id":1}`),
+		[]byte(`{"body":"bar","id":2}`),
+	}
+	if act := msg.GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %s != %s", act, exp)
+	}
+
+	// Without an acknowledgement the cursor must not advance.
+	if err = s.Acknowledge(types.ErrTimeout); err != nil {
+		t.Fatal(err)
+	}
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	s = newReader()
+	if msg, err = s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if act := msg.GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %s != %s", act, exp)
+	}
+	if err = s.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	s.CloseAsync()
+
+	// The cursor should have been persisted.
+	s = newReader()
+	defer s.CloseAsync()
+
+	if msg, err = s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	exp = [][]byte{
+		[]byte(`{"body":"baz","id":3}`),
+	}
+	if act := msg.GetAll(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %s != %s", act, exp)
+	}
+	if err = s.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTimeout)
+	}
+
+	var cursor string
+	if err = db.QueryRow(
+		"SELECT cursor_value FROM benthos_cursors WHERE id = ?", conf.CursorID,
+	).Scan(&cursor); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "3", cursor; exp != act {
+		t.Errorf("Wrong persisted cursor: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,211 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// SSE is a reader implementation that continuously reads server-sent events
+// from an io.Reader type, where the data of each event is read as a message.
+type SSE struct {
+	handleCtor func(lastEventID string) (io.Reader, error)
+	onClose    func()
+
+	handle  io.Reader
+	scanner *bufio.Scanner
+
+	lastEventID string
+
+	maxBuffer int
+	multipart bool
+}
+
+// NewSSE creates a new reader input type.
+//
+// Callers must provide a constructor function for the target io.Reader, which
+// is called on start up and again each time a reader is exhausted. The
+// constructor receives the ID of the last event read, which is empty if no
+// event has carried an ID, in order to resume the stream. If the constructor is
+// called but there is no more content to create a Reader for then the error
+// `io.EOF` should be returned and the SSE will close.
+//
+// Callers must also provide an onClose function, which will be called if the
+// SSE has been instructed to shut down. This function should unblock any
+// blocked Read calls.
+func NewSSE(
+	handleCtor func(lastEventID string) (io.Reader, error),
+	onClose func(),
+	options ...func(r *SSE),
+) (*SSE, error) {
+	r := SSE{
+		handleCtor: handleCtor,
+		onClose:    onClose,
+		maxBuffer:  bufio.MaxScanTokenSize,
+		multipart:  false,
+	}
+
+	for _, opt := range options {
+		opt(&r)
+	}
+
+	return &r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OptSSESetMaxBuffer is a option func that sets the maximum size of the line
+// parsing buffers.
+func OptSSESetMaxBuffer(maxBuffer int) func(r *SSE) {
+	return func(r *SSE) {
+		r.maxBuffer = maxBuffer
+	}
+}
+
+// OptSSESetMultipart is a option func that sets the boolean flag indicating
+// whether each data line of an event should be read as a separate message
+// part.
+func OptSSESetMultipart(multipart bool) func(r *SSE) {
+	return func(r *SSE) {
+		r.multipart = multipart
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (r *SSE) closeHandle() {
+	if r.handle != nil {
+		if closer, ok := r.handle.(io.ReadCloser); ok {
+			closer.Close()
+		}
+		r.handle = nil
+	}
+	r.scanner = nil
+}
+
+// LastEventID returns the ID of the last event read.
+func (r *SSE) LastEventID() string {
+	return r.lastEventID
+}
+
+// Connect attempts to establish a new scanner for an io.Reader.
+func (r *SSE) Connect() error {
+	if r.scanner != nil {
+		return nil
+	}
+	r.closeHandle() // Just incase we have an open handle without a scanner.
+
+	var err error
+	r.handle, err = r.handleCtor(r.lastEventID)
+	if err != nil {
+		if err == io.EOF {
+			return types.ErrTypeClosed
+		}
+		return err
+	}
+
+	r.scanner = bufio.NewScanner(r.handle)
+	if r.maxBuffer != bufio.MaxScanTokenSize {
+		r.scanner.Buffer([]byte{}, r.maxBuffer)
+	}
+	return nil
+}
+
+// Read attempts to read the next event from the io.Reader.
+func (r *SSE) Read() (types.Message, error) {
+	if r.scanner == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	var data [][]byte
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+
+		// An empty line dispatches the event, events without data are
+		// ignored.
+		if len(line) == 0 {
+			if len(data) == 0 {
+				continue
+			}
+			if r.multipart {
+				return types.NewMessage(data), nil
+			}
+			return types.NewMessage([][]byte{bytes.Join(data, []byte("\n"))}), nil
+		}
+
+		// Lines beginning with a colon are comments.
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := string(line), []byte{}
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = string(line[:i]), line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+
+		switch field {
+		case "data":
+			data = append(data, append([]byte(nil), value...))
+		case "id":
+			if !strings.ContainsRune(string(value), 0) {
+				r.lastEventID = string(value)
+			}
+		}
+	}
+
+	err := r.scanner.Err()
+	r.closeHandle()
+
+	// Any incomplete event at the end of the stream is discarded.
+	if err != nil {
+		return nil, err
+	}
+	return nil, types.ErrNotConnected
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not.
+func (r *SSE) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the reader input and stops processing requests.
+func (r *SSE) CloseAsync() {
+	r.onClose()
+}
+
+// WaitForClose blocks until the reader input has closed down.
+func (r *SSE) WaitForClose(timeout time.Duration) error {
+	r.closeHandle()
+	return nil
+}
+
+//------------------------------------------------------------------------------
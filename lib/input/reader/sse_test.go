@@ -0,0 +1,141 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package reader
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestSSEReader(t *testing.T) {
+	streams := []string{
+		": a comment\nretry: 1000\nid: 1\ndata: first message\n\nevent: foo\ndata: second\ndata: message\nid: 2\n\nid: 3\n\ndata:third message\n\n",
+		"data: fourth message\n\ndata: incomplete message\n",
+	}
+
+	var lastIDs []string
+	f, err := NewSSE(
+		func(lastEventID string) (io.Reader, error) {
+			if len(lastIDs) == len(streams) {
+				return nil, io.EOF
+			}
+			lastIDs = append(lastIDs, lastEventID)
+			return bytes.NewBufferString(streams[len(lastIDs)-1]), nil
+		},
+		func() {},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		f.CloseAsync()
+		if err := f.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	exp := [][]string{
+		{"first message"},
+		{"second\nmessage"},
+		{"third message"},
+		nil,
+		{"fourth message"},
+		nil,
+	}
+
+	for _, e := range exp {
+		if err = f.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := f.Read()
+		if e == nil {
+			if err != types.ErrNotConnected {
+				t.Errorf("Wrong error returned: %v != %v", err, types.ErrNotConnected)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var act []string
+		for _, p := range msg.GetAll() {
+			act = append(act, string(p))
+		}
+		if !reflect.DeepEqual(e, act) {
+			t.Errorf("Wrong result: %q != %q", act, e)
+		}
+	}
+
+	if exp, act := []string{"", "3"}, lastIDs; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong last event IDs: %v != %v", act, exp)
+	}
+	if exp, act := "3", f.LastEventID(); exp != act {
+		t.Errorf("Wrong last event ID: %v != %v", act, exp)
+	}
+	if err = f.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestSSEReaderMultipart(t *testing.T) {
+	ctored := false
+	f, err := NewSSE(
+		func(lastEventID string) (io.Reader, error) {
+			if ctored {
+				return nil, io.EOF
+			}
+			ctored = true
+			return strings.NewReader("data: foo\ndata: bar\n\ndata: baz\n\n"), nil
+		},
+		func() {},
+		OptSSESetMultipart(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range [][][]byte{
+		{[]byte("foo"), []byte("bar")},
+		{[]byte("baz")},
+	} {
+		msg, err := f.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := msg.GetAll(); !reflect.DeepEqual(e, act) {
+			t.Errorf("Wrong result: %s != %s", act, e)
+		}
+	}
+
+	if _, err = f.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrNotConnected)
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package reader
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"golang.org/x/net/websocket"
+)
+
+//------------------------------------------------------------------------------
+
+// Websocket modes.
+const (
+	WebsocketModeClient = "client"
+	WebsocketModeServer = "server"
+)
+
+// WebsocketConfig is configuration for the Websocket input type.
+type WebsocketConfig struct {
+	Mode         string `json:"mode" yaml:"mode"`
+	URL          string `json:"url" yaml:"url"`
+	Origin       string `json:"origin" yaml:"origin"`
+	Address      string `json:"address" yaml:"address"`
+	Path         string `json:"path" yaml:"path"`
+	Multipart    bool   `json:"multipart" yaml:"multipart"`
+	PingPeriodMS int64  `json:"ping_period_ms" yaml:"ping_period_ms"`
+}
+
+// NewWebsocketConfig creates a new WebsocketConfig with default values.
+func NewWebsocketConfig() WebsocketConfig {
+	return WebsocketConfig{
+		Mode:         WebsocketModeClient,
+		URL:          "ws://localhost:4195/get/ws",
+		Origin:       "http://localhost",
+		Address:      "",
+		Path:         "/post/ws",
+		Multipart:    false,
+		PingPeriodMS: 30000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Websocket is an input type that reads websocket frames, either by dialing a
+// remote server or by accepting connections from clients.
+type Websocket struct {
+	conf  WebsocketConfig
+	stats metrics.Type
+	log   log.Modular
+
+	lock   sync.Mutex
+	client *websocket.Conn
+	server *http.Server
+
+	msgChan   chan types.Message
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWebsocket creates a new Websocket input type.
+func NewWebsocket(
+	conf WebsocketConfig, log log.Modular, stats metrics.Type,
+) (*Websocket, error) {
+	if conf.Mode != WebsocketModeClient && conf.Mode != WebsocketModeServer {
+		return nil, types.ErrInvalidWebsocketMode
+	}
+	return &Websocket{
+		conf:      conf,
+		stats:     stats,
+		log:       log.NewModule(".input.websocket"),
+		msgChan:   make(chan types.Message),
+		closeChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// pingCodec writes empty ping frames to a websocket connection. Pong frames
+// are discarded by the connection as they are received.
+var pingCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		return nil, websocket.PingFrame, nil
+	},
+}
+
+// keepAlive periodically pings a connection until either it fails or done is
+// closed. A failed ping closes the connection, which unblocks any reads.
+func keepAlive(conn *websocket.Conn, period time.Duration, done <-chan struct{}) {
+	if period <= 0 {
+		return
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := pingCodec.Send(conn, nil); err != nil {
+				conn.Close()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// readMessage reads frames from a connection until a full message is formed.
+// When multipart is enabled an empty frame ends a message of several parts,
+// otherwise each non-empty frame is a message.
+func (w *Websocket) readMessage(conn *websocket.Conn) (types.Message, error) {
+	msg := types.NewMessage(nil)
+	for {
+		var frame []byte
+		if err := websocket.Message.Receive(conn, &frame); err != nil {
+			return nil, err
+		}
+		if len(frame) > 0 {
+			msg.Append(frame)
+			if !w.conf.Multipart {
+				return msg, nil
+			}
+		} else if w.conf.Multipart && msg.Len() > 0 {
+			return msg, nil
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ServeHTTP accepts websocket connections from clients when in server mode.
+// This handler can be registered with an HTTP server, which is done
+// automatically when an address is configured.
+func (w *Websocket) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	websocket.Server{Handler: w.handleConn}.ServeHTTP(res, req)
+}
+
+func (w *Websocket) handleConn(conn *websocket.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+
+	w.stats.Incr("input.websocket.server.connection.up", 1)
+	defer w.stats.Incr("input.websocket.server.connection.down", 1)
+
+	go func() {
+		select {
+		case <-w.closeChan:
+			conn.Close()
+		case <-done:
+		}
+	}()
+	go keepAlive(conn, time.Duration(w.conf.PingPeriodMS)*time.Millisecond, done)
+
+	for {
+		msg, err := w.readMessage(conn)
+		if err != nil {
+			return
+		}
+		select {
+		case w.msgChan <- msg:
+		case <-w.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Connect dials the configured URL in client mode, or begins listening on the
+// configured address in server mode.
+func (w *Websocket) Connect() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	select {
+	case <-w.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	if w.conf.Mode == WebsocketModeServer {
+		if w.server != nil || len(w.conf.Address) == 0 {
+			return nil
+		}
+		listener, err := net.Listen("tcp", w.conf.Address)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle(w.conf.Path, w)
+		w.server = &http.Server{Handler: mux}
+		go func(s *http.Server) {
+			if sErr := s.Serve(listener); sErr != http.ErrServerClosed {
+				w.log.Errorf("Server error: %v\n", sErr)
+			}
+		}(w.server)
+		w.log.Infof(
+			"Receiving websocket messages at: %s\n", w.conf.Address+w.conf.Path,
+		)
+		return nil
+	}
+
+	if w.client != nil {
+		return nil
+	}
+	conn, err := websocket.Dial(w.conf.URL, "", w.conf.Origin)
+	if err != nil {
+		return err
+	}
+	w.client = conn
+	go keepAlive(conn, time.Duration(w.conf.PingPeriodMS)*time.Millisecond, w.closeChan)
+
+	w.log.Infof("Receiving websocket messages from: %s\n", w.conf.URL)
+	return nil
+}
+
+// Read attempts to read a new message from the websocket.
+func (w *Websocket) Read() (types.Message, error) {
+	if w.conf.Mode == WebsocketModeServer {
+		select {
+		case msg := <-w.msgChan:
+			return msg, nil
+		case <-w.closeChan:
+		}
+		return nil, types.ErrTypeClosed
+	}
+
+	w.lock.Lock()
+	conn := w.client
+	w.lock.Unlock()
+
+	if conn == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	msg, err := w.readMessage(conn)
+	if err != nil {
+		select {
+		case <-w.closeChan:
+			return nil, types.ErrTypeClosed
+		default:
+		}
+		w.log.Errorf("Lost websocket connection: %v\n", err)
+		conn.Close()
+
+		w.lock.Lock()
+		w.client = nil
+		w.lock.Unlock()
+		return nil, types.ErrNotConnected
+	}
+	return msg, nil
+}
+
+// Acknowledge instructs whether messages have been successfully propagated.
+func (w *Websocket) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the Websocket input and stops processing requests.
+func (w *Websocket) CloseAsync() {
+	w.closeOnce.Do(func() {
+		close(w.closeChan)
+
+		w.lock.Lock()
+		if w.client != nil {
+			w.client.Close()
+			w.client = nil
+		}
+		if w.server != nil {
+			w.server.Close()
+		}
+		w.lock.Unlock()
+	})
+}
+
+// WaitForClose blocks until the Websocket input has closed down.
+func (w *Websocket) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
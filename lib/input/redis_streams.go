@@ -0,0 +1,67 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["redis_streams"] = TypeSpec{
+		constructor: NewRedisStreams,
+		description: `
+Pulls messages from Redis (v5.0+) streams with the XREADGROUP command as the
+consumer 'client_id' of the consumer group 'consumer_group', which is created
+for each stream if it does not already exist. When 'create_streams' is true
+streams that do not exist are also created. New consumer groups begin from the
+start of their streams when 'start_from_oldest' is true, otherwise from new
+entries only.
+
+Up to 'max_batch_count' entries are read as a single message of multiple parts,
+and are acknowledged with the XACK command once the message has been
+successfully propagated. If an entry consists only of the field 'body_key' then
+its value becomes the message part, otherwise the part is a JSON object of all
+fields of the entry.
+
+Entries that remain unacknowledged by any consumer of the group for at least
+'claim_min_idle_ms' milliseconds, such as those of a crashed consumer, are
+claimed with the XCLAIM command and read before new entries. Setting
+'claim_min_idle_ms' to zero disables claiming.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewRedisStreams creates a new Redis Streams input type.
+func NewRedisStreams(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	r, err := reader.NewRedisStreams(conf.RedisStreams, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("redis_streams", reader.NewPreserver(r), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,444 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["socket"] = TypeSpec{
+		constructor: NewSocket,
+		description: `
+Reads delimited messages from a plain socket. The 'network' field can be one of
+'tcp', 'udp' or 'unix'. When 'mode' is 'listen' the input binds to 'address'
+and accepts any number of connections, or datagrams for the 'udp' network. When
+'mode' is 'dial' the input connects to 'address' and reconnects whenever the
+connection is lost, this mode is not supported for 'udp'.
+
+By default the messages are assumed single part and are line delimited. If the
+multipart option is set to true then lines are interpretted as message parts,
+and an empty line indicates the end of the message. Alternatively, a custom
+delimiter can be set that is used instead of line breaks.
+
+The end of each UDP datagram also ends a line, and therefore messages cannot
+span datagrams.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Socket modes.
+const (
+	SocketModeListen = "listen"
+	SocketModeDial   = "dial"
+)
+
+// SocketConfig contains config fields for the Socket input type.
+type SocketConfig struct {
+	Network     string `json:"network" yaml:"network"`
+	Address     string `json:"address" yaml:"address"`
+	Mode        string `json:"mode" yaml:"mode"`
+	Multipart   bool   `json:"multipart" yaml:"multipart"`
+	MaxBuffer   int    `json:"max_buffer" yaml:"max_buffer"`
+	CustomDelim string `json:"custom_delimiter" yaml:"custom_delimiter"`
+}
+
+// NewSocketConfig creates a SocketConfig populated with default values.
+func NewSocketConfig() SocketConfig {
+	return SocketConfig{
+		Network:     "tcp",
+		Address:     "localhost:6000",
+		Mode:        SocketModeListen,
+		Multipart:   false,
+		MaxBuffer:   bufio.MaxScanTokenSize,
+		CustomDelim: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ErrSocketDialUDP is returned when a socket input is configured to dial a UDP
+// address.
+var ErrSocketDialUDP = errors.New("socket input cannot dial the udp network")
+
+// NewSocket creates a new Socket input type.
+func NewSocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	switch conf.Socket.Network {
+	case "tcp", "udp", "unix":
+	default:
+		return nil, types.ErrInvalidSocketNetwork
+	}
+
+	var rdr reader.Type
+	var err error
+
+	switch conf.Socket.Mode {
+	case SocketModeListen:
+		if conf.Socket.Network == "udp" {
+			rdr, err = newSocketPacketReader(conf.Socket, log, stats)
+		} else {
+			var listener net.Listener
+			if listener, err = net.Listen(conf.Socket.Network, conf.Socket.Address); err == nil {
+				log.Infof("Receiving socket messages at: %v://%v\n", conf.Socket.Network, listener.Addr())
+				rdr = newSocketServerReader(
+					"socket", listener, socketLinesHandler(conf.Socket, log), log, stats,
+				)
+			}
+		}
+	case SocketModeDial:
+		if conf.Socket.Network == "udp" {
+			return nil, ErrSocketDialUDP
+		}
+		rdr, err = newSocketDialReader(conf.Socket, log, stats)
+	default:
+		return nil, types.ErrInvalidSocketMode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReader("socket", reader.NewPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+func socketLinesOpts(conf SocketConfig) []func(*reader.Lines) {
+	return []func(*reader.Lines){
+		reader.OptLinesSetDelimiter(socketDelim(conf)),
+		reader.OptLinesSetMaxBuffer(conf.MaxBuffer),
+		reader.OptLinesSetMultipart(conf.Multipart),
+	}
+}
+
+func socketDelim(conf SocketConfig) string {
+	if len(conf.CustomDelim) > 0 {
+		return conf.CustomDelim
+	}
+	return "\n"
+}
+
+// newSocketDialReader creates a reader that connects to a remote address,
+// reconnecting each time the connection ends.
+func newSocketDialReader(conf SocketConfig, log log.Modular, stats metrics.Type) (reader.Type, error) {
+	var connMut sync.Mutex
+	var closed bool
+	var conn net.Conn
+
+	return reader.NewLines(
+		func() (io.Reader, error) {
+			connMut.Lock()
+			defer connMut.Unlock()
+
+			if conn != nil {
+				conn.Close()
+				conn = nil
+			}
+
+			var err error
+			for !closed {
+				if conn, err = net.Dial(conf.Network, conf.Address); err == nil {
+					stats.Incr("input.socket.dial.success", 1)
+					return conn, nil
+				}
+				log.Errorf("Failed to dial socket: %v\n", err)
+				stats.Incr("input.socket.dial.error", 1)
+
+				connMut.Unlock()
+				<-time.After(time.Second)
+				connMut.Lock()
+			}
+			return nil, io.EOF
+		},
+		func() {
+			connMut.Lock()
+			defer connMut.Unlock()
+
+			closed = true
+
+			// On shutdown we close the connection, this should end any
+			// blocked Read calls.
+			if conn != nil {
+				conn.Close()
+				conn = nil
+			}
+		},
+		socketLinesOpts(conf)...,
+	)
+}
+
+//------------------------------------------------------------------------------
+
+// datagramReader reads UDP datagrams as a stream, where the end of each
+// datagram is terminated with a delimiter if it isn't already.
+type datagramReader struct {
+	conn  net.PacketConn
+	delim []byte
+	buf   []byte
+	rem   []byte
+}
+
+func (d *datagramReader) Read(p []byte) (int, error) {
+	if len(d.rem) == 0 {
+		n, _, err := d.conn.ReadFrom(d.buf)
+		if err != nil {
+			return 0, err
+		}
+		d.rem = d.buf[:n]
+		if !bytes.HasSuffix(d.rem, d.delim) {
+			d.rem = append(d.rem, d.delim...)
+		}
+	}
+	n := copy(p, d.rem)
+	d.rem = d.rem[n:]
+	return n, nil
+}
+
+func (d *datagramReader) Close() error {
+	return d.conn.Close()
+}
+
+// newSocketPacketReader creates a reader that listens for UDP datagrams.
+func newSocketPacketReader(conf SocketConfig, log log.Modular, stats metrics.Type) (reader.Type, error) {
+	conn, err := net.ListenPacket(conf.Network, conf.Address)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Receiving socket messages at: %v://%v\n", conf.Network, conn.LocalAddr())
+
+	delim := []byte(socketDelim(conf))
+	var handle *datagramReader
+	var once sync.Once
+
+	return reader.NewLines(
+		func() (io.Reader, error) {
+			if handle != nil {
+				// Our packet connection only ends when it is closed.
+				return nil, io.EOF
+			}
+			handle = &datagramReader{
+				conn:  conn,
+				delim: delim,
+				buf:   make([]byte, 65536, 65536+len(delim)),
+			}
+			return handle, nil
+		},
+		func() {
+			once.Do(func() {
+				conn.Close()
+			})
+		},
+		socketLinesOpts(conf)...,
+	)
+}
+
+//------------------------------------------------------------------------------
+
+// socketConnHandler reads messages from a connection and calls send with each
+// one, until either the connection ends or send returns false.
+type socketConnHandler func(conn net.Conn, send func(msg types.Message) bool)
+
+// socketLinesHandler returns a connection handler that reads delimited
+// messages.
+func socketLinesHandler(conf SocketConfig, log log.Modular) socketConnHandler {
+	return func(conn net.Conn, send func(msg types.Message) bool) {
+		var handle io.Reader = conn
+		rdr, err := reader.NewLines(
+			func() (io.Reader, error) {
+				if handle == nil {
+					return nil, io.EOF
+				}
+				h := handle
+				handle = nil
+				return h, nil
+			},
+			func() {},
+			socketLinesOpts(conf)...,
+		)
+		if err != nil {
+			log.Errorf("Failed to create socket reader: %v\n", err)
+			return
+		}
+		if err = rdr.Connect(); err != nil {
+			return
+		}
+
+		for {
+			msg, err := rdr.Read()
+			if err != nil {
+				if err != types.ErrNotConnected {
+					log.Errorf("Failed to read socket connection: %v\n", err)
+				}
+				return
+			}
+
+			// Messages reference the buffer of the line reader, which is
+			// reset when acknowledged.
+			msg = msg.DeepCopy()
+			rdr.Acknowledge(nil)
+
+			if !send(msg) {
+				return
+			}
+		}
+	}
+}
+
+// socketServerReader is a reader implementation that accepts any number of
+// stream connections, where messages read from each connection are merged.
+type socketServerReader struct {
+	typeStr string
+	log     log.Modular
+	stats   metrics.Type
+
+	listener net.Listener
+	handler  socketConnHandler
+
+	connMut sync.Mutex
+	conns   map[net.Conn]struct{}
+
+	msgChan   chan types.Message
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// newSocketServerReader creates a reader that accepts stream connections from
+// a listener, reading messages from each connection with a handler.
+func newSocketServerReader(
+	typeStr string,
+	listener net.Listener,
+	handler socketConnHandler,
+	log log.Modular,
+	stats metrics.Type,
+) *socketServerReader {
+	s := &socketServerReader{
+		typeStr:   typeStr,
+		log:       log,
+		stats:     stats,
+		listener:  listener,
+		handler:   handler,
+		conns:     map[net.Conn]struct{}{},
+		msgChan:   make(chan types.Message),
+		closeChan: make(chan struct{}),
+	}
+	go s.acceptLoop()
+	return s
+}
+
+func (s *socketServerReader) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closeChan:
+				return
+			default:
+			}
+			s.log.Errorf("Failed to accept socket connection: %v\n", err)
+			select {
+			case <-time.After(time.Second):
+			case <-s.closeChan:
+				return
+			}
+			continue
+		}
+
+		s.connMut.Lock()
+		s.conns[conn] = struct{}{}
+		s.connMut.Unlock()
+
+		s.stats.Incr("input."+s.typeStr+".connection.accepted", 1)
+		go s.readConn(conn)
+	}
+}
+
+func (s *socketServerReader) readConn(conn net.Conn) {
+	defer func() {
+		s.connMut.Lock()
+		delete(s.conns, conn)
+		s.connMut.Unlock()
+		conn.Close()
+		s.stats.Incr("input."+s.typeStr+".connection.closed", 1)
+	}()
+
+	s.handler(conn, func(msg types.Message) bool {
+		select {
+		case s.msgChan <- msg:
+			return true
+		case <-s.closeChan:
+		}
+		return false
+	})
+}
+
+// Connect is a no-op as the listener is established on construction.
+func (s *socketServerReader) Connect() error {
+	return nil
+}
+
+// Read attempts to read a message from any connection.
+func (s *socketServerReader) Read() (types.Message, error) {
+	select {
+	case msg := <-s.msgChan:
+		return msg, nil
+	case <-s.closeChan:
+	}
+	return nil, types.ErrTypeClosed
+}
+
+// Acknowledge is a no-op as messages are acknowledged once read from a
+// connection.
+func (s *socketServerReader) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the listener and all open connections.
+func (s *socketServerReader) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+		s.listener.Close()
+
+		s.connMut.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.connMut.Unlock()
+	})
+}
+
+// WaitForClose blocks until the reader has closed down.
+func (s *socketServerReader) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,234 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package input
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+func readSocketMsgs(t *testing.T, s Type, n int) [][]string {
+	var msgs [][]string
+	for i := 0; i < n; i++ {
+		var ts types.Transaction
+		select {
+		case ts = <-s.TransactionChan():
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for message")
+		}
+		var parts []string
+		for _, p := range ts.Payload.GetAll() {
+			parts = append(parts, string(p))
+		}
+		msgs = append(msgs, parts)
+		select {
+		case ts.ResponseChan <- types.NewSimpleResponse(nil):
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for response")
+		}
+	}
+	return msgs
+}
+
+func TestSocketTCPListen(t *testing.T) {
+	conf := NewConfig()
+	conf.Socket.Address = "localhost:1251"
+
+	s, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn1, err := net.Dial("tcp", "localhost:1251")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn1.Close()
+	conn2, err := net.Dial("tcp", "localhost:1251")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+
+	if _, err = conn1.Write([]byte("foo\nbar\n")); err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]string{{"foo"}, {"bar"}}
+	if act := readSocketMsgs(t, s, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong messages: %v != %v", act, exp)
+	}
+
+	if _, err = conn2.Write([]byte("baz\n")); err != nil {
+		t.Fatal(err)
+	}
+	exp = [][]string{{"baz"}}
+	if act := readSocketMsgs(t, s, 1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong messages: %v != %v", act, exp)
+	}
+}
+
+func TestSocketUnixListenMultipart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_socket_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Socket.Network = "unix"
+	conf.Socket.Address = filepath.Join(dir, "test.sock")
+	conf.Socket.Multipart = true
+	conf.Socket.CustomDelim = "|"
+
+	s, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := net.Dial("unix", conf.Socket.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Write([]byte("foo|bar||baz||")); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	exp := [][]string{{"foo", "bar"}, {"baz"}}
+	if act := readSocketMsgs(t, s, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong messages: %v != %v", act, exp)
+	}
+}
+
+func TestSocketUDPListen(t *testing.T) {
+	conf := NewConfig()
+	conf.Socket.Network = "udp"
+	conf.Socket.Address = "localhost:1252"
+
+	s, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := net.Dial("udp", "localhost:1252")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Datagrams end lines regardless of a trailing delimiter.
+	for _, d := range []string{"foo", "bar\nbaz\n"} {
+		if _, err = conn.Write([]byte(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := [][]string{{"foo"}, {"bar"}, {"baz"}}
+	if act := readSocketMsgs(t, s, 3); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong messages: %v != %v", act, exp)
+	}
+}
+
+func TestSocketTCPDialReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, cerr := ln.Accept()
+			if cerr != nil {
+				return
+			}
+			conn.Write([]byte("foo\n"))
+			conn.Close()
+		}
+	}()
+
+	conf := NewConfig()
+	conf.Socket.Mode = SocketModeDial
+	conf.Socket.Address = ln.Addr().String()
+
+	s, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	exp := [][]string{{"foo"}, {"foo"}}
+	if act := readSocketMsgs(t, s, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong messages: %v != %v", act, exp)
+	}
+}
+
+func TestSocketBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Socket.Network = "nope"
+	if _, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err != types.ErrInvalidSocketNetwork {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrInvalidSocketNetwork)
+	}
+
+	conf = NewConfig()
+	conf.Socket.Mode = "nope"
+	if _, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err != types.ErrInvalidSocketMode {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrInvalidSocketMode)
+	}
+
+	conf = NewConfig()
+	conf.Socket.Network = "udp"
+	conf.Socket.Mode = SocketModeDial
+	if _, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err != ErrSocketDialUDP {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrSocketDialUDP)
+	}
+}
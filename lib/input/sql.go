@@ -0,0 +1,74 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["sql"] = TypeSpec{
+		constructor: NewSQL,
+		description: `
+Polls an SQL database with a query and reads the resulting rows as a message
+with a JSON object part for each row. The supported drivers are 'mysql',
+'postgres' and 'sqlite3', and 'dsn' is the data source name in the format of
+the chosen driver. The 'sqlite3' driver is only available when Benthos is built
+with cgo enabled, which is not the case for the Docker image, and otherwise the
+input fails to be created.
+
+The query must have a single placeholder argument, which is '?' for 'mysql' and
+'sqlite3' and '$1' for 'postgres', that receives the value of a cursor. The
+query should select rows beyond the cursor in ascending order of the column
+'cursor_column', such as:
+
+` + "``` sql" + `
+SELECT id, body FROM benthos_table WHERE id > ? ORDER BY id ASC LIMIT 100
+` + "```" + `
+
+Once a message has been successfully propagated the cursor is advanced to the
+value of 'cursor_column' of its last row. When no rows are returned the query is
+polled again after 'poll_period_ms' milliseconds.
+
+The cursor begins at 'cursor_init' and is persisted in the table 'cursor_table'
+under the key 'cursor_id', which is created if it does not already exist, so
+that polling resumes from the same position after a restart. Setting
+'cursor_table' to an empty string disables persistence.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSQL creates a new SQL input type.
+func NewSQL(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := reader.NewSQL(conf.SQL, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("sql", reader.NewPreserver(s), log, stats)
+}
+
+//------------------------------------------------------------------------------
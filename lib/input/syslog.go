@@ -0,0 +1,286 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/benthos/lib/util/syslog"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["syslog"] = TypeSpec{
+		constructor: NewSyslog,
+		description: `
+Receives syslog messages over TCP or UDP, and parses each into a JSON object
+with the fields 'priority', 'facility', 'severity', 'version', 'timestamp',
+'hostname', 'appname', 'procid', 'msgid', 'structured_data' and 'message'.
+Fields that are absent from a message are omitted.
+
+The 'format' field can be one of 'rfc5424', 'rfc3164' or 'auto', where 'auto'
+parses messages that contain a version as RFC5424 and all others as RFC3164.
+RFC3164 timestamps lack a year, and therefore the current year is assumed.
+
+Messages that fail to parse are not dropped, instead the raw message is placed
+in the 'message' field of an otherwise empty object.
+
+### Framing
+
+Over TCP each message is framed either by octet counting, where it is prefixed
+with its length and a space, or by a trailing line break. The framing is
+detected for each message. Over UDP each datagram is a message.
+
+TLS is enabled for the TCP network when key and cert files are specified.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SyslogConfig contains config fields for the Syslog input type.
+type SyslogConfig struct {
+	Network   string `json:"network" yaml:"network"`
+	Address   string `json:"address" yaml:"address"`
+	Format    string `json:"format" yaml:"format"`
+	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
+	CertFile  string `json:"cert_file" yaml:"cert_file"`
+	KeyFile   string `json:"key_file" yaml:"key_file"`
+}
+
+// NewSyslogConfig creates a SyslogConfig populated with default values.
+func NewSyslogConfig() SyslogConfig {
+	return SyslogConfig{
+		Network:   "udp",
+		Address:   "localhost:514",
+		Format:    syslog.FormatAuto,
+		MaxBuffer: bufio.MaxScanTokenSize,
+		CertFile:  "",
+		KeyFile:   "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSyslog creates a new Syslog input type.
+func NewSyslog(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	if !syslog.ValidFormat(conf.Syslog.Format) {
+		return nil, syslog.ErrInvalidFormat
+	}
+
+	var rdr reader.Type
+	switch conf.Syslog.Network {
+	case "udp":
+		conn, err := net.ListenPacket(conf.Syslog.Network, conf.Syslog.Address)
+		if err != nil {
+			return nil, err
+		}
+		rdr = &syslogPacketReader{
+			conf:  conf.Syslog,
+			stats: stats,
+			conn:  conn,
+			buf:   make([]byte, 65536),
+		}
+		log.Infof("Receiving syslog messages at: udp://%v\n", conn.LocalAddr())
+	case "tcp":
+		listener, err := net.Listen(conf.Syslog.Network, conf.Syslog.Address)
+		if err != nil {
+			return nil, err
+		}
+		if len(conf.Syslog.CertFile) > 0 || len(conf.Syslog.KeyFile) > 0 {
+			cert, err := tls.LoadX509KeyPair(conf.Syslog.CertFile, conf.Syslog.KeyFile)
+			if err != nil {
+				listener.Close()
+				return nil, err
+			}
+			listener = tls.NewListener(listener, &tls.Config{
+				Certificates: []tls.Certificate{cert},
+			})
+		}
+		rdr = newSocketServerReader(
+			"syslog", listener, syslogStreamHandler(conf.Syslog, log, stats), log, stats,
+		)
+		log.Infof("Receiving syslog messages at: tcp://%v\n", listener.Addr())
+	default:
+		return nil, types.ErrInvalidSocketNetwork
+	}
+
+	return NewReader("syslog", reader.NewPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+// parseSyslog parses a raw syslog message into a single part message of JSON.
+// Messages that fail to parse are kept in the message field.
+func parseSyslog(conf SyslogConfig, stats metrics.Type, b []byte) types.Message {
+	sMsg, err := syslog.Parse(b, conf.Format)
+	if err != nil {
+		stats.Incr("input.syslog.parse.error", 1)
+		sMsg = &syslog.Message{Message: string(b)}
+	} else {
+		stats.Incr("input.syslog.parse.success", 1)
+	}
+	jBytes, _ := json.Marshal(sMsg)
+	return types.NewMessage([][]byte{jBytes})
+}
+
+// errSyslogFrame is returned when an octet counted frame is malformed.
+var errSyslogFrame = errors.New("invalid syslog octet counting frame")
+
+// maxSyslogCountLen is the maximum number of digits of an octet count.
+const maxSyslogCountLen = 10
+
+// splitSyslogFrames returns a bufio.SplitFunc for syslog messages framed either
+// by octet counting or by line breaks. Octet counted frames larger than maxSize
+// are rejected.
+func splitSyslogFrames(maxSize int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		if data[0] >= '0' && data[0] <= '9' {
+			i := bytes.IndexByte(data, ' ')
+			if i < 0 {
+				if atEOF || len(data) > maxSyslogCountLen {
+					return 0, nil, errSyslogFrame
+				}
+				return 0, nil, nil
+			}
+			n, err := strconv.Atoi(string(data[:i]))
+			if err != nil || n < 0 || n > maxSize {
+				return 0, nil, errSyslogFrame
+			}
+			if len(data) < i+1+n {
+				if atEOF {
+					return 0, nil, io.ErrUnexpectedEOF
+				}
+				return 0, nil, nil
+			}
+			return i + 1 + n, data[i+1 : i+1+n], nil
+		}
+
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			return i + 1, bytes.TrimSuffix(data[:i], []byte("\r")), nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// newSyslogScanner returns a scanner of syslog frames from a reader, where the
+// buffer fits octet counted frames of maxSize along with their count prefix.
+func newSyslogScanner(r io.Reader, maxSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer([]byte{}, maxSize+maxSyslogCountLen+1)
+	scanner.Split(splitSyslogFrames(maxSize))
+	return scanner
+}
+
+// syslogStreamHandler returns a connection handler that reads framed syslog
+// messages.
+func syslogStreamHandler(conf SyslogConfig, log log.Modular, stats metrics.Type) socketConnHandler {
+	return func(conn net.Conn, send func(msg types.Message) bool) {
+		scanner := newSyslogScanner(conn, conf.MaxBuffer)
+
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			if !send(parseSyslog(conf, stats, scanner.Bytes())) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Errorf("Failed to read syslog connection: %v\n", err)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// syslogPacketReader is a reader implementation that reads a syslog message
+// from each UDP datagram.
+type syslogPacketReader struct {
+	conf  SyslogConfig
+	stats metrics.Type
+
+	conn      net.PacketConn
+	buf       []byte
+	closed    int32
+	closeOnce sync.Once
+}
+
+// Connect is a no-op as the connection is established on construction.
+func (s *syslogPacketReader) Connect() error {
+	return nil
+}
+
+// Read attempts to read a message from the next datagram.
+func (s *syslogPacketReader) Read() (types.Message, error) {
+	for {
+		n, _, err := s.conn.ReadFrom(s.buf)
+		if err != nil {
+			if atomic.LoadInt32(&s.closed) == 1 {
+				return nil, types.ErrTypeClosed
+			}
+			return nil, err
+		}
+		if b := bytes.TrimRight(s.buf[:n], "\r\n"); len(b) > 0 {
+			return parseSyslog(s.conf, s.stats, b), nil
+		}
+	}
+}
+
+// Acknowledge is a no-op as datagrams cannot be acknowledged.
+func (s *syslogPacketReader) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the reader and closes the connection.
+func (s *syslogPacketReader) CloseAsync() {
+	s.closeOnce.Do(func() {
+		atomic.StoreInt32(&s.closed, 1)
+		s.conn.Close()
+	})
+}
+
+// WaitForClose blocks until the reader has closed down.
+func (s *syslogPacketReader) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
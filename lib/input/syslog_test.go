@@ -0,0 +1,272 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package input
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+func readSyslogMsgs(t *testing.T, s Type, n int) []string {
+	var msgs []string
+	for _, m := range readSocketMsgs(t, s, n) {
+		if len(m) != 1 {
+			t.Fatalf("Wrong count of parts: %v", len(m))
+		}
+		msgs = append(msgs, m[0])
+	}
+	return msgs
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conf := NewConfig()
+	conf.Syslog.Address = "localhost:1253"
+
+	s, err := NewSyslog(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := net.Dial("udp", "localhost:1253")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, d := range []string{
+		"<34>1 2003-10-11T22:14:15.003Z mymachine su - ID47 [a b=\"c\"] hello\n",
+		"not syslog",
+	} {
+		if _, err = conn.Write([]byte(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := []string{
+		`{"priority":34,"facility":4,"severity":2,"version":1,"timestamp":"2003-10-11T22:14:15.003Z","hostname":"mymachine","appname":"su","msgid":"ID47","structured_data":{"a":{"b":"c"}},"message":"hello"}`,
+		`{"priority":0,"facility":0,"severity":0,"message":"not syslog"}`,
+	}
+	if act := readSyslogMsgs(t, s, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong messages: %v != %v", act, exp)
+	}
+}
+
+func TestSyslogTCPFraming(t *testing.T) {
+	conf := NewConfig()
+	conf.Syslog.Network = "tcp"
+	conf.Syslog.Address = "localhost:1254"
+	conf.Syslog.Format = "rfc5424"
+
+	s, err := NewSyslog(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:1254")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	msgs := []string{"<13>1 - host app - - - foo", "<13>1 - host app - - - multi\nline"}
+	if _, err = conn.Write([]byte(
+		strconv.Itoa(len(msgs[0])) + " " + msgs[0] +
+			"<13>1 - host app - - - bar\r\n" +
+			strconv.Itoa(len(msgs[1])) + " " + msgs[1],
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		`{"priority":13,"facility":1,"severity":5,"version":1,"hostname":"host","appname":"app","message":"foo"}`,
+		`{"priority":13,"facility":1,"severity":5,"version":1,"hostname":"host","appname":"app","message":"bar"}`,
+		`{"priority":13,"facility":1,"severity":5,"version":1,"hostname":"host","appname":"app","message":"multi\nline"}`,
+	}
+	if act := readSyslogMsgs(t, s, 3); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong messages: %v != %v", act, exp)
+	}
+}
+
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestSyslogTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_syslog_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Syslog.Network = "tcp"
+	conf.Syslog.Address = "localhost:1255"
+	conf.Syslog.CertFile, conf.Syslog.KeyFile = writeTestCert(t, dir)
+
+	s, err := NewSyslog(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", "localhost:1255", &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("<34>Oct 11 22:14:15 mymachine su[12]: failed\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	act := readSyslogMsgs(t, s, 1)[0]
+	for _, exp := range []string{`"appname":"su"`, `"procid":"12"`, `"hostname":"mymachine"`, `"message":"failed"`} {
+		if !strings.Contains(act, exp) {
+			t.Errorf("Message '%v' does not contain '%v'", act, exp)
+		}
+	}
+}
+
+func TestSyslogSplitFrames(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("3 foo\n4 barbaz\nqux\r\n\nquz"))
+	scanner.Split(splitSyslogFrames(bufio.MaxScanTokenSize))
+
+	var act []string
+	for scanner.Scan() {
+		act = append(act, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"foo", "", "barb", "az", "qux", "", "quz"}; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong frames: %q != %q", act, exp)
+	}
+
+	scanner = bufio.NewScanner(strings.NewReader("10 foo"))
+	scanner.Split(splitSyslogFrames(bufio.MaxScanTokenSize))
+	for scanner.Scan() {
+	}
+	if scanner.Err() == nil {
+		t.Error("Expected error from truncated frame")
+	}
+
+	for _, input := range []string{
+		"9223372036854775807 foo",
+		"99999999999999999999 foo",
+		"65537 foo",
+	} {
+		scanner = bufio.NewScanner(strings.NewReader(input))
+		scanner.Split(splitSyslogFrames(65536))
+		for scanner.Scan() {
+		}
+		if exp, act := errSyslogFrame, scanner.Err(); exp != act {
+			t.Errorf("Wrong error for frame '%v': %v != %v", input, act, exp)
+		}
+	}
+
+	// A frame of the maximum size fits the buffer along with its prefix.
+	for _, size := range []int{65536, 65535, 65530} {
+		frame := strings.Repeat("a", size)
+		scanner = newSyslogScanner(strings.NewReader(strconv.Itoa(size)+" "+frame), 65536)
+		if !scanner.Scan() {
+			t.Fatalf("Failed to scan frame of size %v: %v", size, scanner.Err())
+		}
+		if act := scanner.Text(); act != frame {
+			t.Errorf("Wrong frame of size %v: %v", size, len(act))
+		}
+	}
+}
+
+func TestSyslogBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Syslog.Format = "nope"
+	if _, err := NewSyslog(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad format")
+	}
+
+	conf = NewConfig()
+	conf.Syslog.Network = "unix"
+	if _, err := NewSyslog(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err != types.ErrInvalidSocketNetwork {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrInvalidSocketNetwork)
+	}
+}
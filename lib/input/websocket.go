@@ -0,0 +1,66 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["websocket"] = TypeSpec{
+		constructor: NewWebsocket,
+		description: `
+Reads messages from websocket frames. In 'client' mode the input dials the
+websocket server at 'url' and reconnects whenever the connection is lost. In
+'server' mode the input accepts any number of websocket connections at 'path',
+either on the server at 'address' or, when left blank, on the default service.
+
+Each frame is read as a message part. When 'multipart' is set parts are
+accumulated until an empty frame is received, which ends the message.
+Otherwise each non-empty frame is a message of a single part.
+
+Connections are kept alive with ping frames sent every 'ping_period_ms'
+milliseconds, a connection that fails to send a ping is closed. Set this field
+to zero in order to disable pings.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewWebsocket creates a new Websocket input type.
+func NewWebsocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := reader.NewWebsocket(conf.Websocket, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Websocket.Mode == reader.WebsocketModeServer && len(conf.Websocket.Address) == 0 {
+		mgr.RegisterEndpoint(
+			conf.Websocket.Path, "Stream messages into Benthos over a websocket.", w.ServeHTTP,
+		)
+	}
+	return NewReader("websocket", reader.NewPreserver(w), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,169 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package input
+
+import (
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"golang.org/x/net/websocket"
+)
+
+func readWebsocketMsg(t *testing.T, w Type) [][]byte {
+	var ts types.Transaction
+	select {
+	case ts = <-w.TransactionChan():
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for message")
+	}
+	select {
+	case ts.ResponseChan <- types.NewSimpleResponse(nil):
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for response")
+	}
+	return ts.Payload.GetAll()
+}
+
+func TestWebsocketClientMode(t *testing.T) {
+	frames := [][]byte{
+		[]byte("foo"), []byte("bar"), []byte{}, []byte("baz"), []byte{},
+	}
+
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for _, f := range frames {
+			if err := websocket.Message.Send(ws, f); err != nil {
+				t.Error(err)
+			}
+		}
+		var discard []byte
+		websocket.Message.Receive(ws, &discard)
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Websocket.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+	conf.Websocket.Multipart = true
+
+	w, err := NewWebsocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		w.CloseAsync()
+		if err := w.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if exp, act := [][]byte{[]byte("foo"), []byte("bar")}, readWebsocketMsg(t, w); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %s != %s", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("baz")}, readWebsocketMsg(t, w); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %s != %s", act, exp)
+	}
+}
+
+func TestWebsocketClientReconnect(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		// Send a single frame and then drop the connection.
+		websocket.Message.Send(ws, []byte("foo"))
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Websocket.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	w, err := NewWebsocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		w.CloseAsync()
+		if err := w.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		if exp, act := [][]byte{[]byte("foo")}, readWebsocketMsg(t, w); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong message: %s != %s", act, exp)
+		}
+	}
+}
+
+func TestWebsocketServerMode(t *testing.T) {
+	conf := NewConfig()
+	conf.Websocket.Mode = reader.WebsocketModeServer
+	conf.Websocket.Address = "localhost:1245"
+	conf.Websocket.Path = "/testws"
+
+	w, err := NewWebsocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		w.CloseAsync()
+		if err := w.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	var conns []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		var conn *websocket.Conn
+		for j := 0; j < 50; j++ {
+			if conn, err = websocket.Dial("ws://localhost:1245/testws", "", "http://localhost"); err == nil {
+				break
+			}
+			<-time.After(time.Millisecond * 100)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+
+	for i, conn := range conns {
+		if err = websocket.Message.Send(conn, []byte{byte('a' + i)}); err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := [][]byte{{byte('a' + i)}}, readWebsocketMsg(t, w); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong message: %s != %s", act, exp)
+		}
+	}
+}
+
+func TestWebsocketBadMode(t *testing.T) {
+	conf := NewConfig()
+	conf.Websocket.Mode = "nope"
+
+	if _, err := NewWebsocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err != types.ErrInvalidWebsocketMode {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrInvalidWebsocketMode)
+	}
+}
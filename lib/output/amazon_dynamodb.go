@@ -0,0 +1,67 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["amazon_dynamodb"] = TypeSpec{
+		constructor: NewAmazonDynamoDB,
+		description: `
+Writes messages as items to an Amazon DynamoDB table, where each message part
+must be a JSON object that is converted into an item with an attribute per
+field. JSON numbers are written as DynamoDB numbers, objects as maps and arrays
+as lists.
+
+The partition key of the table is set with 'hash_key' and, for tables with a
+composite primary key, the sort key with 'range_key'. By default key attributes
+are taken from the fields of the same name, but they can instead be copied from
+any field of the object by setting 'hash_key_path' and 'range_key_path' to a
+dot separated path. Parts that are not JSON objects or that lack a key are
+dropped.
+
+Items are written with BatchWriteItem requests of at most 'max_batch_count'
+items. Items that are left unprocessed by a request are retried up to 'retries'
+times, waiting 'retry_period_ms' between attempts. A message is only
+acknowledged once all of its items have been written, otherwise the whole
+message is sent again.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAmazonDynamoDB creates a new AmazonDynamoDB output type.
+func NewAmazonDynamoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewAmazonDynamoDB(conf.AmazonDynamoDB, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("amazon_dynamodb", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
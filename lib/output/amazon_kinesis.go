@@ -0,0 +1,65 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["amazon_kinesis"] = TypeSpec{
+		constructor: NewAmazonKinesis,
+		description: `
+Sends messages to an Amazon Kinesis stream, where each message part is sent as
+a separate record. Parts are sent with PutRecords requests of at most
+'max_batch_count' records, and records that fail within a request are retried
+on their own up to 'retries' times, waiting 'retry_period_ms' between attempts.
+A message is only acknowledged once all of its records have been accepted,
+otherwise the whole message is sent again.
+
+The fields 'partition_key' and 'hash_key' can be dynamically set using function
+interpolations described [here](../config_interpolation.md#functions), which
+are resolved for each message part and can therefore reference its contents.
+When 'partition_key' is empty a random key is generated for each record, and
+when 'hash_key' is empty the shard is chosen by hashing the partition key.
+
+The field 'endpoint' can be used to target a local stand-in such as
+[kinesalite](https://github.com/mhart/kinesalite).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAmazonKinesis creates a new AmazonKinesis output type.
+func NewAmazonKinesis(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewAmazonKinesis(conf.AmazonKinesis, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("amazon_kinesis", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
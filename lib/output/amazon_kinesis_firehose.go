@@ -0,0 +1,59 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["amazon_kinesis_firehose"] = TypeSpec{
+		constructor: NewAmazonKinesisFirehose,
+		description: `
+Sends messages to an Amazon Kinesis Firehose delivery stream, where each message
+part is sent as a separate record. Parts are sent with PutRecordBatch requests
+of at most 'max_batch_count' records, and records that fail within a request
+are retried on their own up to 'retries' times, waiting 'retry_period_ms'
+between attempts. A message is only acknowledged once all of its records have
+been accepted, otherwise the whole message is sent again.
+
+Firehose does not add delimiters between records, therefore parts that should
+be separated at the destination need to end with a delimiter such as a newline.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAmazonKinesisFirehose creates a new AmazonKinesisFirehose output type.
+func NewAmazonKinesisFirehose(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewAmazonKinesisFirehose(conf.AmazonKinesisFirehose, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("amazon_kinesis_firehose", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -36,7 +36,30 @@ func init() {
 Sends message parts as objects to an Amazon S3 bucket. Each object is uploaded
 with the path specified with the 'path' field, in order to have a different path
 for each object you should use function interpolations described
-[here](../config_interpolation.md#functions).`,
+[here](../config_interpolation.md#functions).
+
+Objects larger than 'part_size_bytes' are sent with a multipart upload, where
+the part size must be at least 5MB. The 'endpoint' and 'force_path_style_urls'
+fields can be used in order to target S3 compatible services.
+
+### Batching
+
+When any of the 'batching' limits 'max_count', 'max_bytes' or 'period_ms' are
+set, message parts are accumulated into a single object that is uploaded once
+any limit is reached, where the period is measured from the first part of the
+batch. The 'encoding' of the object can be 'lines', where each part is followed
+by a newline, 'gzip', which is the same but gzip compressed, or 'tar', where
+each part is a file of a tar archive.
+
+Messages added to a batch are not acknowledged until an upload that they are
+part of completes. When a 'period_ms' is set a message that does not complete
+its batch is held until the period has passed and the batch is uploaded, and
+since the next message is not sent until then the period is only useful for
+accumulating the parts of multiple part messages, such as those created by the
+'combine' processor. When a buffer is used messages are removed from the buffer
+as soon as they are added to a batch, and therefore delaying acknowledgements
+requires the buffer type 'none'. Any pending batch is uploaded when the output
+is closed.`,
 	}
 }
 
@@ -44,9 +67,11 @@ for each object you should use function interpolations described
 
 // NewAmazonS3 creates a new AmazonS3 output type.
 func NewAmazonS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	return NewWriter(
-		"amazon_s3", writer.NewAmazonS3(conf.AmazonS3, log, stats), log, stats,
-	)
+	s3, err := writer.NewAmazonS3(conf.AmazonS3, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("amazon_s3", s3, log, stats)
 }
 
 //------------------------------------------------------------------------------
@@ -35,6 +35,10 @@ func TestSanitise(t *testing.T) {
 			"key":           "benthos-key",
 			"exchange":      "benthos-exchange",
 			"exchange_type": "direct",
+			"headers":       map[string]interface{}{},
+			"persistent":    false,
+			"mandatory":     false,
+			"immediate":     false,
 		},
 	}
 
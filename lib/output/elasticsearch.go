@@ -0,0 +1,70 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["elasticsearch"] = TypeSpec{
+		constructor: NewElasticsearch,
+		description: `
+Publishes messages into an Elasticsearch index using the bulk API, where each
+message part is indexed as a separate document. Parts that are valid JSON are
+compacted before being sent.
+
+The fields 'index', 'type' and 'id' can be dynamically set using function
+interpolations described [here](../config_interpolation.md#functions), which
+are resolved for each message part and can therefore reference its contents.
+When 'type' is empty it is omitted, and when 'id' is empty Elasticsearch
+generates an ID for each document.
+
+The parts of a message are sent in bulk requests of at most 'max_batch_count'
+documents and 'max_batch_bytes' bytes. Documents that fail within a bulk
+response with a status that can be retried (429 or 5XX) are retried on their
+own up to 'retries' times, waiting 'retry_period_ms' between attempts. A message
+is only acknowledged once all of its parts have been indexed, otherwise the
+whole message is sent again, and therefore setting an 'id' is recommended in
+order to avoid duplicate documents.
+
+Basic authentication and OAuth can be enabled with the 'basic_auth' and 'oauth'
+fields.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewElasticsearch creates a new Elasticsearch output type.
+func NewElasticsearch(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	e, err := writer.NewElasticsearch(conf.Elasticsearch, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("elasticsearch", e, log, stats)
+}
+
+//------------------------------------------------------------------------------
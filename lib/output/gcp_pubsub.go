@@ -0,0 +1,68 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["gcp_pubsub"] = TypeSpec{
+		constructor: NewGCPPubSub,
+		description: `
+Sends messages to a GCP Cloud Pub/Sub topic, where each message part is
+published as a separate Pub/Sub message. A message is only acknowledged once
+all of its parts have been accepted by the server, otherwise the whole message
+is sent again.
+
+The fields 'ordering_key' and the values of 'attributes' can be dynamically set
+using function interpolations described
+[here](../config_interpolation.md#functions), which are resolved for each
+message part and can therefore reference its contents. Messages within Benthos
+do not carry metadata, and therefore ordering keys and attributes can only be
+derived from message contents in this way. When 'ordering_key' is set message
+ordering is enabled for the topic publisher, and parts with the same key are
+delivered in order to subscriptions that have ordering enabled.
+
+Credentials are taken from the environment as described in
+https://cloud.google.com/docs/authentication/production. Setting the
+environment variable ` + "`PUBSUB_EMULATOR_HOST`" + ` connects this output to
+a Pub/Sub emulator instead.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGCPPubSub creates a new GCP Cloud Pub/Sub output type.
+func NewGCPPubSub(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewGCPPubSub(conf.GCPPubSub, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("gcp_pubsub", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
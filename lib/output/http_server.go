@@ -27,6 +27,8 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -50,7 +52,20 @@ service, but this will ignore TLS options.
 
 You can receive a single, discrete message on the configured 'path' endpoint, or
 receive a constant stream of line delimited messages on the configured
-'stream_path' endpoint.`,
+'stream_path' endpoint.
+
+### Server-Sent Events
+
+Messages can also be consumed as a 'text/event-stream' on the configured
+'sse_path' endpoint. Each message is sent as an event with a unique, increasing
+ID, where each line of each message part is written as a 'data' field.
+
+Each message is delivered to only one of the connected clients, and therefore
+multiple clients share the stream of events rather than each receiving a copy.
+The most recent 'sse_replay_size' events are retained, and a client that
+reconnects with the header 'Last-Event-ID' first receives any retained events
+that were sent to the same client after that ID, excluding events sent to other
+clients. Events older than this window cannot be replayed.`,
 	}
 }
 
@@ -58,23 +73,27 @@ receive a constant stream of line delimited messages on the configured
 
 // HTTPServerConfig is configuration for the HTTPServer input type.
 type HTTPServerConfig struct {
-	Address    string `json:"address" yaml:"address"`
-	Path       string `json:"path" yaml:"path"`
-	StreamPath string `json:"stream_path" yaml:"stream_path"`
-	TimeoutMS  int64  `json:"timeout_ms" yaml:"timeout_ms"`
-	CertFile   string `json:"cert_file" yaml:"cert_file"`
-	KeyFile    string `json:"key_file" yaml:"key_file"`
+	Address       string `json:"address" yaml:"address"`
+	Path          string `json:"path" yaml:"path"`
+	StreamPath    string `json:"stream_path" yaml:"stream_path"`
+	SSEPath       string `json:"sse_path" yaml:"sse_path"`
+	SSEReplaySize int    `json:"sse_replay_size" yaml:"sse_replay_size"`
+	TimeoutMS     int64  `json:"timeout_ms" yaml:"timeout_ms"`
+	CertFile      string `json:"cert_file" yaml:"cert_file"`
+	KeyFile       string `json:"key_file" yaml:"key_file"`
 }
 
 // NewHTTPServerConfig creates a new HTTPServerConfig with default values.
 func NewHTTPServerConfig() HTTPServerConfig {
 	return HTTPServerConfig{
-		Address:    "",
-		Path:       "/get",
-		StreamPath: "/get/stream",
-		TimeoutMS:  5000,
-		CertFile:   "",
-		KeyFile:    "",
+		Address:       "",
+		Path:          "/get",
+		StreamPath:    "/get/stream",
+		SSEPath:       "/get/sse",
+		SSEReplaySize: 100,
+		TimeoutMS:     5000,
+		CertFile:      "",
+		KeyFile:       "",
 	}
 }
 
@@ -91,11 +110,23 @@ type HTTPServer struct {
 	mux    *http.ServeMux
 	server *http.Server
 
+	sseLastID  uint64
+	sseMut     sync.Mutex
+	sseReplays []sseEvent
+
 	transactions <-chan types.Transaction
 
 	closedChan chan struct{}
 }
 
+// sseEvent is an encoded server-sent event retained for replays, where prev is
+// the ID of the event previously sent to the same client.
+type sseEvent struct {
+	id   uint64
+	prev uint64
+	data []byte
+}
+
 // NewHTTPServer creates a new HTTPServer input type.
 func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 	var mux *http.ServeMux
@@ -123,6 +154,9 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		if len(h.conf.HTTPServer.StreamPath) > 0 {
 			h.mux.HandleFunc(h.conf.HTTPServer.StreamPath, h.streamHandler)
 		}
+		if len(h.conf.HTTPServer.SSEPath) > 0 {
+			h.mux.HandleFunc(h.conf.HTTPServer.SSEPath, h.sseHandler)
+		}
 	} else {
 		if len(h.conf.HTTPServer.Path) > 0 {
 			mgr.RegisterEndpoint(
@@ -137,6 +171,13 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 				h.streamHandler,
 			)
 		}
+		if len(h.conf.HTTPServer.SSEPath) > 0 {
+			mgr.RegisterEndpoint(
+				h.conf.HTTPServer.SSEPath,
+				"Read a continuous stream of server-sent events from Benthos.",
+				h.sseHandler,
+			)
+		}
 	}
 	return &h, nil
 }
@@ -263,6 +304,142 @@ func (h *HTTPServer) streamHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// encodeSSEEvent assigns a message the next event ID and encodes it as a
+// server-sent event following the event prev sent to the same client.
+func (h *HTTPServer) encodeSSEEvent(msg types.Message, prev uint64) sseEvent {
+	buf := &bytes.Buffer{}
+
+	h.sseMut.Lock()
+	defer h.sseMut.Unlock()
+
+	h.sseLastID++
+	id := h.sseLastID
+
+	buf.WriteString("id: ")
+	buf.WriteString(strconv.FormatUint(id, 10))
+	buf.WriteByte('\n')
+	for _, part := range msg.GetAll() {
+		for _, line := range bytes.Split(part, []byte("\n")) {
+			buf.WriteString("data: ")
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteByte('\n')
+
+	return sseEvent{id: id, prev: prev, data: buf.Bytes()}
+}
+
+// retainSSEEvent retains an event that was successfully sent for replays.
+func (h *HTTPServer) retainSSEEvent(event sseEvent) {
+	h.sseMut.Lock()
+	defer h.sseMut.Unlock()
+
+	if size := h.conf.HTTPServer.SSEReplaySize; size > 0 {
+		if len(h.sseReplays) >= size {
+			h.sseReplays = append(h.sseReplays[:0], h.sseReplays[len(h.sseReplays)-size+1:]...)
+		}
+		h.sseReplays = append(h.sseReplays, event)
+	}
+}
+
+// sseReplaysSince returns all retained events that were sent to the same client
+// after an event ID, and the ID of the last of them.
+func (h *HTTPServer) sseReplaysSince(id uint64) ([][]byte, uint64) {
+	h.sseMut.Lock()
+	defer h.sseMut.Unlock()
+
+	var events [][]byte
+	last := id
+	for _, e := range h.sseReplays {
+		if e.prev == last {
+			events = append(events, e.data)
+			last = e.id
+		}
+	}
+	if len(events) == 0 && h.sseLastID > id && (len(h.sseReplays) == 0 || h.sseReplays[0].id > id+1) {
+		// Some events that followed the ID may no longer be retained.
+		h.stats.Incr("output.http_server.sse.replay.incomplete", 1)
+	}
+	return events, last
+}
+
+func (h *HTTPServer) sseHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	h.stats.Incr("output.http_server.sse.request.received", 1)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		h.stats.Incr("output.http_server.sse.error.cast_flusher", 1)
+		h.log.Errorln("Failed to cast response writer to flusher")
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
+		h.stats.Incr("output.http_server.sse.error.wrong_method", 1)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// The ID of the last event sent to this client, which is zero for new
+	// clients.
+	var lastID uint64
+	if idStr := r.Header.Get("Last-Event-ID"); len(idStr) > 0 {
+		if id, err := strconv.ParseUint(idStr, 10, 64); err == nil && id > 0 {
+			var replays [][]byte
+			replays, lastID = h.sseReplaysSince(id)
+			for _, e := range replays {
+				if _, err = w.Write(e); err != nil {
+					h.stats.Incr("output.http_server.sse.error.write", 1)
+					return
+				}
+				h.stats.Incr("output.http_server.sse.replay.count", 1)
+			}
+		}
+	}
+	flusher.Flush()
+
+	for atomic.LoadInt32(&h.running) == 1 {
+		var ts types.Transaction
+		var open bool
+
+		select {
+		case ts, open = <-h.transactions:
+			if !open {
+				go h.CloseAsync()
+				return
+			}
+		case <-r.Context().Done():
+			h.stats.Incr("output.http_server.sse.client_closed", 1)
+			return
+		}
+		h.stats.Incr("output.http_server.count", 1)
+		h.stats.Incr("output.http_server.sse.count", 1)
+
+		event := h.encodeSSEEvent(ts.Payload, lastID)
+		_, err := w.Write(event.data)
+		ts.ResponseChan <- types.NewSimpleResponse(err)
+
+		if err != nil {
+			h.stats.Incr("output.http_server.sse.error.write", 1)
+			return
+		}
+		h.retainSSEEvent(event)
+		lastID = event.id
+
+		flusher.Flush()
+		h.stats.Incr("output.http_server.send.success", 1)
+		h.stats.Incr("output.http_server.sse.send.success", 1)
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // StartReceiving assigns a messages channel for the output to read.
@@ -21,9 +21,12 @@
 package output
 
 import (
+	"bufio"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -162,3 +165,138 @@ func TestHTTPTimeout(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHTTPServerSSE(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTPServer.Path = ""
+	conf.HTTPServer.StreamPath = ""
+	conf.HTTPServer.SSEReplaySize = 2
+
+	mgr := newMockManager()
+	h, err := NewHTTPServer(conf, mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.CloseAsync()
+		if err := h.WaitForClose(time.Second * 5); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	msgChan := make(chan types.Transaction)
+	if err = h.StartReceiving(msgChan); err != nil {
+		t.Fatal(err)
+	}
+
+	tserve := httptest.NewServer(mgr.endpoints["/get/sse"])
+	defer tserve.Close()
+
+	sendMsg := func(parts ...string) {
+		msg := types.NewMessage(nil)
+		for _, p := range parts {
+			msg.Append([]byte(p))
+		}
+		resChan := make(chan types.Response)
+		select {
+		case msgChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for message")
+			return
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for response")
+		}
+	}
+
+	connect := func(lastEventID string) (*http.Response, *bufio.Scanner) {
+		req, rErr := http.NewRequest("GET", tserve.URL, nil)
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+		if len(lastEventID) > 0 {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, rErr := http.DefaultClient.Do(req)
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+		if exp, act := "text/event-stream", res.Header.Get("Content-Type"); exp != act {
+			t.Errorf("Wrong content type: %v != %v", act, exp)
+		}
+		return res, bufio.NewScanner(res.Body)
+	}
+
+	readLines := func(scanner *bufio.Scanner, n int) []string {
+		var lines []string
+		for i := 0; i < n && scanner.Scan(); i++ {
+			lines = append(lines, scanner.Text())
+		}
+		return lines
+	}
+
+	res, scanner := connect("")
+	for _, m := range [][]string{{"foo"}, {"bar", "baz\nqux"}, {"quz"}} {
+		sendMsg(m...)
+	}
+
+	exp := []string{
+		"id: 1", "data: foo", "",
+		"id: 2", "data: bar", "data: baz", "data: qux", "",
+		"id: 3", "data: quz", "",
+	}
+	if act := readLines(scanner, len(exp)); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong events: %q != %q", act, exp)
+	}
+	res.Body.Close()
+
+	// Wait for the first handler to notice that the client has gone.
+	<-time.After(time.Millisecond * 100)
+
+	// Only the last two events are retained, and therefore the replay of all
+	// events following the first is complete.
+	res, scanner = connect("1")
+	go sendMsg("quack")
+
+	exp = []string{
+		"id: 2", "data: bar", "data: baz", "data: qux", "",
+		"id: 3", "data: quz", "",
+		"id: 4", "data: quack", "",
+	}
+	if act := readLines(scanner, len(exp)); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong events: %q != %q", act, exp)
+	}
+	res.Body.Close()
+	<-time.After(time.Millisecond * 100)
+
+	// Events sent to another client are not replayed.
+	res, scanner = connect("")
+	sendMsg("other1")
+	sendMsg("other2")
+
+	exp = []string{
+		"id: 5", "data: other1", "",
+		"id: 6", "data: other2", "",
+	}
+	if act := readLines(scanner, len(exp)); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong events: %q != %q", act, exp)
+	}
+	res.Body.Close()
+	<-time.After(time.Millisecond * 100)
+
+	res, scanner = connect("4")
+	go sendMsg("quack2")
+
+	exp = []string{
+		"id: 7", "data: quack2", "",
+	}
+	if act := readLines(scanner, len(exp)); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong events: %q != %q", act, exp)
+	}
+	res.Body.Close()
+}
@@ -38,14 +38,26 @@ acknowledged, which is propagated back to the input. The config field
 'ack_replicas' determines whether we wait for acknowledgement from all replicas
 or just a single broker.
 
-If the field 'key' is not empty then each message will be given its contents as
-a key. This field can be dynamically set using function interpolations described
-[here](../config_interpolation.md#functions).
+The fields 'key', 'partition' and the values of 'headers' can be dynamically
+set using function interpolations described
+[here](../config_interpolation.md#functions), which are resolved for each
+message part and can therefore reference its contents. Messages within Benthos
+do not carry metadata, and therefore keys and headers can only be derived from
+message contents in this way.
 
-By default the paritioner will select partitions based on a hash of the key
-value. If the key is empty then a partition is chosen at random. You can
-alternatively force the partitioner to round-robin partitions with the field
-'round_robin_partitions'.`,
+The field 'partitioner' determines how partitions are selected, and can be one
+of 'fnv1a_hash', 'murmur2_hash', 'random', 'round_robin' or 'manual'. The hash
+partitioners select a partition based on a hash of the key, or at random when
+the key is empty, where 'murmur2_hash' matches the default partitioner of the
+Java client. The 'manual' partitioner writes each part to the partition
+resolved from the field 'partition'. The deprecated field
+'round_robin_partitions' overrides the partitioner with 'round_robin' when set.
+
+Record headers require a 'target_version' of at least 0.11.0.0. The field
+'compression' can be one of 'none', 'gzip', 'snappy' or 'lz4', and the fields
+within 'batching' configure how many messages, bytes or milliseconds the
+producer may accumulate before flushing a batch, where zero values are left at
+the client defaults.`,
 	}
 }
 
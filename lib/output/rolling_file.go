@@ -0,0 +1,72 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["rolling_file"] = TypeSpec{
+		constructor: NewRollingFile,
+		description: `
+Appends messages to a file in the same format as the 'file' output, and rotates
+the file once it would exceed 'max_size_bytes', once it has been open for
+'max_period_ms' milliseconds, or when the interpolated value of 'path' changes.
+Setting 'max_size_bytes' or 'max_period_ms' to zero disables the respective
+limit.
+
+The 'path' can be dynamically set using function interpolations described
+[here](../config_interpolation.md#functions), which are resolved with the first
+part of each message. For example, the default path creates a new directory for
+each hour.
+
+Files are flushed and synced to disk when they are rotated. A file rotated due
+to its size or age is renamed with the suffix '.<timestamp>', whereas a file
+rotated due to a path change keeps its name. When 'gzip' is true rotated files
+are compressed and given the additional suffix '.gz', and a file rotated due to
+a path change is also given the timestamp suffix when a compressed file of the
+same name already exists, so that compressed files are never overwritten.
+
+When 'max_files' is greater than zero the oldest rotated files beyond that count
+are removed. Files are found by replacing each interpolation function of 'path'
+with a wildcard and matching any suffix, and therefore other files that match
+this pattern are also removed.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewRollingFile creates a new RollingFile output type.
+func NewRollingFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	r, err := writer.NewRollingFile(conf.RollingFile, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("rolling_file", r, log, stats)
+}
+
+//------------------------------------------------------------------------------
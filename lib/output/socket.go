@@ -0,0 +1,60 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["socket"] = TypeSpec{
+		constructor: NewSocket,
+		description: `
+Writes delimited messages to a plain socket. The 'network' field can be one of
+'tcp', 'udp' or 'unix', and the output dials 'address' on start up.
+
+Each message part is followed by a line break, or a custom delimiter when set,
+and messages of multiple parts are followed by an extra delimiter in order to
+mark the end of the message. For the 'udp' network each message is written as a
+single datagram.
+
+When the connection is lost the output reconnects, where consecutive failed
+attempts are delayed by an exponential backoff of up to 'max_backoff_ms'
+milliseconds.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSocket creates a new Socket output type.
+func NewSocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewSocket(conf.Socket, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("socket", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,182 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package output
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+func sendSocketMsg(t *testing.T, tChan chan types.Transaction, parts ...string) {
+	msg := types.NewMessage(nil)
+	for _, p := range parts {
+		msg.Append([]byte(p))
+	}
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out sending message")
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for response")
+	}
+}
+
+func TestSocketTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	linesChan := make(chan []string)
+	go func() {
+		for {
+			conn, cerr := ln.Accept()
+			if cerr != nil {
+				return
+			}
+			var lines []string
+			scanner := bufio.NewScanner(conn)
+			for len(lines) < 4 && scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			conn.Close()
+			linesChan <- lines
+		}
+	}()
+
+	conf := NewConfig()
+	conf.Socket.Address = ln.Addr().String()
+
+	s, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	tChan := make(chan types.Transaction)
+	if err = s.StartReceiving(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	sendSocketMsg(t, tChan, "foo")
+	sendSocketMsg(t, tChan, "bar", "baz")
+
+	exp := []string{"foo", "bar", "baz", ""}
+	select {
+	case act := <-linesChan:
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong lines: %q != %q", act, exp)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for lines")
+	}
+
+	// The server has closed the connection, writes continue until the loss is
+	// detected and the output reconnects.
+	doneChan := make(chan struct{})
+	defer close(doneChan)
+	go func() {
+		for {
+			resChan := make(chan types.Response)
+			select {
+			case tChan <- types.NewTransaction(types.NewMessage([][]byte{[]byte("qux")}), resChan):
+			case <-doneChan:
+				return
+			}
+			select {
+			case <-resChan:
+			case <-doneChan:
+				return
+			}
+		}
+	}()
+
+	select {
+	case act := <-linesChan:
+		for _, l := range act {
+			if l != "qux" {
+				t.Errorf("Wrong line: %v", l)
+			}
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("Timed out waiting for reconnect")
+	}
+}
+
+func TestSocketUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conf := NewConfig()
+	conf.Socket.Network = "udp"
+	conf.Socket.Address = conn.LocalAddr().String()
+
+	s, err := NewSocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	tChan := make(chan types.Transaction)
+	if err = s.StartReceiving(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	sendSocketMsg(t, tChan, "foo", "bar")
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar\n\n", string(buf[:n]); exp != act {
+		t.Errorf("Wrong datagram: %q != %q", act, exp)
+	}
+}
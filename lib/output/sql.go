@@ -0,0 +1,82 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["sql"] = TypeSpec{
+		constructor: NewSQL,
+		description: `
+Executes a statement against an SQL database for each part of a message. The
+supported drivers are 'mysql', 'postgres' and 'sqlite3', and 'dsn' is the data
+source name in the format of the chosen driver. The 'sqlite3' driver is only
+available when Benthos is built with cgo enabled, which is not the case for the
+Docker image, and otherwise the output fails to be created.
+
+The arguments of the statement are mapped from fields of each message part,
+which is parsed as JSON, where each entry of 'args' is the dot path of a field
+to use for the placeholder of the same position. Placeholders are '?' for
+'mysql' and 'sqlite3' and '$1', '$2', etc for 'postgres'. Fields that do not
+exist are NULL, objects and arrays are written as JSON strings, and an empty
+path refers to the entire raw content of the part.
+
+Rows can be upserted with the syntax of the database, for example with Postgres
+or SQLite:
+
+` + "``` sql" + `
+INSERT INTO benthos_table (id, body) VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE SET body = excluded.body
+` + "```" + `
+
+Or with MySQL:
+
+` + "``` sql" + `
+INSERT INTO benthos_table (id, body) VALUES (?, ?)
+ON DUPLICATE KEY UPDATE body = VALUES(body)
+` + "```" + `
+
+The statements of all parts of a message are executed within a single
+transaction, and therefore a batch of messages combined into a single message
+is written atomically. If any part fails the transaction is rolled back and the
+message is written again.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSQL creates a new SQL output type.
+func NewSQL(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSQL(conf.SQL, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("sql", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,133 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package output
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["sync_response"] = TypeSpec{
+		constructor: NewSyncResponse,
+		description: `
+The sync response output type does not write messages anywhere. Instead each
+message is returned to the input it originated from as part of the
+acknowledgement, allowing inputs that support it (such as the 'http_server'
+input with 'sync_response' enabled) to reply to requests with the processed
+message.
+
+Responses are only able to reach the input when the 'none' buffer is used and
+there are no brokers between the input and this output.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SyncResponse is an output type that returns messages to their source within
+// the transaction response.
+type SyncResponse struct {
+	running int32
+
+	log   log.Modular
+	stats metrics.Type
+
+	transactions <-chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewSyncResponse creates a new SyncResponse output type.
+func NewSyncResponse(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	return &SyncResponse{
+		running:    1,
+		log:        log.NewModule(".output.sync_response"),
+		stats:      stats,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loop is an internal loop that responds to each transaction with its message.
+func (s *SyncResponse) loop() {
+	defer func() {
+		s.stats.Decr("output.sync_response.running", 1)
+		close(s.closedChan)
+	}()
+	s.stats.Incr("output.sync_response.running", 1)
+
+	for atomic.LoadInt32(&s.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-s.transactions:
+			if !open {
+				return
+			}
+			s.stats.Incr("output.sync_response.count", 1)
+		case <-s.closeChan:
+			return
+		}
+		select {
+		case ts.ResponseChan <- types.NewMessageResponse(ts.Payload):
+			s.stats.Incr("output.sync_response.send.success", 1)
+		case <-s.closeChan:
+			return
+		}
+	}
+}
+
+// StartReceiving assigns a messages channel for the output to read.
+func (s *SyncResponse) StartReceiving(ts <-chan types.Transaction) error {
+	if s.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	s.transactions = ts
+	go s.loop()
+	return nil
+}
+
+// CloseAsync shuts down the SyncResponse output and stops processing messages.
+func (s *SyncResponse) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&s.running, 1, 0) {
+		close(s.closeChan)
+	}
+}
+
+// WaitForClose blocks until the SyncResponse output has closed down.
+func (s *SyncResponse) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-s.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,73 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["websocket"] = TypeSpec{
+		constructor: NewWebsocket,
+		description: `
+Sends messages as websocket frames. In 'client' mode the output dials the
+websocket server at 'url' and reconnects whenever the connection is lost. In
+'server' mode the output accepts any number of websocket connections at 'path',
+either on the server at 'address' or, when left blank, on the default service,
+and each message is sent to all connected clients. When no clients are
+connected the output blocks until one connects.
+
+Each message part is sent as a frame, and messages of multiple parts are
+followed by an empty frame, which marks the end of the message for a websocket
+input with 'multipart' set.
+
+Connections are kept alive with ping frames sent every 'ping_period_ms'
+milliseconds, a connection that fails to send a ping is closed. Set this field
+to zero in order to disable pings.
+
+Each message and ping must be written within 'write_timeout_ms' milliseconds,
+otherwise the connection is considered lost. In server mode this means that a
+client that stops reading is dropped rather than holding up the other clients.
+Set this field to zero in order to disable the timeout.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewWebsocket creates a new Websocket output type.
+func NewWebsocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewWebsocket(conf.Websocket, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Websocket.Mode == writer.WebsocketModeServer && len(conf.Websocket.Address) == 0 {
+		mgr.RegisterEndpoint(
+			conf.Websocket.Path, "Stream messages out of Benthos over a websocket.", w.ServeHTTP,
+		)
+	}
+	return NewWriter("websocket", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
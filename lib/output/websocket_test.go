@@ -0,0 +1,149 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package output
+
+import (
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"golang.org/x/net/websocket"
+)
+
+func sendWebsocketMsg(t *testing.T, tChan chan types.Transaction, parts ...[]byte) {
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(types.NewMessage(parts), resChan):
+	case <-time.After(time.Second * 5):
+		t.Error("Timed out sending message")
+		return
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second * 5):
+		t.Error("Timed out waiting for response")
+	}
+}
+
+func receiveWebsocketFrames(t *testing.T, conn *websocket.Conn, n int) [][]byte {
+	frames := [][]byte{}
+	for i := 0; i < n; i++ {
+		var frame []byte
+		if err := websocket.Message.Receive(conn, &frame); err != nil {
+			t.Error(err)
+			break
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestWebsocketClientMode(t *testing.T) {
+	framesChan := make(chan [][]byte)
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		framesChan <- receiveWebsocketFrames(t, ws, 4)
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Websocket.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	w, err := NewWebsocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		w.CloseAsync()
+		if err := w.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	tChan := make(chan types.Transaction)
+	if err = w.StartReceiving(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	sendWebsocketMsg(t, tChan, []byte("foo"))
+	sendWebsocketMsg(t, tChan, []byte("bar"), []byte("baz"))
+
+	exp := [][]byte{[]byte("foo"), []byte("bar"), []byte("baz"), {}}
+	select {
+	case act := <-framesChan:
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong frames: %s != %s", act, exp)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for frames")
+	}
+}
+
+func TestWebsocketServerMode(t *testing.T) {
+	conf := NewConfig()
+	conf.Websocket.Mode = writer.WebsocketModeServer
+	conf.Websocket.Address = "localhost:1246"
+	conf.Websocket.Path = "/testws"
+
+	w, err := NewWebsocket(conf, nil, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		w.CloseAsync()
+		if err := w.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	tChan := make(chan types.Transaction)
+	if err = w.StartReceiving(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	var conn *websocket.Conn
+	for i := 0; i < 50; i++ {
+		if conn, err = websocket.Dial("ws://localhost:1246/testws", "", "http://localhost"); err == nil {
+			break
+		}
+		<-time.After(time.Millisecond * 100)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The write blocks until the client has been registered.
+	go sendWebsocketMsg(t, tChan, []byte("foo"), []byte("bar"))
+
+	exp := [][]byte{[]byte("foo"), []byte("bar"), {}}
+	if act := receiveWebsocketFrames(t, conn, 3); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong frames: %s != %s", act, exp)
+	}
+}
@@ -77,6 +77,7 @@ func (w *Writer) loop() {
 		countPath      = "output." + w.typeStr + ".count"
 		successPath    = "output." + w.typeStr + ".send.success"
 		errorPath      = "output." + w.typeStr + ".send.error"
+		pendingPath    = "output." + w.typeStr + ".send.pending"
 		connPath       = "output." + w.typeStr + ".connection.up"
 		failedConnPath = "output." + w.typeStr + ".connection.failed"
 		lostConnPath   = "output." + w.typeStr + ".connection.lost"
@@ -157,14 +158,18 @@ func (w *Writer) loop() {
 			return
 		}
 
-		if err != nil {
+		var res types.Response = types.NewSimpleResponse(err)
+		if err == writer.ErrBatchPending {
+			w.stats.Incr(pendingPath, 1)
+			res = types.NewUnacknowledgedResponse()
+		} else if err != nil {
 			w.log.Errorf("Failed to send message to %v: %v\n", w.typeStr, err)
 			w.stats.Incr(errorPath, 1)
 		} else {
 			w.stats.Incr(successPath, 1)
 		}
 		select {
-		case ts.ResponseChan <- types.NewSimpleResponse(err):
+		case ts.ResponseChan <- res:
 		case <-w.closeChan:
 			return
 		}
@@ -0,0 +1,287 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/aws/attribute"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/gabs"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+//------------------------------------------------------------------------------
+
+// The maximum number of items accepted by a single BatchWriteItem request.
+const dynamoDBMaxBatchCount = 25
+
+// AmazonDynamoDBConfig contains configuration fields for the output
+// AmazonDynamoDB type.
+type AmazonDynamoDBConfig struct {
+	sess.Config   `json:",inline" yaml:",inline"`
+	Table         string `json:"table" yaml:"table"`
+	HashKey       string `json:"hash_key" yaml:"hash_key"`
+	HashKeyPath   string `json:"hash_key_path" yaml:"hash_key_path"`
+	RangeKey      string `json:"range_key" yaml:"range_key"`
+	RangeKeyPath  string `json:"range_key_path" yaml:"range_key_path"`
+	MaxBatchCount int    `json:"max_batch_count" yaml:"max_batch_count"`
+	RetryMS       int64  `json:"retry_period_ms" yaml:"retry_period_ms"`
+	NumRetries    int    `json:"retries" yaml:"retries"`
+}
+
+// NewAmazonDynamoDBConfig creates a new Config with default values.
+func NewAmazonDynamoDBConfig() AmazonDynamoDBConfig {
+	return AmazonDynamoDBConfig{
+		Config:        sess.NewConfig(),
+		Table:         "",
+		HashKey:       "id",
+		HashKeyPath:   "",
+		RangeKey:      "",
+		RangeKeyPath:  "",
+		MaxBatchCount: dynamoDBMaxBatchCount,
+		RetryMS:       1000,
+		NumRetries:    3,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AmazonDynamoDB is a benthos writer.Type implementation that writes messages
+// as items to an Amazon DynamoDB table.
+type AmazonDynamoDB struct {
+	conf AmazonDynamoDBConfig
+
+	dynamo *dynamodb.DynamoDB
+	mut    sync.RWMutex
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewAmazonDynamoDB creates a new Amazon DynamoDB writer.Type.
+func NewAmazonDynamoDB(
+	conf AmazonDynamoDBConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*AmazonDynamoDB, error) {
+	if len(conf.Table) == 0 {
+		return nil, errors.New("invalid table (cannot be empty)")
+	}
+	if len(conf.HashKey) == 0 {
+		return nil, errors.New("invalid hash_key (cannot be empty)")
+	}
+	if conf.MaxBatchCount <= 0 || conf.MaxBatchCount > dynamoDBMaxBatchCount {
+		return nil, fmt.Errorf("invalid max_batch_count (must be between 1 and %v)", dynamoDBMaxBatchCount)
+	}
+	return &AmazonDynamoDB{
+		conf:      conf,
+		closeChan: make(chan struct{}),
+		log:       log.NewModule(".output.amazon_dynamodb"),
+		stats:     stats,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target table.
+func (a *AmazonDynamoDB) Connect() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.dynamo != nil {
+		return nil
+	}
+
+	sess, err := a.conf.GetSession()
+	if err != nil {
+		return err
+	}
+
+	a.dynamo = dynamodb.New(sess)
+
+	a.log.Infof("Writing items to Amazon DynamoDB table: %v\n", a.conf.Table)
+	return nil
+}
+
+// setDynamoDBKey sets a key attribute of an item, taking the value from a path of the
+// JSON object when the path is set.
+func setDynamoDBKey(
+	item map[string]*dynamodb.AttributeValue,
+	obj map[string]interface{},
+	key, path string,
+) error {
+	if len(path) > 0 {
+		gObj, err := gabs.Consume(obj)
+		if err != nil {
+			return err
+		}
+		v := gObj.Path(path).Data()
+		if v == nil {
+			return fmt.Errorf("key path '%v' not found", path)
+		}
+		av, err := attribute.FromJSON(v)
+		if err != nil {
+			return err
+		}
+		item[key] = av
+	}
+	if _, exists := item[key]; !exists {
+		return fmt.Errorf("key attribute '%v' not found", key)
+	}
+	return nil
+}
+
+// toItem converts a message part into a DynamoDB item.
+func (a *AmazonDynamoDB) toItem(part []byte) (map[string]*dynamodb.AttributeValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(part))
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("failed to parse part as JSON object: %v", err)
+	}
+	item, err := attribute.MapFromJSON(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err = setDynamoDBKey(item, obj, a.conf.HashKey, a.conf.HashKeyPath); err != nil {
+		return nil, err
+	}
+	if len(a.conf.RangeKey) > 0 {
+		if err = setDynamoDBKey(item, obj, a.conf.RangeKey, a.conf.RangeKeyPath); err != nil {
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+// itemKey returns a string that uniquely identifies the key of an item.
+func (a *AmazonDynamoDB) itemKey(item map[string]*dynamodb.AttributeValue) string {
+	key := item[a.conf.HashKey].String()
+	if len(a.conf.RangeKey) > 0 {
+		key += item[a.conf.RangeKey].String()
+	}
+	return key
+}
+
+// writeBatch writes a batch of items, retrying unprocessed items.
+func (a *AmazonDynamoDB) writeBatch(client *dynamodb.DynamoDB, items []map[string]*dynamodb.AttributeValue) error {
+	requests := make([]*dynamodb.WriteRequest, 0, len(items))
+	for _, item := range items {
+		requests = append(requests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{Item: item},
+		})
+	}
+
+	for i := 0; ; i++ {
+		res, err := client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{
+				a.conf.Table: requests,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if requests = res.UnprocessedItems[a.conf.Table]; len(requests) == 0 {
+			return nil
+		}
+		if i >= a.conf.NumRetries {
+			return fmt.Errorf("failed to write %v unprocessed items", len(requests))
+		}
+
+		a.stats.Incr("output.amazon_dynamodb.send.retry", int64(len(requests)))
+		select {
+		case <-time.After(time.Duration(a.conf.RetryMS) * time.Millisecond):
+		case <-a.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+}
+
+// Write attempts to write each part of a message as an item to the target
+// table. Parts that are not JSON objects containing the configured keys are
+// dropped.
+func (a *AmazonDynamoDB) Write(msg types.Message) error {
+	a.mut.RLock()
+	client := a.dynamo
+	a.mut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	var batch []map[string]*dynamodb.AttributeValue
+	batchKeys := map[string]struct{}{}
+
+	for _, part := range msg.GetAll() {
+		item, err := a.toItem(part)
+		if err != nil {
+			a.stats.Incr("output.amazon_dynamodb.send.dropped.invalid", 1)
+			a.log.Errorf("Dropping message part: %v\n", err)
+			continue
+		}
+
+		// A batch may not contain the same key twice, therefore we send the
+		// current batch before adding a duplicate key.
+		key := a.itemKey(item)
+		if _, exists := batchKeys[key]; exists || len(batch) == a.conf.MaxBatchCount {
+			if err = a.writeBatch(client, batch); err != nil {
+				return err
+			}
+			batch = nil
+			batchKeys = map[string]struct{}{}
+		}
+		batch = append(batch, item)
+		batchKeys[key] = struct{}{}
+	}
+
+	if len(batch) > 0 {
+		return a.writeBatch(client, batch)
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *AmazonDynamoDB) CloseAsync() {
+	a.closedOnce.Do(func() {
+		close(a.closeChan)
+	})
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs. If the timeout occurs then an error is returned.
+func (a *AmazonDynamoDB) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,210 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+type dynamoDBTestItem map[string]map[string]interface{}
+
+// dynamoDBTestServer is a minimal DynamoDB stand-in that accepts BatchWriteItem
+// requests, leaving the first attempt of each item with an "id" in failOnce
+// unprocessed.
+type dynamoDBTestServer struct {
+	sync.Mutex
+	requests [][]dynamoDBTestItem
+	failOnce map[string]bool
+}
+
+func (d *dynamoDBTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+
+	if target := r.Header.Get("X-Amz-Target"); target != "DynamoDB_20120810.BatchWriteItem" {
+		http.Error(w, "unexpected target: "+target, http.StatusBadRequest)
+		return
+	}
+
+	type writeRequest struct {
+		PutRequest struct {
+			Item dynamoDBTestItem
+		}
+	}
+	var req struct {
+		RequestItems map[string][]writeRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var items []dynamoDBTestItem
+	var unprocessed []writeRequest
+	for _, wr := range req.RequestItems["foo"] {
+		items = append(items, wr.PutRequest.Item)
+		if id, _ := wr.PutRequest.Item["id"]["S"].(string); d.failOnce[id] {
+			delete(d.failOnce, id)
+			unprocessed = append(unprocessed, wr)
+		}
+	}
+	d.requests = append(d.requests, items)
+
+	res := map[string]interface{}{}
+	if len(unprocessed) > 0 {
+		res["UnprocessedItems"] = map[string]interface{}{"foo": unprocessed}
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(res)
+}
+
+func newDynamoDBTestWriter(t *testing.T, conf AmazonDynamoDBConfig) *AmazonDynamoDB {
+	conf.Credentials.ID = "id"
+	conf.Credentials.Secret = "secret"
+	conf.Table = "foo"
+	conf.RetryMS = 1
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	w, err := NewAmazonDynamoDB(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+//------------------------------------------------------------------------------
+
+func TestAmazonDynamoDBBatches(t *testing.T) {
+	d := &dynamoDBTestServer{
+		failOnce: map[string]bool{"b": true},
+	}
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	conf := NewAmazonDynamoDBConfig()
+	conf.Endpoint = server.URL
+	w := newDynamoDBTestWriter(t, conf)
+
+	if err := w.Write(types.NewMessage([][]byte{
+		[]byte(`{"id":"a","n":1}`),
+		[]byte(`{"id":"b","n":2}`),
+		[]byte(`not json`),
+		[]byte(`{"no_id":"c"}`),
+		[]byte(`{"id":"a","n":3}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	// The duplicate key "a" starts a new batch, and "b" is retried on its own.
+	exp := [][]string{
+		{`{"id":{"S":"a"},"n":{"N":"1"}}`, `{"id":{"S":"b"},"n":{"N":"2"}}`},
+		{`{"id":{"S":"b"},"n":{"N":"2"}}`},
+		{`{"id":{"S":"a"},"n":{"N":"3"}}`},
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if len(d.requests) != len(exp) {
+		t.Fatalf("Wrong count of requests: %v != %v", len(d.requests), len(exp))
+	}
+	for i, items := range d.requests {
+		if len(items) != len(exp[i]) {
+			t.Errorf("Wrong count of items in request %v: %v != %v", i, len(items), len(exp[i]))
+			continue
+		}
+		for j, item := range items {
+			act, _ := json.Marshal(item)
+			if string(act) != exp[i][j] {
+				t.Errorf("Wrong item in request %v: %s != %v", i, act, exp[i][j])
+			}
+		}
+	}
+}
+
+func TestAmazonDynamoDBKeyPaths(t *testing.T) {
+	d := &dynamoDBTestServer{}
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	conf := NewAmazonDynamoDBConfig()
+	conf.Endpoint = server.URL
+	conf.HashKeyPath = "user.name"
+	conf.RangeKey = "ts"
+	conf.RangeKeyPath = "meta.ts"
+	w := newDynamoDBTestWriter(t, conf)
+
+	if err := w.Write(types.NewMessage([][]byte{
+		[]byte(`{"user":{"name":"foo"},"meta":{"ts":1234567890123}}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if len(d.requests) != 1 || len(d.requests[0]) != 1 {
+		t.Fatalf("Wrong requests: %v", d.requests)
+	}
+	item := d.requests[0][0]
+	if exp, act := "foo", item["id"]["S"]; exp != act {
+		t.Errorf("Wrong hash key: %v != %v", act, exp)
+	}
+	if exp, act := "1234567890123", item["ts"]["N"]; exp != act {
+		t.Errorf("Wrong range key: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonDynamoDBRetriesExhausted(t *testing.T) {
+	d := &dynamoDBTestServer{
+		failOnce: map[string]bool{"a": true},
+	}
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	conf := NewAmazonDynamoDBConfig()
+	conf.Endpoint = server.URL
+	conf.NumRetries = 0
+	w := newDynamoDBTestWriter(t, conf)
+
+	if err := w.Write(types.NewMessage([][]byte{[]byte(`{"id":"a"}`)})); err == nil {
+		t.Error("Expected error from unprocessed item")
+	}
+	if err := w.Write(types.NewMessage([][]byte{[]byte(`{"id":"a"}`)})); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
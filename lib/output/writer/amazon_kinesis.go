@@ -0,0 +1,244 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	uuid "github.com/satori/go.uuid"
+)
+
+//------------------------------------------------------------------------------
+
+// The maximum number of records accepted by a single PutRecords request.
+const kinesisMaxRecordsCount = 500
+
+// AmazonKinesisConfig contains configuration fields for the output
+// AmazonKinesis type.
+type AmazonKinesisConfig struct {
+	sess.Config   `json:",inline" yaml:",inline"`
+	Stream        string `json:"stream" yaml:"stream"`
+	PartitionKey  string `json:"partition_key" yaml:"partition_key"`
+	HashKey       string `json:"hash_key" yaml:"hash_key"`
+	MaxBatchCount int    `json:"max_batch_count" yaml:"max_batch_count"`
+	RetryMS       int64  `json:"retry_period_ms" yaml:"retry_period_ms"`
+	NumRetries    int    `json:"retries" yaml:"retries"`
+}
+
+// NewAmazonKinesisConfig creates a new Config with default values.
+func NewAmazonKinesisConfig() AmazonKinesisConfig {
+	return AmazonKinesisConfig{
+		Config:        sess.NewConfig(),
+		Stream:        "",
+		PartitionKey:  "",
+		HashKey:       "",
+		MaxBatchCount: kinesisMaxRecordsCount,
+		RetryMS:       1000,
+		NumRetries:    3,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AmazonKinesis is a benthos writer.Type implementation that writes messages to
+// an Amazon Kinesis stream.
+type AmazonKinesis struct {
+	conf AmazonKinesisConfig
+
+	partitionKey       []byte
+	interpPartitionKey bool
+	hashKey            []byte
+	interpHashKey      bool
+
+	kinesis *kinesis.Kinesis
+	mut     sync.RWMutex
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewAmazonKinesis creates a new Amazon Kinesis writer.Type.
+func NewAmazonKinesis(
+	conf AmazonKinesisConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*AmazonKinesis, error) {
+	if len(conf.Stream) == 0 {
+		return nil, errors.New("invalid stream (cannot be empty)")
+	}
+	if conf.MaxBatchCount <= 0 || conf.MaxBatchCount > kinesisMaxRecordsCount {
+		return nil, fmt.Errorf("invalid max_batch_count (must be between 1 and %v)", kinesisMaxRecordsCount)
+	}
+	partitionKey := []byte(conf.PartitionKey)
+	hashKey := []byte(conf.HashKey)
+	return &AmazonKinesis{
+		conf:               conf,
+		partitionKey:       partitionKey,
+		interpPartitionKey: text.ContainsFunctionVariables(partitionKey),
+		hashKey:            hashKey,
+		interpHashKey:      text.ContainsFunctionVariables(hashKey),
+		closeChan:          make(chan struct{}),
+		log:                log.NewModule(".output.amazon_kinesis"),
+		stats:              stats,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target Kinesis stream.
+func (a *AmazonKinesis) Connect() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.kinesis != nil {
+		return nil
+	}
+
+	sess, err := a.conf.GetSession()
+	if err != nil {
+		return err
+	}
+
+	a.kinesis = kinesis.New(sess)
+
+	a.log.Infof("Sending messages to Amazon Kinesis stream: %v\n", a.conf.Stream)
+	return nil
+}
+
+// toRecords converts the parts of a message into Kinesis records, resolving
+// the partition and hash keys of each part.
+func (a *AmazonKinesis) toRecords(msg types.Message) []*kinesis.PutRecordsRequestEntry {
+	records := make([]*kinesis.PutRecordsRequestEntry, 0, msg.Len())
+	for i, part := range msg.GetAll() {
+		partitionKey := a.partitionKey
+		if a.interpPartitionKey {
+			partitionKey = text.ReplaceMessageFunctionVariables(msg, i, a.partitionKey)
+		}
+		if len(partitionKey) == 0 {
+			partitionKey = []byte(uuid.NewV4().String())
+		}
+		record := &kinesis.PutRecordsRequestEntry{
+			Data:         part,
+			PartitionKey: aws.String(string(partitionKey)),
+		}
+
+		hashKey := a.hashKey
+		if a.interpHashKey {
+			hashKey = text.ReplaceMessageFunctionVariables(msg, i, a.hashKey)
+		}
+		if len(hashKey) > 0 {
+			record.ExplicitHashKey = aws.String(string(hashKey))
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// putRecords sends a batch of records and returns the records that failed.
+func (a *AmazonKinesis) putRecords(
+	client *kinesis.Kinesis,
+	records []*kinesis.PutRecordsRequestEntry,
+) ([]*kinesis.PutRecordsRequestEntry, error) {
+	res, err := client.PutRecords(&kinesis.PutRecordsInput{
+		StreamName: aws.String(a.conf.Stream),
+		Records:    records,
+	})
+	if err != nil {
+		return records, err
+	}
+	if aws.Int64Value(res.FailedRecordCount) == 0 {
+		return nil, nil
+	}
+
+	var failed []*kinesis.PutRecordsRequestEntry
+	for i, entry := range res.Records {
+		if entry.ErrorCode != nil && i < len(records) {
+			failed = append(failed, records[i])
+			err = fmt.Errorf("%v: %v", aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage))
+		}
+	}
+	return failed, err
+}
+
+// Write attempts to write each part of a message as a record to the target
+// Kinesis stream. Records that fail are retried on their own.
+func (a *AmazonKinesis) Write(msg types.Message) error {
+	a.mut.RLock()
+	client := a.kinesis
+	a.mut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	records := a.toRecords(msg)
+	for len(records) > 0 {
+		batch := records
+		if len(batch) > a.conf.MaxBatchCount {
+			batch = batch[:a.conf.MaxBatchCount]
+		}
+		records = records[len(batch):]
+
+		failed, err := a.putRecords(client, batch)
+		for i := 0; len(failed) > 0 && i < a.conf.NumRetries; i++ {
+			a.stats.Incr("output.amazon_kinesis.send.retry", int64(len(failed)))
+			a.log.Warnf("Retrying %v failed records: %v\n", len(failed), err)
+			select {
+			case <-time.After(time.Duration(a.conf.RetryMS) * time.Millisecond):
+			case <-a.closeChan:
+				return types.ErrTypeClosed
+			}
+			failed, err = a.putRecords(client, failed)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to send %v records: %v", len(failed), err)
+		}
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *AmazonKinesis) CloseAsync() {
+	a.closedOnce.Do(func() {
+		close(a.closeChan)
+	})
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs. If the timeout occurs then an error is returned.
+func (a *AmazonKinesis) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
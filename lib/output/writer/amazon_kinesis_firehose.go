@@ -0,0 +1,202 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/firehose"
+)
+
+//------------------------------------------------------------------------------
+
+// The maximum number of records accepted by a single PutRecordBatch request.
+const firehoseMaxRecordsCount = 500
+
+// AmazonKinesisFirehoseConfig contains configuration fields for the output
+// AmazonKinesisFirehose type.
+type AmazonKinesisFirehoseConfig struct {
+	sess.Config   `json:",inline" yaml:",inline"`
+	Stream        string `json:"stream" yaml:"stream"`
+	MaxBatchCount int    `json:"max_batch_count" yaml:"max_batch_count"`
+	RetryMS       int64  `json:"retry_period_ms" yaml:"retry_period_ms"`
+	NumRetries    int    `json:"retries" yaml:"retries"`
+}
+
+// NewAmazonKinesisFirehoseConfig creates a new Config with default values.
+func NewAmazonKinesisFirehoseConfig() AmazonKinesisFirehoseConfig {
+	return AmazonKinesisFirehoseConfig{
+		Config:        sess.NewConfig(),
+		Stream:        "",
+		MaxBatchCount: firehoseMaxRecordsCount,
+		RetryMS:       1000,
+		NumRetries:    3,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AmazonKinesisFirehose is a benthos writer.Type implementation that writes
+// messages to an Amazon Kinesis Firehose delivery stream.
+type AmazonKinesisFirehose struct {
+	conf AmazonKinesisFirehoseConfig
+
+	firehose *firehose.Firehose
+	mut      sync.RWMutex
+
+	closeChan  chan struct{}
+	closedOnce sync.Once
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewAmazonKinesisFirehose creates a new Amazon Kinesis Firehose writer.Type.
+func NewAmazonKinesisFirehose(
+	conf AmazonKinesisFirehoseConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*AmazonKinesisFirehose, error) {
+	if len(conf.Stream) == 0 {
+		return nil, errors.New("invalid stream (cannot be empty)")
+	}
+	if conf.MaxBatchCount <= 0 || conf.MaxBatchCount > firehoseMaxRecordsCount {
+		return nil, fmt.Errorf("invalid max_batch_count (must be between 1 and %v)", firehoseMaxRecordsCount)
+	}
+	return &AmazonKinesisFirehose{
+		conf:      conf,
+		closeChan: make(chan struct{}),
+		log:       log.NewModule(".output.amazon_kinesis_firehose"),
+		stats:     stats,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target delivery stream.
+func (a *AmazonKinesisFirehose) Connect() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.firehose != nil {
+		return nil
+	}
+
+	sess, err := a.conf.GetSession()
+	if err != nil {
+		return err
+	}
+
+	a.firehose = firehose.New(sess)
+
+	a.log.Infof("Sending messages to Amazon Kinesis Firehose delivery stream: %v\n", a.conf.Stream)
+	return nil
+}
+
+// putRecords sends a batch of records and returns the records that failed.
+func (a *AmazonKinesisFirehose) putRecords(
+	client *firehose.Firehose,
+	records []*firehose.Record,
+) ([]*firehose.Record, error) {
+	res, err := client.PutRecordBatch(&firehose.PutRecordBatchInput{
+		DeliveryStreamName: aws.String(a.conf.Stream),
+		Records:            records,
+	})
+	if err != nil {
+		return records, err
+	}
+	if aws.Int64Value(res.FailedPutCount) == 0 {
+		return nil, nil
+	}
+
+	var failed []*firehose.Record
+	for i, entry := range res.RequestResponses {
+		if entry.ErrorCode != nil && i < len(records) {
+			failed = append(failed, records[i])
+			err = fmt.Errorf("%v: %v", aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage))
+		}
+	}
+	return failed, err
+}
+
+// Write attempts to write each part of a message as a record to the target
+// delivery stream. Records that fail are retried on their own.
+func (a *AmazonKinesisFirehose) Write(msg types.Message) error {
+	a.mut.RLock()
+	client := a.firehose
+	a.mut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	records := make([]*firehose.Record, 0, msg.Len())
+	for _, part := range msg.GetAll() {
+		records = append(records, &firehose.Record{Data: part})
+	}
+
+	for len(records) > 0 {
+		batch := records
+		if len(batch) > a.conf.MaxBatchCount {
+			batch = batch[:a.conf.MaxBatchCount]
+		}
+		records = records[len(batch):]
+
+		failed, err := a.putRecords(client, batch)
+		for i := 0; len(failed) > 0 && i < a.conf.NumRetries; i++ {
+			a.stats.Incr("output.amazon_kinesis_firehose.send.retry", int64(len(failed)))
+			a.log.Warnf("Retrying %v failed records: %v\n", len(failed), err)
+			select {
+			case <-time.After(time.Duration(a.conf.RetryMS) * time.Millisecond):
+			case <-a.closeChan:
+				return types.ErrTypeClosed
+			}
+			failed, err = a.putRecords(client, failed)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to send %v records: %v", len(failed), err)
+		}
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *AmazonKinesisFirehose) CloseAsync() {
+	a.closedOnce.Do(func() {
+		close(a.closeChan)
+	})
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs. If the timeout occurs then an error is returned.
+func (a *AmazonKinesisFirehose) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,154 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// firehoseTestServer is a minimal Kinesis Firehose stand-in that accepts
+// PutRecordBatch requests, failing the first attempt of each record with data
+// in failOnce.
+type firehoseTestServer struct {
+	sync.Mutex
+	requests [][]string
+	failOnce map[string]bool
+}
+
+func (f *firehoseTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if target := r.Header.Get("X-Amz-Target"); target != "Firehose_20150804.PutRecordBatch" {
+		http.Error(w, "unexpected target: "+target, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		DeliveryStreamName string
+		Records            []struct {
+			Data []byte
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type responseEntry struct {
+		ErrorCode    string `json:",omitempty"`
+		ErrorMessage string `json:",omitempty"`
+		RecordId     string `json:",omitempty"`
+	}
+	var res struct {
+		FailedPutCount   int
+		RequestResponses []responseEntry
+	}
+
+	var records []string
+	for _, record := range req.Records {
+		data := string(record.Data)
+		records = append(records, data)
+		if f.failOnce[data] {
+			delete(f.failOnce, data)
+			res.FailedPutCount++
+			res.RequestResponses = append(res.RequestResponses, responseEntry{
+				ErrorCode:    "ServiceUnavailableException",
+				ErrorMessage: "slow down",
+			})
+			continue
+		}
+		res.RequestResponses = append(res.RequestResponses, responseEntry{
+			RecordId: "1",
+		})
+	}
+	f.requests = append(f.requests, records)
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(res)
+}
+
+//------------------------------------------------------------------------------
+
+func TestAmazonKinesisFirehoseRetryFailedRecords(t *testing.T) {
+	f := &firehoseTestServer{
+		failOnce: map[string]bool{"bar": true},
+	}
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	conf := NewAmazonKinesisFirehoseConfig()
+	conf.Endpoint = server.URL
+	conf.Credentials.ID = "id"
+	conf.Credentials.Secret = "secret"
+	conf.Stream = "foo"
+	conf.RetryMS = 1
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	w, err := NewAmazonKinesisFirehose(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Write(types.NewMessage([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]string{{"foo", "bar", "baz"}, {"bar"}}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if len(f.requests) != len(exp) {
+		t.Fatalf("Wrong count of requests: %v != %v", len(f.requests), len(exp))
+	}
+	for i, req := range f.requests {
+		if len(req) != len(exp[i]) {
+			t.Errorf("Wrong records in request %v: %v != %v", i, req, exp[i])
+			continue
+		}
+		for j := range req {
+			if req[j] != exp[i][j] {
+				t.Errorf("Wrong record in request %v: %v != %v", i, req[j], exp[i][j])
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,206 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+type kinesisTestRecord struct {
+	Data         []byte
+	PartitionKey string
+}
+
+// kinesisTestServer is a minimal Kinesis stand-in that accepts PutRecords
+// requests, failing the first attempt of each record with a partition key in
+// failOnce.
+type kinesisTestServer struct {
+	sync.Mutex
+	requests [][]kinesisTestRecord
+	failOnce map[string]bool
+}
+
+func (k *kinesisTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.Lock()
+	defer k.Unlock()
+
+	if target := r.Header.Get("X-Amz-Target"); target != "Kinesis_20131202.PutRecords" {
+		http.Error(w, "unexpected target: "+target, http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		StreamName string
+		Records    []kinesisTestRecord
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	k.requests = append(k.requests, req.Records)
+
+	type resultEntry struct {
+		ErrorCode      string `json:",omitempty"`
+		ErrorMessage   string `json:",omitempty"`
+		SequenceNumber string `json:",omitempty"`
+		ShardId        string `json:",omitempty"`
+	}
+	var res struct {
+		FailedRecordCount int
+		Records           []resultEntry
+	}
+	for _, record := range req.Records {
+		if k.failOnce[record.PartitionKey] {
+			delete(k.failOnce, record.PartitionKey)
+			res.FailedRecordCount++
+			res.Records = append(res.Records, resultEntry{
+				ErrorCode:    "ProvisionedThroughputExceededException",
+				ErrorMessage: "slow down",
+			})
+			continue
+		}
+		res.Records = append(res.Records, resultEntry{
+			SequenceNumber: "1",
+			ShardId:        "shardId-000000000000",
+		})
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(res)
+}
+
+//------------------------------------------------------------------------------
+
+func TestAmazonKinesisRetryFailedRecords(t *testing.T) {
+	k := &kinesisTestServer{
+		failOnce: map[string]bool{"b": true},
+	}
+	server := httptest.NewServer(k)
+	defer server.Close()
+
+	conf := NewAmazonKinesisConfig()
+	conf.Endpoint = server.URL
+	conf.Credentials.ID = "id"
+	conf.Credentials.Secret = "secret"
+	conf.Stream = "foo"
+	conf.PartitionKey = "${!json_field:key}"
+	conf.MaxBatchCount = 2
+	conf.RetryMS = 1
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	w, err := NewAmazonKinesis(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Write(types.NewMessage([][]byte{
+		[]byte(`{"key":"a"}`),
+		[]byte(`{"key":"b"}`),
+		[]byte(`{"key":"c"}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]string{{"a", "b"}, {"b"}, {"c"}}
+
+	k.Lock()
+	defer k.Unlock()
+
+	if len(k.requests) != len(exp) {
+		t.Fatalf("Wrong count of requests: %v != %v", len(k.requests), len(exp))
+	}
+	for i, req := range k.requests {
+		if len(req) != len(exp[i]) {
+			t.Errorf("Wrong count of records in request %v: %v != %v", i, len(req), len(exp[i]))
+			continue
+		}
+		for j, record := range req {
+			if act := record.PartitionKey; act != exp[i][j] {
+				t.Errorf("Wrong partition key in request %v: %v != %v", i, act, exp[i][j])
+			}
+			if exp, act := `{"key":"`+exp[i][j]+`"}`, string(record.Data); exp != act {
+				t.Errorf("Wrong record data in request %v: %v != %v", i, act, exp)
+			}
+		}
+	}
+}
+
+func TestAmazonKinesisRetriesExhausted(t *testing.T) {
+	k := &kinesisTestServer{
+		failOnce: map[string]bool{"a": true},
+	}
+	server := httptest.NewServer(k)
+	defer server.Close()
+
+	conf := NewAmazonKinesisConfig()
+	conf.Endpoint = server.URL
+	conf.Credentials.ID = "id"
+	conf.Credentials.Secret = "secret"
+	conf.Stream = "foo"
+	conf.PartitionKey = "a"
+	conf.NumRetries = 0
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	w, err := NewAmazonKinesis(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Write(types.NewMessage([][]byte{[]byte("foo")})); err == nil {
+		t.Error("Expected error from failed record")
+	}
+	if err = w.Write(types.NewMessage([][]byte{[]byte("foo")})); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAmazonKinesisBadConfig(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := NewAmazonKinesisConfig()
+	if _, err := NewAmazonKinesis(conf, logger, metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty stream")
+	}
+
+	conf.Stream = "foo"
+	conf.MaxBatchCount = 501
+	if _, err := NewAmazonKinesis(conf, logger, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad max_batch_count")
+	}
+}
+
+//------------------------------------------------------------------------------
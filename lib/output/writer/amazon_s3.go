@@ -21,54 +21,127 @@
 package writer
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
 	"github.com/Jeffail/benthos/lib/util/service/log"
 	"github.com/Jeffail/benthos/lib/util/service/metrics"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 //------------------------------------------------------------------------------
 
-// AmazonAWSCredentialsConfig contains configuration params for AWS credentials.
-type AmazonAWSCredentialsConfig struct {
-	ID     string `json:"id" yaml:"id"`
-	Secret string `json:"secret" yaml:"secret"`
-	Token  string `json:"token" yaml:"token"`
+// AmazonS3BatchingConfig contains configuration for accumulating messages into
+// a single object.
+type AmazonS3BatchingConfig struct {
+	MaxCount int    `json:"max_count" yaml:"max_count"`
+	MaxBytes int    `json:"max_bytes" yaml:"max_bytes"`
+	PeriodMS int    `json:"period_ms" yaml:"period_ms"`
+	Encoding string `json:"encoding" yaml:"encoding"`
 }
 
 // AmazonS3Config is configuration values for the input type.
 type AmazonS3Config struct {
-	Region      string                     `json:"region" yaml:"region"`
-	Bucket      string                     `json:"bucket" yaml:"bucket"`
-	Path        string                     `json:"path" yaml:"path"`
-	Credentials AmazonAWSCredentialsConfig `json:"credentials" yaml:"credentials"`
-	TimeoutS    int64                      `json:"timeout_s" yaml:"timeout_s"`
+	sess.Config    `json:",inline" yaml:",inline"`
+	Bucket         string                 `json:"bucket" yaml:"bucket"`
+	Path           string                 `json:"path" yaml:"path"`
+	ForcePathStyle bool                   `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	TimeoutS       int64                  `json:"timeout_s" yaml:"timeout_s"`
+	PartSizeBytes  int64                  `json:"part_size_bytes" yaml:"part_size_bytes"`
+	Batching       AmazonS3BatchingConfig `json:"batching" yaml:"batching"`
 }
 
 // NewAmazonS3Config creates a new Config with default values.
 func NewAmazonS3Config() AmazonS3Config {
 	return AmazonS3Config{
-		Region: "eu-west-1",
-		Bucket: "",
-		Path:   "${!count:files}-${!timestamp_unix_nano}.txt",
-		Credentials: AmazonAWSCredentialsConfig{
-			ID:     "",
-			Secret: "",
-			Token:  "",
+		Config:         sess.NewConfig(),
+		Bucket:         "",
+		Path:           "${!count:files}-${!timestamp_unix_nano}.txt",
+		ForcePathStyle: false,
+		TimeoutS:       5,
+		PartSizeBytes:  s3manager.DefaultUploadPartSize,
+		Batching: AmazonS3BatchingConfig{
+			MaxCount: 0,
+			MaxBytes: 0,
+			PeriodMS: 0,
+			Encoding: "lines",
 		},
-		TimeoutS: 5,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+type s3BatchEncoder func(parts [][]byte) ([]byte, error)
+
+func s3LinesEncoder(parts [][]byte) ([]byte, error) {
+	return append(bytes.Join(parts, []byte("\n")), '\n'), nil
+}
+
+func s3GzipEncoder(parts [][]byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	for _, part := range parts {
+		if _, err := zw.Write(part); err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write([]byte("\n")); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func s3TarEncoder(parts [][]byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	now := time.Now()
+	for i, part := range parts {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    strconv.Itoa(i),
+			Mode:    int64(os.FileMode(0666)),
+			Size:    int64(len(part)),
+			ModTime: now,
+		}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(part); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func strToS3BatchEncoder(str string) (s3BatchEncoder, error) {
+	switch str {
+	case "lines":
+		return s3LinesEncoder, nil
+	case "gzip":
+		return s3GzipEncoder, nil
+	case "tar":
+		return s3TarEncoder, nil
+	}
+	return nil, fmt.Errorf("s3 batch encoding not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
 // AmazonS3 is a benthos writer.Type implementation that writes messages to an
 // Amazon S3 bucket.
 type AmazonS3 struct {
@@ -80,6 +153,17 @@ type AmazonS3 struct {
 	session  *session.Session
 	uploader *s3manager.Uploader
 
+	batching   bool
+	encoder    s3BatchEncoder
+	batchMut   sync.Mutex
+	batch      [][]byte
+	batchBytes int
+	batchStart time.Time
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
+
 	log   log.Modular
 	stats metrics.Type
 }
@@ -89,80 +173,210 @@ func NewAmazonS3(
 	conf AmazonS3Config,
 	log log.Modular,
 	stats metrics.Type,
-) *AmazonS3 {
+) (*AmazonS3, error) {
 	pathBytes := []byte(conf.Path)
 	interpolatePath := text.ContainsFunctionVariables(pathBytes)
-	return &AmazonS3{
+	a := &AmazonS3{
 		conf:            conf,
 		pathBytes:       pathBytes,
 		interpolatePath: interpolatePath,
-		log:             log.NewModule(".output.amazon_s3"),
-		stats:           stats,
+		batching: conf.Batching.MaxCount > 0 ||
+			conf.Batching.MaxBytes > 0 ||
+			conf.Batching.PeriodMS > 0,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log.NewModule(".output.amazon_s3"),
+		stats:      stats,
 	}
+	if a.batching {
+		var err error
+		if a.encoder, err = strToS3BatchEncoder(conf.Batching.Encoding); err != nil {
+			return nil, err
+		}
+		go a.loop()
+	} else {
+		close(a.closedChan)
+	}
+	return a, nil
 }
 
 // Connect attempts to establish a connection to the target S3 bucket and any
 // relevant queues used to traverse the objects (SQS, etc).
 func (a *AmazonS3) Connect() error {
+	a.batchMut.Lock()
+	defer a.batchMut.Unlock()
+
 	if a.session != nil {
 		return nil
 	}
 
-	awsConf := aws.NewConfig()
-	if len(a.conf.Region) > 0 {
-		awsConf = awsConf.WithRegion(a.conf.Region)
+	sess, err := a.conf.GetSession(func(c *aws.Config) {
+		c.S3ForcePathStyle = aws.Bool(a.conf.ForcePathStyle)
+	})
+	if err != nil {
+		return err
+	}
+
+	a.session = sess
+	a.uploader = s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		if a.conf.PartSizeBytes > 0 {
+			u.PartSize = a.conf.PartSizeBytes
+		}
+	})
+
+	if a.batching {
+		a.log.Infof("Uploading batches of messages as objects to Amazon S3 bucket: %v\n", a.conf.Bucket)
+	} else {
+		a.log.Infof("Uploading message parts as objects to Amazon S3 bucket: %v\n", a.conf.Bucket)
 	}
-	if len(a.conf.Credentials.ID) > 0 {
-		awsConf = awsConf.WithCredentials(credentials.NewStaticCredentials(
-			a.conf.Credentials.ID,
-			a.conf.Credentials.Secret,
-			a.conf.Credentials.Token,
-		))
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// upload writes a single object to the bucket, which is uploaded in multiple
+// parts if it is larger than the part size.
+func (a *AmazonS3) upload(body []byte) error {
+	path := a.conf.Path
+	if a.interpolatePath {
+		path = string(text.ReplaceFunctionVariables(a.pathBytes))
 	}
 
-	sess, err := session.NewSession(awsConf)
+	_, err := a.uploader.Upload(&s3manager.UploadInput{
+		Body:   bytes.NewReader(body),
+		Bucket: aws.String(a.conf.Bucket),
+		Key:    aws.String(path),
+	})
+	return err
+}
+
+// flushBatch encodes and uploads the pending batch, and must be called while
+// holding the batch mutex.
+func (a *AmazonS3) flushBatch() error {
+	if len(a.batch) == 0 {
+		return nil
+	}
+	body, err := a.encoder(a.batch)
+	if err == nil {
+		err = a.upload(body)
+	}
 	if err != nil {
+		a.stats.Incr("output.amazon_s3.batch.error", 1)
 		return err
 	}
 
-	a.session = sess
-	a.uploader = s3manager.NewUploader(sess)
+	a.stats.Incr("output.amazon_s3.batch.sent", 1)
+	a.stats.Incr("output.amazon_s3.batch.parts", int64(len(a.batch)))
+	a.batch = nil
+	a.batchBytes = 0
+	return nil
+}
+
+// batchReady returns true if the pending batch has reached any of its limits.
+func (a *AmazonS3) batchReady() bool {
+	b := a.conf.Batching
+	return (b.MaxCount > 0 && len(a.batch) >= b.MaxCount) ||
+		(b.MaxBytes > 0 && a.batchBytes >= b.MaxBytes) ||
+		(b.PeriodMS > 0 && time.Since(a.batchStart) >= time.Duration(b.PeriodMS)*time.Millisecond)
+}
+
+// loop uploads any remaining batch when the writer is closed.
+func (a *AmazonS3) loop() {
+	defer close(a.closedChan)
+
+	<-a.closeChan
+	a.batchMut.Lock()
+	if a.uploader != nil {
+		if err := a.flushBatch(); err != nil {
+			a.log.Errorf("Failed to upload final batch: %v\n", err)
+		}
+	}
+	a.batchMut.Unlock()
+}
 
-	a.log.Infof("Uploading message parts as objects to Amazon S3 bucket: %v\n", a.conf.Bucket)
+// awaitPeriod blocks until the batching period of the pending batch has
+// passed, and must be called while holding the batch mutex, which is released
+// during the wait.
+func (a *AmazonS3) awaitPeriod() error {
+	period := time.Duration(a.conf.Batching.PeriodMS) * time.Millisecond
+	wait := period - time.Since(a.batchStart)
+	if wait <= 0 {
+		return nil
+	}
+
+	a.batchMut.Unlock()
+	defer a.batchMut.Lock()
+
+	select {
+	case <-time.After(wait):
+	case <-a.closeChan:
+		return types.ErrTypeClosed
+	}
 	return nil
 }
 
-// Write attempts to write message contents to a target S3 bucket as files.
+// Write attempts to write message contents to a target S3 bucket as files. When
+// batching is enabled the message parts are added to a pending batch instead,
+// which is uploaded as a single object once it reaches a limit. When a batching
+// period is set the call blocks until the batch has been uploaded, so that the
+// message is acknowledged along with the rest of its batch.
 func (a *AmazonS3) Write(msg types.Message) error {
 	if a.session == nil {
 		return types.ErrNotConnected
 	}
 
-	for _, part := range msg.GetAll() {
-		path := a.conf.Path
-		if a.interpolatePath {
-			path = string(text.ReplaceFunctionVariables(a.pathBytes))
+	if !a.batching {
+		for _, part := range msg.GetAll() {
+			if err := a.upload(part); err != nil {
+				return err
+			}
 		}
+		return nil
+	}
 
-		if _, err := a.uploader.Upload(&s3manager.UploadInput{
-			Body:   bytes.NewReader(part),
-			Bucket: aws.String(a.conf.Bucket),
-			Key:    aws.String(path),
-		}); err != nil {
+	a.batchMut.Lock()
+	defer a.batchMut.Unlock()
+
+	if len(a.batch) == 0 {
+		a.batchStart = time.Now()
+	}
+	for _, part := range msg.GetAll() {
+		a.batch = append(a.batch, part)
+		a.batchBytes += len(part)
+	}
+	if !a.batchReady() {
+		if a.conf.Batching.PeriodMS <= 0 {
+			return ErrBatchPending
+		}
+		if err := a.awaitPeriod(); err != nil {
 			return err
 		}
 	}
 
+	if err := a.flushBatch(); err != nil {
+		// The failed messages are resent and therefore the batch is dropped.
+		a.batch = nil
+		a.batchBytes = 0
+		return err
+	}
 	return nil
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonS3) CloseAsync() {
+	a.closeOnce.Do(func() {
+		close(a.closeChan)
+	})
 }
 
 // WaitForClose will block until either the reader is closed or a specified
 // timeout occurs.
-func (a *AmazonS3) WaitForClose(time.Duration) error {
+func (a *AmazonS3) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-a.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
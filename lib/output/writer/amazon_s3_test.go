@@ -0,0 +1,364 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// s3TestServer is a minimal S3 compatible stand-in that supports single and
+// multipart object uploads with path style URLs.
+type s3TestServer struct {
+	sync.Mutex
+	objects    map[string][]byte
+	parts      map[string]map[int][]byte
+	multiparts int
+}
+
+func newS3TestServer() *s3TestServer {
+	return &s3TestServer{
+		objects: map[string][]byte{},
+		parts:   map[string]map[int][]byte{},
+	}
+}
+
+func (s *s3TestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	_, initiate := query["uploads"]
+
+	switch {
+	case r.Method == "POST" && initiate:
+		s.multiparts++
+		id := strconv.Itoa(s.multiparts)
+		s.parts[id] = map[int][]byte{}
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%v</UploadId></InitiateMultipartUploadResult>`, id)
+	case r.Method == "PUT" && uploadID != "":
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		s.parts[uploadID][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%v"`, n))
+	case r.Method == "POST" && uploadID != "":
+		var nums []int
+		for n := range s.parts[uploadID] {
+			nums = append(nums, n)
+		}
+		sort.Ints(nums)
+		var object []byte
+		for _, n := range nums {
+			object = append(object, s.parts[uploadID][n]...)
+		}
+		s.objects[r.URL.Path] = object
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Key>%v</Key></CompleteMultipartUploadResult>`, r.URL.Path)
+	case r.Method == "PUT":
+		s.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"foo"`)
+	default:
+		http.Error(w, "not supported", http.StatusBadRequest)
+	}
+}
+
+func (s *s3TestServer) getObjects() map[string][]byte {
+	s.Lock()
+	defer s.Unlock()
+
+	objects := map[string][]byte{}
+	for k, v := range s.objects {
+		objects[k] = v
+	}
+	return objects
+}
+
+func newS3TestWriter(t *testing.T, url string, batching AmazonS3BatchingConfig) *AmazonS3 {
+	conf := NewAmazonS3Config()
+	conf.Bucket = "foo"
+	conf.Path = "${!count:s3_test}.obj"
+	conf.Endpoint = url
+	conf.ForcePathStyle = true
+	conf.Credentials.ID = "id"
+	conf.Credentials.Secret = "secret"
+	conf.Batching = batching
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	a, err := NewAmazonS3(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAmazonS3BadEncoding(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Batching.MaxCount = 10
+	conf.Batching.Encoding = "nope"
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	if _, err := NewAmazonS3(conf, logger, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad encoding")
+	}
+}
+
+func TestAmazonS3BatchCount(t *testing.T) {
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	a := newS3TestWriter(t, server.URL, AmazonS3BatchingConfig{
+		MaxCount: 3,
+		Encoding: "lines",
+	})
+	defer a.CloseAsync()
+
+	expErrs := []error{ErrBatchPending, ErrBatchPending, nil}
+	for i, exp := range expErrs {
+		if err := a.Write(types.NewMessage([][]byte{[]byte(strconv.Itoa(i))})); err != exp {
+			t.Fatalf("Wrong error from write %v: %v != %v", i, err, exp)
+		}
+	}
+
+	objects := s.getObjects()
+	if exp, act := 1, len(objects); exp != act {
+		t.Fatalf("Wrong count of objects: %v != %v", act, exp)
+	}
+	for _, obj := range objects {
+		if exp, act := "0\n1\n2\n", string(obj); exp != act {
+			t.Errorf("Wrong object content: %q != %q", act, exp)
+		}
+	}
+}
+
+func TestAmazonS3BatchTarAndGzip(t *testing.T) {
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	parts := [][]byte{[]byte("foo"), []byte("bar")}
+
+	a := newS3TestWriter(t, server.URL, AmazonS3BatchingConfig{
+		MaxCount: 2,
+		Encoding: "tar",
+	})
+	defer a.CloseAsync()
+	if err := a.Write(types.NewMessage(parts)); err != nil {
+		t.Fatal(err)
+	}
+
+	g := newS3TestWriter(t, server.URL, AmazonS3BatchingConfig{
+		MaxBytes: 6,
+		Encoding: "gzip",
+	})
+	defer g.CloseAsync()
+	if err := g.Write(types.NewMessage(parts[:1])); err != ErrBatchPending {
+		t.Fatalf("Wrong error: %v != %v", err, ErrBatchPending)
+	}
+	if err := g.Write(types.NewMessage(parts[1:])); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := s.getObjects()
+	if exp, act := 2, len(objects); exp != act {
+		t.Fatalf("Wrong count of objects: %v != %v", act, exp)
+	}
+
+	var tarParts, gzipContent []string
+	for _, obj := range objects {
+		if zr, err := gzip.NewReader(bytes.NewReader(obj)); err == nil {
+			content, err := ioutil.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gzipContent = append(gzipContent, string(content))
+			continue
+		}
+		tr := tar.NewReader(bytes.NewReader(obj))
+		for {
+			_, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tarParts = append(tarParts, string(content))
+		}
+	}
+
+	if exp, act := []string{"foo", "bar"}, tarParts; fmt.Sprint(exp) != fmt.Sprint(act) {
+		t.Errorf("Wrong tar parts: %q != %q", act, exp)
+	}
+	if exp, act := []string{"foo\nbar\n"}, gzipContent; fmt.Sprint(exp) != fmt.Sprint(act) {
+		t.Errorf("Wrong gzip content: %q != %q", act, exp)
+	}
+}
+
+func TestAmazonS3BatchMultipart(t *testing.T) {
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	a := newS3TestWriter(t, server.URL, AmazonS3BatchingConfig{
+		MaxBytes: 7 * 1024 * 1024,
+		Encoding: "lines",
+	})
+	defer a.CloseAsync()
+
+	part := bytes.Repeat([]byte("a"), 1024*1024)
+	var exp []byte
+	for i := 0; i < 7; i++ {
+		err := a.Write(types.NewMessage([][]byte{part}))
+		if i < 6 && err != ErrBatchPending {
+			t.Fatalf("Wrong error: %v != %v", err, ErrBatchPending)
+		} else if i == 6 && err != nil {
+			t.Fatal(err)
+		}
+		exp = append(append(exp, part...), '\n')
+	}
+
+	s.Lock()
+	multiparts := s.multiparts
+	s.Unlock()
+	if multiparts != 1 {
+		t.Errorf("Expected a multipart upload, received %v", multiparts)
+	}
+	objects := s.getObjects()
+	if exp, act := 1, len(objects); exp != act {
+		t.Fatalf("Wrong count of objects: %v != %v", act, exp)
+	}
+	for _, obj := range objects {
+		if !bytes.Equal(exp, obj) {
+			t.Errorf("Wrong object content of length %v, expected %v", len(obj), len(exp))
+		}
+	}
+}
+
+func TestAmazonS3BatchPeriod(t *testing.T) {
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	a := newS3TestWriter(t, server.URL, AmazonS3BatchingConfig{
+		PeriodMS: 10,
+		Encoding: "lines",
+	})
+
+	// Writes block until the batch is uploaded by the period.
+	for _, p := range []string{"foo", "bar"} {
+		if err := a.Write(types.NewMessage([][]byte{[]byte(p)})); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, obj := range s.getObjects() {
+			if string(obj) == p+"\n" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Batch %q not uploaded before write returned", p)
+		}
+	}
+	a.CloseAsync()
+	if err := a.WaitForClose(time.Second * 5); err != nil {
+		t.Fatal(err)
+	}
+
+	var contents []string
+	for _, obj := range s.getObjects() {
+		contents = append(contents, string(obj))
+	}
+	sort.Strings(contents)
+	if exp, act := []string{"bar\n", "foo\n"}, contents; fmt.Sprint(exp) != fmt.Sprint(act) {
+		t.Errorf("Wrong objects: %q != %q", act, exp)
+	}
+}
+
+func TestAmazonS3BatchPeriodClose(t *testing.T) {
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	a := newS3TestWriter(t, server.URL, AmazonS3BatchingConfig{
+		PeriodMS: 3600000,
+		Encoding: "lines",
+	})
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		a.CloseAsync()
+	}()
+	if err := a.Write(types.NewMessage([][]byte{[]byte("foo")})); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+	if err := a.WaitForClose(time.Second * 5); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAmazonS3NoBatching(t *testing.T) {
+	s := newS3TestServer()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	a := newS3TestWriter(t, server.URL, NewAmazonS3Config().Batching)
+	defer a.CloseAsync()
+
+	if err := a.Write(types.NewMessage([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, len(s.getObjects()); exp != act {
+		t.Errorf("Wrong count of objects: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,366 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// ElasticsearchConfig is configuration for the Elasticsearch output type.
+type ElasticsearchConfig struct {
+	URLs           []string `json:"urls" yaml:"urls"`
+	Index          string   `json:"index" yaml:"index"`
+	Type           string   `json:"type" yaml:"type"`
+	ID             string   `json:"id" yaml:"id"`
+	MaxBatchCount  int      `json:"max_batch_count" yaml:"max_batch_count"`
+	MaxBatchBytes  int      `json:"max_batch_bytes" yaml:"max_batch_bytes"`
+	TimeoutMS      int64    `json:"timeout_ms" yaml:"timeout_ms"`
+	RetryMS        int64    `json:"retry_period_ms" yaml:"retry_period_ms"`
+	NumRetries     int      `json:"retries" yaml:"retries"`
+	SkipCertVerify bool     `json:"skip_cert_verify" yaml:"skip_cert_verify"`
+	auth.Config    `json:",inline" yaml:",inline"`
+}
+
+// NewElasticsearchConfig creates a new ElasticsearchConfig with default
+// values.
+func NewElasticsearchConfig() ElasticsearchConfig {
+	return ElasticsearchConfig{
+		URLs:           []string{"http://localhost:9200"},
+		Index:          "benthos_index",
+		Type:           "doc",
+		ID:             "",
+		MaxBatchCount:  100,
+		MaxBatchBytes:  5000000,
+		TimeoutMS:      5000,
+		RetryMS:        1000,
+		NumRetries:     3,
+		SkipCertVerify: false,
+		Config:         auth.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// esBulkItem is a single action and document of a bulk request.
+type esBulkItem struct {
+	action []byte
+	doc    []byte
+}
+
+// esBulkResponse is the subset of a bulk response used to detect item errors.
+type esBulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]esBulkItemResult `json:"items"`
+}
+
+// esBulkItemResult is the result of a single item of a bulk request.
+type esBulkItemResult struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// retryable returns true if the failure of an item might be resolved by
+// retrying it.
+func (r esBulkItemResult) retryable() bool {
+	return r.Status == http.StatusTooManyRequests || r.Status >= 500
+}
+
+//------------------------------------------------------------------------------
+
+// Elasticsearch is a writer type that writes messages into Elasticsearch with
+// the bulk API.
+type Elasticsearch struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf ElasticsearchConfig
+	urls []string
+
+	indexBytes []byte
+	typeBytes  []byte
+	idBytes    []byte
+
+	client *http.Client
+	url    string
+}
+
+// NewElasticsearch creates a new Elasticsearch writer type.
+func NewElasticsearch(conf ElasticsearchConfig, log log.Modular, stats metrics.Type) (*Elasticsearch, error) {
+	e := Elasticsearch{
+		log:        log.NewModule(".output.elasticsearch"),
+		stats:      stats,
+		conf:       conf,
+		indexBytes: []byte(conf.Index),
+		typeBytes:  []byte(conf.Type),
+		idBytes:    []byte(conf.ID),
+	}
+	for _, u := range conf.URLs {
+		for _, splitURL := range strings.Split(u, ",") {
+			if len(splitURL) > 0 {
+				e.urls = append(e.urls, strings.TrimSuffix(splitURL, "/"))
+			}
+		}
+	}
+	if len(e.urls) == 0 {
+		return nil, errors.New("at least one elasticsearch url must be specified")
+	}
+	if len(conf.Index) == 0 {
+		return nil, errors.New("an elasticsearch index must be specified")
+	}
+
+	e.client = &http.Client{
+		Timeout: time.Duration(conf.TimeoutMS) * time.Millisecond,
+	}
+	if conf.SkipCertVerify {
+		e.client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return &e, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect selects the first Elasticsearch node that responds to a request.
+func (e *Elasticsearch) Connect() error {
+	if len(e.url) > 0 {
+		return nil
+	}
+
+	var err error
+	for _, u := range e.urls {
+		var req *http.Request
+		if req, err = http.NewRequest("GET", u, nil); err != nil {
+			return err
+		}
+		if err = e.conf.Sign(req); err != nil {
+			return err
+		}
+
+		var res *http.Response
+		if res, err = e.client.Do(req); err != nil {
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			err = fmt.Errorf("unexpected status from %v: %v", u, res.Status)
+			continue
+		}
+
+		e.url = u
+		e.log.Infof("Sending messages to Elasticsearch at URL: %v\n", u)
+		return nil
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// esInterpolate resolves the function interpolations of a field for a part.
+func esInterpolate(msg types.Message, index int, field []byte) string {
+	if text.ContainsFunctionVariables(field) {
+		return string(text.ReplaceMessageFunctionVariables(msg, index, field))
+	}
+	return string(field)
+}
+
+// buildItems creates a bulk item for each part of a message.
+func (e *Elasticsearch) buildItems(msg types.Message) ([]esBulkItem, error) {
+	items := make([]esBulkItem, msg.Len())
+	for i, part := range msg.GetAll() {
+		meta := map[string]string{
+			"_index": esInterpolate(msg, i, e.indexBytes),
+		}
+		if t := esInterpolate(msg, i, e.typeBytes); len(t) > 0 {
+			meta["_type"] = t
+		}
+		if id := esInterpolate(msg, i, e.idBytes); len(id) > 0 {
+			meta["_id"] = id
+		}
+
+		action, err := json.Marshal(map[string]interface{}{"index": meta})
+		if err != nil {
+			return nil, err
+		}
+
+		// Documents must not span multiple lines of a bulk request.
+		doc := part
+		compacted := bytes.Buffer{}
+		if err = json.Compact(&compacted, part); err == nil {
+			doc = compacted.Bytes()
+		}
+		items[i] = esBulkItem{action: action, doc: doc}
+	}
+	return items, nil
+}
+
+// batchItems splits items into batches that respect the configured count and
+// byte size limits, where an item larger than the byte limit is sent alone.
+func (e *Elasticsearch) batchItems(items []esBulkItem) [][]esBulkItem {
+	var batches [][]esBulkItem
+	var batch []esBulkItem
+	batchBytes := 0
+
+	for _, item := range items {
+		itemBytes := len(item.action) + len(item.doc) + 2
+		if len(batch) > 0 &&
+			((e.conf.MaxBatchCount > 0 && len(batch) >= e.conf.MaxBatchCount) ||
+				(e.conf.MaxBatchBytes > 0 && batchBytes+itemBytes > e.conf.MaxBatchBytes)) {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+		batch = append(batch, item)
+		batchBytes += itemBytes
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// sendBatch sends a batch of items with a bulk request and returns the items
+// that failed with a retryable error. An error is returned if the request
+// failed or if any items failed with an error that cannot be retried.
+func (e *Elasticsearch) sendBatch(batch []esBulkItem) ([]esBulkItem, error) {
+	body := bytes.Buffer{}
+	for _, item := range batch {
+		body.Write(item.action)
+		body.WriteByte('\n')
+		body.Write(item.doc)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest("POST", e.url+"/_bulk", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if err = e.conf.Sign(req); err != nil {
+		return nil, err
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		e.log.Errorf("Bulk request failed: %v\n", err)
+		e.url = ""
+		return nil, types.ErrNotConnected
+	}
+	defer res.Body.Close()
+
+	resBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+			return batch, nil
+		}
+		return nil, fmt.Errorf("bulk request returned status %v: %s", res.Status, resBytes)
+	}
+
+	var bulkRes esBulkResponse
+	if err = json.Unmarshal(resBytes, &bulkRes); err != nil {
+		return nil, fmt.Errorf("failed to parse bulk response: %v", err)
+	}
+	if !bulkRes.Errors {
+		return nil, nil
+	}
+	if len(bulkRes.Items) != len(batch) {
+		return nil, fmt.Errorf(
+			"bulk response contained %v items, expected %v",
+			len(bulkRes.Items), len(batch),
+		)
+	}
+
+	var failed []esBulkItem
+	for i, resItem := range bulkRes.Items {
+		for _, result := range resItem {
+			if result.Status >= 200 && result.Status <= 299 {
+				continue
+			}
+			e.stats.Incr("output.elasticsearch.send.item.error", 1)
+			if !result.retryable() {
+				err = fmt.Errorf("failed to index document: %s", result.Error)
+				continue
+			}
+			failed = append(failed, batch[i])
+		}
+	}
+	return failed, err
+}
+
+// Write sends each part of a message as a document to Elasticsearch using bulk
+// requests, where only the items that fail within a bulk response are retried.
+func (e *Elasticsearch) Write(msg types.Message) error {
+	if len(e.url) == 0 {
+		return types.ErrNotConnected
+	}
+
+	items, err := e.buildItems(msg)
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range e.batchItems(items) {
+		for retries := 0; len(batch) > 0; retries++ {
+			if retries > 0 {
+				if retries > e.conf.NumRetries {
+					return fmt.Errorf("failed to index %v documents after %v retries", len(batch), e.conf.NumRetries)
+				}
+				e.stats.Incr("output.elasticsearch.send.retry", 1)
+				<-time.After(time.Duration(e.conf.RetryMS) * time.Millisecond)
+			}
+			if batch, err = e.sendBatch(batch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CloseAsync shuts down the Elasticsearch output and stops processing
+// messages.
+func (e *Elasticsearch) CloseAsync() {
+}
+
+// WaitForClose blocks until the Elasticsearch output has closed down.
+func (e *Elasticsearch) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
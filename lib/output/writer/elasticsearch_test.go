@@ -0,0 +1,249 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+type esTestServer struct {
+	sync.Mutex
+	requests [][]string
+	indexed  map[string]string
+	failures map[string]int
+}
+
+func (s *esTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "foo" || pass != "bar" {
+		http.Error(w, "unauthorised", http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path == "/" {
+		w.Write([]byte(`{}`))
+		return
+	}
+	if r.URL.Path != "/_bulk" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	var ids []string
+	var items []interface{}
+	hasErrors := false
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !scanner.Scan() {
+			http.Error(w, "missing document", http.StatusBadRequest)
+			return
+		}
+		meta := action["index"]
+		id := meta["_index"] + "/" + meta["_type"] + "/" + meta["_id"]
+		ids = append(ids, id)
+
+		status := 201
+		if s.failures[id] > 0 {
+			s.failures[id]--
+			status = 429
+			hasErrors = true
+		} else if meta["_id"] == "bad" {
+			status = 400
+			hasErrors = true
+		} else {
+			s.indexed[id] = scanner.Text()
+		}
+		items = append(items, map[string]interface{}{
+			"index": map[string]interface{}{
+				"status": status,
+				"error":  map[string]interface{}{"type": "test_error"},
+			},
+		})
+	}
+	s.requests = append(s.requests, ids)
+
+	resBytes, _ := json.Marshal(map[string]interface{}{
+		"errors": hasErrors,
+		"items":  items,
+	})
+	w.Write(resBytes)
+}
+
+func newESTestWriter(t *testing.T, url string) *Elasticsearch {
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{url}
+	conf.Index = "${!json_field:index}"
+	conf.ID = "${!json_field:id}"
+	conf.MaxBatchCount = 2
+	conf.RetryMS = 1
+	conf.BasicAuth.Enabled = true
+	conf.BasicAuth.Username = "foo"
+	conf.BasicAuth.Password = "bar"
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	e, err := NewElasticsearch(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestElasticsearchRetryFailedItems(t *testing.T) {
+	s := &esTestServer{
+		indexed:  map[string]string{},
+		failures: map[string]int{"foo/doc/2": 2},
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := newESTestWriter(t, server.URL)
+
+	if err := e.Write(types.NewMessage([][]byte{
+		[]byte(`{"index":"foo","id":"1"}`),
+		[]byte("{\n\t\"index\": \"foo\",\n\t\"id\": \"2\"\n}"),
+		[]byte(`{"index":"bar","id":"3"}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	expReqs := [][]string{
+		{"foo/doc/1", "foo/doc/2"},
+		{"foo/doc/2"},
+		{"foo/doc/2"},
+		{"bar/doc/3"},
+	}
+	if !reflect.DeepEqual(expReqs, s.requests) {
+		t.Errorf("Wrong requests: %v != %v", s.requests, expReqs)
+	}
+
+	expIndexed := map[string]string{
+		"foo/doc/1": `{"index":"foo","id":"1"}`,
+		"foo/doc/2": `{"index":"foo","id":"2"}`,
+		"bar/doc/3": `{"index":"bar","id":"3"}`,
+	}
+	if !reflect.DeepEqual(expIndexed, s.indexed) {
+		t.Errorf("Wrong indexed documents: %v != %v", s.indexed, expIndexed)
+	}
+}
+
+func TestElasticsearchRetriesExhausted(t *testing.T) {
+	s := &esTestServer{
+		indexed:  map[string]string{},
+		failures: map[string]int{"foo/doc/1": 10},
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := newESTestWriter(t, server.URL)
+
+	if err := e.Write(types.NewMessage([][]byte{
+		[]byte(`{"index":"foo","id":"1"}`),
+	})); err == nil {
+		t.Error("Expected error from exhausted retries")
+	}
+	if exp, act := e.conf.NumRetries+1, len(s.requests); exp != act {
+		t.Errorf("Wrong count of requests: %v != %v", act, exp)
+	}
+}
+
+func TestElasticsearchNonRetryableItem(t *testing.T) {
+	s := &esTestServer{
+		indexed:  map[string]string{},
+		failures: map[string]int{},
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := newESTestWriter(t, server.URL)
+
+	if err := e.Write(types.NewMessage([][]byte{
+		[]byte(`{"index":"foo","id":"bad"}`),
+	})); err == nil {
+		t.Error("Expected error from bad document")
+	}
+	if exp, act := 1, len(s.requests); exp != act {
+		t.Errorf("Wrong count of requests: %v != %v", act, exp)
+	}
+}
+
+func TestElasticsearchBatchBytes(t *testing.T) {
+	conf := NewElasticsearchConfig()
+	conf.MaxBatchCount = 0
+	conf.MaxBatchBytes = 50
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	e, err := NewElasticsearch(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := []esBulkItem{
+		{action: make([]byte, 10), doc: make([]byte, 10)},
+		{action: make([]byte, 10), doc: make([]byte, 10)},
+		{action: make([]byte, 10), doc: make([]byte, 60)},
+		{action: make([]byte, 10), doc: make([]byte, 10)},
+	}
+	batches := e.batchItems(items)
+	if exp, act := 3, len(batches); exp != act {
+		t.Fatalf("Wrong count of batches: %v != %v", act, exp)
+	}
+	for i, exp := range []int{2, 1, 1} {
+		if act := len(batches[i]); act != exp {
+			t.Errorf("Wrong size of batch %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestElasticsearchNotConnected(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	e, err := NewElasticsearch(NewElasticsearchConfig(), logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Write(types.NewMessage([][]byte{[]byte("foo")})); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,229 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// GCPPubSubConfig contains configuration fields for the output GCPPubSub type.
+type GCPPubSubConfig struct {
+	ProjectID   string            `json:"project" yaml:"project"`
+	TopicID     string            `json:"topic" yaml:"topic"`
+	OrderingKey string            `json:"ordering_key" yaml:"ordering_key"`
+	Attributes  map[string]string `json:"attributes" yaml:"attributes"`
+	TimeoutMS   int64             `json:"timeout_ms" yaml:"timeout_ms"`
+}
+
+// NewGCPPubSubConfig creates a new Config with default values.
+func NewGCPPubSubConfig() GCPPubSubConfig {
+	return GCPPubSubConfig{
+		ProjectID:   "",
+		TopicID:     "",
+		OrderingKey: "",
+		Attributes:  map[string]string{},
+		TimeoutMS:   5000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// gcpInterpString is a config string that may contain function interpolations.
+type gcpInterpString struct {
+	raw         []byte
+	interpolate bool
+}
+
+func newGCPInterpString(s string) gcpInterpString {
+	raw := []byte(s)
+	return gcpInterpString{
+		raw:         raw,
+		interpolate: text.ContainsFunctionVariables(raw),
+	}
+}
+
+func (g gcpInterpString) get(msg types.Message, index int) string {
+	if g.interpolate {
+		return string(text.ReplaceMessageFunctionVariables(msg, index, g.raw))
+	}
+	return string(g.raw)
+}
+
+//------------------------------------------------------------------------------
+
+// GCPPubSub is a benthos writer.Type implementation that writes messages to a
+// GCP Cloud Pub/Sub topic.
+type GCPPubSub struct {
+	conf GCPPubSubConfig
+
+	orderingKey gcpInterpString
+	attributes  map[string]gcpInterpString
+	timeout     time.Duration
+
+	client *pubsub.Client
+	topic  *pubsub.Topic
+	mut    sync.RWMutex
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewGCPPubSub creates a new GCP Cloud Pub/Sub writer.Type.
+func NewGCPPubSub(
+	conf GCPPubSubConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*GCPPubSub, error) {
+	if len(conf.TopicID) == 0 {
+		return nil, errors.New("invalid topic (cannot be empty)")
+	}
+	attributes := map[string]gcpInterpString{}
+	for k, v := range conf.Attributes {
+		attributes[k] = newGCPInterpString(v)
+	}
+	return &GCPPubSub{
+		conf:        conf,
+		orderingKey: newGCPInterpString(conf.OrderingKey),
+		attributes:  attributes,
+		timeout:     time.Duration(conf.TimeoutMS) * time.Millisecond,
+		log:         log.NewModule(".output.gcp_pubsub"),
+		stats:       stats,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target topic.
+func (c *GCPPubSub) Connect() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.topic != nil {
+		return nil
+	}
+
+	client, err := pubsub.NewClient(context.Background(), c.conf.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), c.timeout)
+	defer done()
+
+	topic := client.Topic(c.conf.TopicID)
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		client.Close()
+		return err
+	}
+	if !exists {
+		client.Close()
+		return fmt.Errorf("topic '%v' does not exist", c.conf.TopicID)
+	}
+	topic.EnableMessageOrdering = len(c.conf.OrderingKey) > 0
+
+	c.client = client
+	c.topic = topic
+
+	c.log.Infof("Sending GCP Cloud Pub/Sub messages to topic: %v\n", c.conf.TopicID)
+	return nil
+}
+
+// Write attempts to write message contents to a target topic, blocking until
+// each part has been acknowledged by the server.
+func (c *GCPPubSub) Write(msg types.Message) error {
+	c.mut.RLock()
+	topic := c.topic
+	c.mut.RUnlock()
+
+	if topic == nil {
+		return types.ErrNotConnected
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), c.timeout)
+	defer done()
+
+	results := make([]*pubsub.PublishResult, msg.Len())
+	keys := make([]string, msg.Len())
+	for i, part := range msg.GetAll() {
+		gMsg := &pubsub.Message{
+			Data: part,
+		}
+		if len(c.attributes) > 0 {
+			gMsg.Attributes = make(map[string]string, len(c.attributes))
+			for k, v := range c.attributes {
+				gMsg.Attributes[k] = v.get(msg, i)
+			}
+		}
+		if topic.EnableMessageOrdering {
+			keys[i] = c.orderingKey.get(msg, i)
+			gMsg.OrderingKey = keys[i]
+		}
+		results[i] = topic.Publish(ctx, gMsg)
+	}
+
+	var err error
+	for i, r := range results {
+		if _, rerr := r.Get(ctx); rerr != nil {
+			// Publishing is paused for an ordering key after a failure until
+			// it is explicitly resumed.
+			if len(keys[i]) > 0 {
+				topic.ResumePublish(keys[i])
+			}
+			err = rerr
+		}
+	}
+	return err
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (c *GCPPubSub) CloseAsync() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.topic != nil {
+		c.topic.Stop()
+		c.client.Close()
+
+		c.topic = nil
+		c.client = nil
+	}
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs. If the timeout occurs then an error is returned.
+func (c *GCPPubSub) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
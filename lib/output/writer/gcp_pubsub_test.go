@@ -0,0 +1,143 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build integration
+
+package writer
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+// These tests require a Pub/Sub emulator, which can be started with:
+//
+//   gcloud beta emulators pubsub start --host-port=localhost:8085
+//   export PUBSUB_EMULATOR_HOST=localhost:8085
+
+func TestGCPPubSubOrderingAndAttributes(t *testing.T) {
+	if len(os.Getenv("PUBSUB_EMULATOR_HOST")) == 0 {
+		t.Skip("PUBSUB_EMULATOR_HOST is not set")
+	}
+
+	id := "benthos_test_output_ordering"
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "benthos-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	topic, err := client.CreateTopic(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer topic.Delete(ctx)
+
+	sub, err := client.CreateSubscription(ctx, id, pubsub.SubscriptionConfig{
+		Topic:                 topic,
+		AckDeadline:           time.Second * 10,
+		EnableMessageOrdering: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Delete(ctx)
+
+	conf := NewGCPPubSubConfig()
+	conf.ProjectID = "benthos-test"
+	conf.TopicID = id
+	conf.OrderingKey = "${!json_field:key}"
+	conf.Attributes = map[string]string{
+		"source": "benthos",
+		"key":    "${!json_field:key}",
+	}
+
+	w, err := NewGCPPubSub(conf, log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"}), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	parts := [][]byte{
+		[]byte(`{"key":"a","n":0}`),
+		[]byte(`{"key":"b","n":0}`),
+		[]byte(`{"key":"a","n":1}`),
+		[]byte(`{"key":"a","n":2}`),
+	}
+	if err = w.Write(types.NewMessage(parts)); err != nil {
+		t.Fatal(err)
+	}
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	var mut sync.Mutex
+	received := map[string][]string{}
+	count := 0
+
+	rctx, done := context.WithTimeout(ctx, time.Second*10)
+	defer done()
+	if err = sub.Receive(rctx, func(ctx context.Context, m *pubsub.Message) {
+		m.Ack()
+
+		mut.Lock()
+		defer mut.Unlock()
+
+		if exp, act := "benthos", m.Attributes["source"]; exp != act {
+			t.Errorf("Wrong source attribute: %v != %v", act, exp)
+		}
+		if exp, act := m.Attributes["key"], m.OrderingKey; exp != act {
+			t.Errorf("Wrong ordering key: %v != %v", act, exp)
+		}
+		received[m.OrderingKey] = append(received[m.OrderingKey], string(m.Data))
+		if count++; count == len(parts) {
+			done()
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string][]string{
+		"a": {`{"key":"a","n":0}`, `{"key":"a","n":1}`, `{"key":"a","n":2}`},
+		"b": {`{"key":"b","n":0}`},
+	}
+	for k, v := range exp {
+		if len(received[k]) != len(v) {
+			t.Fatalf("Wrong messages received for key %v: %v != %v", k, received[k], v)
+		}
+		for i := range v {
+			if v[i] != received[k][i] {
+				t.Errorf("Wrong message %v for key %v: %v != %v", i, k, received[k][i], v[i])
+			}
+		}
+	}
+}
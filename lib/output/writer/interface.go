@@ -21,9 +21,16 @@
 package writer
 
 import (
+	"errors"
+
 	"github.com/Jeffail/benthos/lib/types"
 )
 
+// ErrBatchPending is returned by Write when a message has been added to a batch
+// that is yet to be sent. The message is left unacknowledged, and is
+// acknowledged along with all prior messages by the next successful Write.
+var ErrBatchPending = errors.New("message added to a pending batch")
+
 // Type is a type that writes Benthos messages to a third party sink.
 type Type interface {
 	// Connect attempts to establish a connection to the sink, if unsuccessful
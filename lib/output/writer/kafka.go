@@ -21,7 +21,9 @@
 package writer
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,16 +36,46 @@ import (
 
 //------------------------------------------------------------------------------
 
+// Kafka partitioners.
+const (
+	KafkaPartitionerFNV1aHash   = "fnv1a_hash"
+	KafkaPartitionerMurmur2Hash = "murmur2_hash"
+	KafkaPartitionerManual      = "manual"
+	KafkaPartitionerRandom      = "random"
+	KafkaPartitionerRoundRobin  = "round_robin"
+)
+
+// Errors returned by the Kafka writer.
+var (
+	ErrInvalidKafkaPartitioner = errors.New("kafka partitioner was not recognised")
+	ErrInvalidKafkaCompression = errors.New("kafka compression codec was not recognised")
+	ErrKafkaHeadersVersion     = errors.New("kafka headers require a target version of at least 0.11.0.0")
+)
+
+// KafkaBatchingConfig is configuration for the batching of messages by the
+// Kafka producer, where zero values are left at the client defaults.
+type KafkaBatchingConfig struct {
+	MaxMessages int   `json:"max_messages" yaml:"max_messages"`
+	MaxBytes    int   `json:"max_bytes" yaml:"max_bytes"`
+	PeriodMS    int64 `json:"period_ms" yaml:"period_ms"`
+}
+
 // KafkaConfig is configuration for the Kafka output type.
 type KafkaConfig struct {
-	Addresses            []string `json:"addresses" yaml:"addresses"`
-	ClientID             string   `json:"client_id" yaml:"client_id"`
-	Key                  string   `json:"key" yaml:"key"`
-	RoundRobinPartitions bool     `json:"round_robin_partitions" yaml:"round_robin_partitions"`
-	Topic                string   `json:"topic" yaml:"topic"`
-	MaxMsgBytes          int      `json:"max_msg_bytes" yaml:"max_msg_bytes"`
-	TimeoutMS            int      `json:"timeout_ms" yaml:"timeout_ms"`
-	AckReplicas          bool     `json:"ack_replicas" yaml:"ack_replicas"`
+	Addresses            []string            `json:"addresses" yaml:"addresses"`
+	ClientID             string              `json:"client_id" yaml:"client_id"`
+	Key                  string              `json:"key" yaml:"key"`
+	Partitioner          string              `json:"partitioner" yaml:"partitioner"`
+	Partition            string              `json:"partition" yaml:"partition"`
+	RoundRobinPartitions bool                `json:"round_robin_partitions" yaml:"round_robin_partitions"`
+	Topic                string              `json:"topic" yaml:"topic"`
+	Headers              map[string]string   `json:"headers" yaml:"headers"`
+	Compression          string              `json:"compression" yaml:"compression"`
+	Batching             KafkaBatchingConfig `json:"batching" yaml:"batching"`
+	MaxMsgBytes          int                 `json:"max_msg_bytes" yaml:"max_msg_bytes"`
+	TimeoutMS            int                 `json:"timeout_ms" yaml:"timeout_ms"`
+	AckReplicas          bool                `json:"ack_replicas" yaml:"ack_replicas"`
+	TargetVersion        string              `json:"target_version" yaml:"target_version"`
 }
 
 // NewKafkaConfig creates a new KafkaConfig with default values.
@@ -52,16 +84,95 @@ func NewKafkaConfig() KafkaConfig {
 		Addresses:            []string{"localhost:9092"},
 		ClientID:             "benthos_kafka_output",
 		Key:                  "",
+		Partitioner:          KafkaPartitionerFNV1aHash,
+		Partition:            "",
 		RoundRobinPartitions: false,
 		Topic:                "benthos_stream",
-		MaxMsgBytes:          1000000,
-		TimeoutMS:            5000,
-		AckReplicas:          true,
+		Headers:              map[string]string{},
+		Compression:          "none",
+		Batching: KafkaBatchingConfig{
+			MaxMessages: 0,
+			MaxBytes:    0,
+			PeriodMS:    0,
+		},
+		MaxMsgBytes:   1000000,
+		TimeoutMS:     5000,
+		AckReplicas:   true,
+		TargetVersion: "",
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// murmur2 is the murmur2 hash as implemented by the Java Kafka client.
+func murmur2(data []byte) int32 {
+	length := len(data)
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// murmur2Partitioner selects partitions by the murmur2 hash of message keys in
+// the same way as the default partitioner of the Java Kafka client, and
+// therefore messages of a key are written to the same partitions by both.
+// Messages without a key are written to random partitions.
+type murmur2Partitioner struct {
+	random sarama.Partitioner
+}
+
+func newMurmur2Partitioner(topic string) sarama.Partitioner {
+	return &murmur2Partitioner{
+		random: sarama.NewRandomPartitioner(topic),
+	}
+}
+
+func (p *murmur2Partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if msg.Key == nil {
+		return p.random.Partition(msg, numPartitions)
+	}
+	key, err := msg.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+	return (murmur2(key) & 0x7fffffff) % numPartitions, nil
+}
+
+func (p *murmur2Partitioner) RequiresConsistency() bool {
+	return true
+}
+
+//------------------------------------------------------------------------------
+
 // Kafka is a writer type that writes messages into kafka.
 type Kafka struct {
 	log   log.Modular
@@ -73,6 +184,14 @@ type Kafka struct {
 	keyBytes       []byte
 	interpolateKey bool
 
+	partitionBytes  []byte
+	manualPartition bool
+	headers         map[string][]byte
+
+	version     sarama.KafkaVersion
+	partitioner sarama.PartitionerConstructor
+	compression sarama.CompressionCodec
+
 	producer sarama.SyncProducer
 }
 
@@ -87,6 +206,56 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 		conf:           conf,
 		keyBytes:       keyBytes,
 		interpolateKey: interpolateKey,
+		partitionBytes: []byte(conf.Partition),
+		headers:        map[string][]byte{},
+		version:        sarama.NewConfig().Version,
+	}
+
+	partitioner := conf.Partitioner
+	if conf.RoundRobinPartitions {
+		partitioner = KafkaPartitionerRoundRobin
+	}
+	switch partitioner {
+	case KafkaPartitionerFNV1aHash:
+		k.partitioner = sarama.NewHashPartitioner
+	case KafkaPartitionerMurmur2Hash:
+		k.partitioner = newMurmur2Partitioner
+	case KafkaPartitionerManual:
+		k.partitioner = sarama.NewManualPartitioner
+		k.manualPartition = true
+	case KafkaPartitionerRandom:
+		k.partitioner = sarama.NewRandomPartitioner
+	case KafkaPartitionerRoundRobin:
+		k.partitioner = sarama.NewRoundRobinPartitioner
+	default:
+		return nil, ErrInvalidKafkaPartitioner
+	}
+
+	switch conf.Compression {
+	case "none":
+		k.compression = sarama.CompressionNone
+	case "gzip":
+		k.compression = sarama.CompressionGZIP
+	case "snappy":
+		k.compression = sarama.CompressionSnappy
+	case "lz4":
+		k.compression = sarama.CompressionLZ4
+	default:
+		return nil, ErrInvalidKafkaCompression
+	}
+
+	if len(conf.TargetVersion) > 0 {
+		var err error
+		if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, value := range conf.Headers {
+		k.headers[name] = []byte(value)
+	}
+	if len(k.headers) > 0 && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, ErrKafkaHeadersVersion
 	}
 
 	for _, addr := range conf.Addresses {
@@ -110,15 +279,19 @@ func (k *Kafka) Connect() error {
 
 	config := sarama.NewConfig()
 	config.ClientID = k.conf.ClientID
+	config.Version = k.version
 
 	config.Producer.MaxMessageBytes = k.conf.MaxMsgBytes
 	config.Producer.Timeout = time.Duration(k.conf.TimeoutMS) * time.Millisecond
 	config.Producer.Return.Errors = true
 	config.Producer.Return.Successes = true
 
-	if k.conf.RoundRobinPartitions {
-		config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
-	}
+	config.Producer.Partitioner = k.partitioner
+	config.Producer.Compression = k.compression
+
+	config.Producer.Flush.Messages = k.conf.Batching.MaxMessages
+	config.Producer.Flush.Bytes = k.conf.Batching.MaxBytes
+	config.Producer.Flush.Frequency = time.Duration(k.conf.Batching.PeriodMS) * time.Millisecond
 
 	if k.conf.AckReplicas {
 		config.Producer.RequiredAcks = sarama.WaitForAll
@@ -143,7 +316,7 @@ func (k *Kafka) Write(msg types.Message) error {
 	}
 
 	msgs := []*sarama.ProducerMessage{}
-	for _, part := range msg.GetAll() {
+	for i, part := range msg.GetAll() {
 		if len(part) > k.conf.MaxMsgBytes {
 			k.stats.Incr("output.kafka.send.dropped.max_msg_bytes", 1)
 			continue
@@ -151,7 +324,7 @@ func (k *Kafka) Write(msg types.Message) error {
 
 		key := k.keyBytes
 		if k.interpolateKey {
-			key = text.ReplaceFunctionVariables(k.keyBytes)
+			key = text.ReplaceMessageFunctionVariables(msg, i, k.keyBytes)
 		}
 		nextMsg := &sarama.ProducerMessage{
 			Topic: k.conf.Topic,
@@ -160,6 +333,22 @@ func (k *Kafka) Write(msg types.Message) error {
 		if len(key) > 0 {
 			nextMsg.Key = sarama.ByteEncoder(key)
 		}
+
+		if k.manualPartition {
+			pStr := string(text.ReplaceMessageFunctionVariables(msg, i, k.partitionBytes))
+			partition, err := strconv.ParseInt(pStr, 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse partition '%v': %v", pStr, err)
+			}
+			nextMsg.Partition = int32(partition)
+		}
+
+		for name, value := range k.headers {
+			nextMsg.Headers = append(nextMsg.Headers, sarama.RecordHeader{
+				Key:   []byte(name),
+				Value: text.ReplaceMessageFunctionVariables(msg, i, value),
+			})
+		}
 		msgs = append(msgs, nextMsg)
 	}
 
@@ -0,0 +1,111 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaMurmur2(t *testing.T) {
+	// Expected values taken from the Java Kafka client.
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+
+	for input, exp := range tests {
+		if act := murmur2([]byte(input)); act != exp {
+			t.Errorf("Wrong hash for '%v': %v != %v", input, act, exp)
+		}
+	}
+}
+
+func TestKafkaMurmur2Partitioner(t *testing.T) {
+	p := newMurmur2Partitioner("foo")
+	if !p.RequiresConsistency() {
+		t.Error("Expected partitioner to require consistency")
+	}
+
+	msg := &sarama.ProducerMessage{Key: sarama.StringEncoder("foobar")}
+	partition, err := p.Partition(msg, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (int32(-790332482) & 0x7fffffff) % 10; partition != exp {
+		t.Errorf("Wrong partition: %v != %v", partition, exp)
+	}
+
+	for i := 0; i < 100; i++ {
+		if partition, err = p.Partition(&sarama.ProducerMessage{}, 10); err != nil {
+			t.Fatal(err)
+		}
+		if partition < 0 || partition >= 10 {
+			t.Fatalf("Partition out of range: %v", partition)
+		}
+	}
+}
+
+func TestKafkaBadConfigs(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := NewKafkaConfig()
+	conf.Partitioner = "nope"
+	if _, err := NewKafka(conf, logger, metrics.DudType{}); err != ErrInvalidKafkaPartitioner {
+		t.Errorf("Wrong error: %v != %v", err, ErrInvalidKafkaPartitioner)
+	}
+
+	conf = NewKafkaConfig()
+	conf.Compression = "nope"
+	if _, err := NewKafka(conf, logger, metrics.DudType{}); err != ErrInvalidKafkaCompression {
+		t.Errorf("Wrong error: %v != %v", err, ErrInvalidKafkaCompression)
+	}
+
+	conf = NewKafkaConfig()
+	conf.Headers = map[string]string{"foo": "bar"}
+	conf.TargetVersion = "0.10.0.0"
+	if _, err := NewKafka(conf, logger, metrics.DudType{}); err != ErrKafkaHeadersVersion {
+		t.Errorf("Wrong error: %v != %v", err, ErrKafkaHeadersVersion)
+	}
+
+	conf.TargetVersion = "1.0.0"
+	if _, err := NewKafka(conf, logger, metrics.DudType{}); err != nil {
+		t.Error(err)
+	}
+
+	conf = NewKafkaConfig()
+	conf.TargetVersion = "not a version"
+	if _, err := NewKafka(conf, logger, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad version")
+	}
+}
+
+//------------------------------------------------------------------------------
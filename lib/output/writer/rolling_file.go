@@ -0,0 +1,385 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// RollingFileConfig is configuration for the RollingFile output type.
+type RollingFileConfig struct {
+	Path         string `json:"path" yaml:"path"`
+	CustomDelim  string `json:"custom_delimiter" yaml:"custom_delimiter"`
+	MaxSizeBytes int64  `json:"max_size_bytes" yaml:"max_size_bytes"`
+	MaxPeriodMS  int    `json:"max_period_ms" yaml:"max_period_ms"`
+	Gzip         bool   `json:"gzip" yaml:"gzip"`
+	MaxFiles     int    `json:"max_files" yaml:"max_files"`
+}
+
+// NewRollingFileConfig creates a new RollingFileConfig with default values.
+func NewRollingFileConfig() RollingFileConfig {
+	return RollingFileConfig{
+		Path:         "./logs/${!timestamp:2006-01-02T15}/benthos.log",
+		CustomDelim:  "",
+		MaxSizeBytes: 100000000,
+		MaxPeriodMS:  0,
+		Gzip:         false,
+		MaxFiles:     0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var rollingFileFunctionRegex = regexp.MustCompile(`\${![a-z_]+(:[^}]+)?}`)
+
+// RollingFile is a writer type that appends messages to a file, which is
+// rotated once it reaches a size limit, an age limit, or when its interpolated
+// path changes.
+type RollingFile struct {
+	conf      RollingFileConfig
+	pathBytes []byte
+	delim     []byte
+
+	// A glob pattern that matches all files written by this writer.
+	pattern string
+
+	mut    sync.Mutex
+	file   *os.File
+	buf    *bufio.Writer
+	path   string
+	size   int64
+	opened time.Time
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewRollingFile creates a new RollingFile writer type.
+func NewRollingFile(conf RollingFileConfig, log log.Modular, stats metrics.Type) (*RollingFile, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("a path must be specified")
+	}
+
+	delim := []byte("\n")
+	if len(conf.CustomDelim) > 0 {
+		delim = []byte(conf.CustomDelim)
+	}
+
+	r := &RollingFile{
+		conf:       conf,
+		pathBytes:  []byte(conf.Path),
+		delim:      delim,
+		pattern:    rollingFileFunctionRegex.ReplaceAllString(conf.Path, "*") + "*",
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log.NewModule(".output.rolling_file"),
+		stats:      stats,
+	}
+	if _, err := filepath.Match(r.pattern, ""); err != nil {
+		return nil, err
+	}
+
+	if conf.MaxPeriodMS > 0 {
+		go r.loop()
+	} else {
+		close(r.closedChan)
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loop rotates the active file once it exceeds the maximum period, even when no
+// further messages arrive.
+func (r *RollingFile) loop() {
+	defer close(r.closedChan)
+
+	period := time.Duration(r.conf.MaxPeriodMS) * time.Millisecond
+	interval := period
+	if interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.closeChan:
+			return
+		}
+
+		r.mut.Lock()
+		if r.file != nil && time.Since(r.opened) >= period {
+			if err := r.rotate(true); err != nil {
+				r.log.Errorf("Failed to rotate file: %v\n", err)
+			}
+			r.prune()
+		}
+		r.mut.Unlock()
+	}
+}
+
+// Connect is a noop, files are opened as messages are written.
+func (r *RollingFile) Connect() error {
+	r.log.Infof("Writing messages to rolling files at path: %v\n", r.conf.Path)
+	return nil
+}
+
+// open opens the active file, appending to it if it already exists.
+func (r *RollingFile) open(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0777)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.FileMode(0666))
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.buf = bufio.NewWriter(file)
+	r.path = path
+	r.size = info.Size()
+	r.opened = time.Now()
+	return nil
+}
+
+// rotate flushes, syncs and closes the active file. If rename is true the file
+// is moved aside with a timestamp suffix so that its path can be reused. The
+// closed file is then compressed if gzip is enabled, in which case it is also
+// moved aside when a previously compressed file of the same path exists.
+func (r *RollingFile) rotate(rename bool) error {
+	file := r.file
+	r.file = nil
+	r.buf.Flush()
+
+	err := file.Sync()
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	r.stats.Incr("output.rolling_file.rotated", 1)
+
+	closedPath := r.path
+	if !rename && r.conf.Gzip {
+		if _, serr := os.Stat(closedPath + ".gz"); serr == nil {
+			rename = true
+		}
+	}
+	if rename {
+		closedPath = r.path + "." + time.Now().Format("20060102T150405.000000000")
+		if err = os.Rename(r.path, closedPath); err != nil {
+			return err
+		}
+	}
+	if r.conf.Gzip {
+		if err = gzipFile(closedPath); err != nil {
+			r.log.Errorf("Failed to compress file '%v': %v\n", closedPath, err)
+		}
+	}
+	r.log.Infof("Rotated file '%v'\n", closedPath)
+	return nil
+}
+
+// gzipFile compresses a file into a new file with the suffix '.gz' and removes
+// the original. An existing file with the suffix is never overwritten.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_EXCL, os.FileMode(0666))
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// prune removes the oldest files written by this writer, other than the active
+// file, beyond the maximum number of retained files.
+func (r *RollingFile) prune() {
+	if r.conf.MaxFiles <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(r.pattern)
+	if err != nil {
+		r.log.Errorf("Failed to match retained files: %v\n", err)
+		return
+	}
+
+	type retained struct {
+		path    string
+		modTime time.Time
+	}
+	var files []retained
+	for _, path := range matches {
+		if r.file != nil && path == r.path {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, retained{path: path, modTime: info.ModTime()})
+	}
+	if len(files) <= r.conf.MaxFiles {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files[:len(files)-r.conf.MaxFiles] {
+		if err = os.Remove(f.path); err != nil {
+			r.log.Errorf("Failed to remove file '%v': %v\n", f.path, err)
+			continue
+		}
+		r.stats.Incr("output.rolling_file.removed", 1)
+	}
+}
+
+// Write appends a message to the active file, rotating the file beforehand if
+// its path has changed or if it has reached its size or age limit.
+func (r *RollingFile) Write(msg types.Message) error {
+	if msg.Len() == 0 {
+		return nil
+	}
+
+	path := r.conf.Path
+	if text.ContainsFunctionVariables(r.pathBytes) {
+		path = string(text.ReplaceMessageFunctionVariables(msg, 0, r.pathBytes))
+	}
+
+	var data []byte
+	if msg.Len() == 1 {
+		data = append(append(data, msg.Get(0)...), r.delim...)
+	} else {
+		data = append(bytes.Join(msg.GetAll(), r.delim), r.delim...)
+		data = append(data, r.delim...)
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.file != nil {
+		var err error
+		if path != r.path {
+			err = r.rotate(false)
+		} else if r.conf.MaxSizeBytes > 0 && r.size > 0 && r.size+int64(len(data)) > r.conf.MaxSizeBytes {
+			err = r.rotate(true)
+		} else if r.conf.MaxPeriodMS > 0 && time.Since(r.opened) >= time.Duration(r.conf.MaxPeriodMS)*time.Millisecond {
+			err = r.rotate(true)
+		}
+		if err != nil {
+			r.log.Errorf("Failed to rotate file: %v\n", err)
+		}
+	}
+
+	if r.file == nil {
+		if err := r.open(path); err != nil {
+			return err
+		}
+		r.prune()
+	}
+
+	n, err := r.buf.Write(data)
+	r.size += int64(n)
+	if err == nil {
+		err = r.buf.Flush()
+	}
+	return err
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (r *RollingFile) CloseAsync() {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (r *RollingFile) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.file != nil {
+		r.buf.Flush()
+		r.file.Sync()
+		r.file.Close()
+		r.file = nil
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,256 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func newRollingFileTest(t *testing.T, conf RollingFileConfig) *RollingFile {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	r, err := NewRollingFile(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func readFileString(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestRollingFileSizeGzipRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "out.log")
+	conf.MaxSizeBytes = 10
+	conf.Gzip = true
+	conf.MaxFiles = 2
+
+	r := newRollingFileTest(t, conf)
+
+	for i := 0; i < 7; i++ {
+		if err = r.Write(types.NewMessage([][]byte{[]byte("aaaa")})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "aaaa\n", readFileString(t, conf.Path); exp != act {
+		t.Errorf("Wrong active file content: %q != %q", act, exp)
+	}
+
+	rotated, err := filepath.Glob(conf.Path + ".*.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, len(rotated); exp != act {
+		t.Fatalf("Wrong count of retained files: %v != %v", act, exp)
+	}
+	for _, path := range rotated {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(zr)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := "aaaa\naaaa\n", string(content); exp != act {
+			t.Errorf("Wrong rotated file content: %q != %q", act, exp)
+		}
+	}
+}
+
+func TestRollingFilePathChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "${!json_field:day}", "out.log")
+
+	r := newRollingFileTest(t, conf)
+	defer r.CloseAsync()
+
+	msgs := []types.Message{
+		types.NewMessage([][]byte{[]byte(`{"day":"a","n":1}`)}),
+		types.NewMessage([][]byte{[]byte(`{"day":"a","n":2}`), []byte(`{"day":"b","n":3}`)}),
+		types.NewMessage([][]byte{[]byte(`{"day":"b","n":4}`)}),
+	}
+	for _, msg := range msgs {
+		if err = r.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := "{\"day\":\"a\",\"n\":1}\n{\"day\":\"a\",\"n\":2}\n{\"day\":\"b\",\"n\":3}\n\n"
+	if act := readFileString(t, filepath.Join(dir, "a", "out.log")); exp != act {
+		t.Errorf("Wrong file content: %q != %q", act, exp)
+	}
+	exp = "{\"day\":\"b\",\"n\":4}\n"
+	if act := readFileString(t, filepath.Join(dir, "b", "out.log")); exp != act {
+		t.Errorf("Wrong file content: %q != %q", act, exp)
+	}
+}
+
+func TestRollingFilePathChangeGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "${!json_field:k}.log")
+	conf.Gzip = true
+
+	r := newRollingFileTest(t, conf)
+
+	// Paths that return to a previous value are rotated more than once.
+	for _, part := range []string{
+		`{"k":"a","n":1}`,
+		`{"k":"b","n":2}`,
+		`{"k":"a","n":3}`,
+		`{"k":"b","n":4}`,
+	} {
+		if err = r.Write(types.NewMessage([][]byte{[]byte(part)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "*.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 3, len(rotated); exp != act {
+		t.Fatalf("Wrong count of rotated files: %v != %v", act, exp)
+	}
+
+	var records []string
+	for _, path := range rotated {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(zr)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, strings.Split(strings.TrimSpace(string(content)), "\n")...)
+	}
+	records = append(records, strings.TrimSpace(readFileString(t, filepath.Join(dir, "b.log"))))
+
+	sort.Strings(records)
+	exp := []string{
+		`{"k":"a","n":1}`,
+		`{"k":"a","n":3}`,
+		`{"k":"b","n":2}`,
+		`{"k":"b","n":4}`,
+	}
+	if !reflect.DeepEqual(exp, records) {
+		t.Errorf("Wrong records: %q != %q", records, exp)
+	}
+}
+
+func TestRollingFilePeriod(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_rolling_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewRollingFileConfig()
+	conf.Path = filepath.Join(dir, "out.log")
+	conf.MaxPeriodMS = 10
+
+	r := newRollingFileTest(t, conf)
+	defer r.CloseAsync()
+
+	if err = r.Write(types.NewMessage([][]byte{[]byte("foo")})); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		rotated, _ := filepath.Glob(conf.Path + ".*")
+		if len(rotated) == 1 {
+			if exp, act := "foo\n", readFileString(t, rotated[0]); exp != act {
+				t.Errorf("Wrong rotated file content: %q != %q", act, exp)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for file rotation")
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+	if _, err = os.Stat(conf.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected active file to be rotated: %v", err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,206 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package writer
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// SocketConfig is configuration for the Socket output type.
+type SocketConfig struct {
+	Network      string `json:"network" yaml:"network"`
+	Address      string `json:"address" yaml:"address"`
+	CustomDelim  string `json:"custom_delimiter" yaml:"custom_delimiter"`
+	MaxBackoffMS int64  `json:"max_backoff_ms" yaml:"max_backoff_ms"`
+}
+
+// NewSocketConfig creates a new SocketConfig with default values.
+func NewSocketConfig() SocketConfig {
+	return SocketConfig{
+		Network:      "tcp",
+		Address:      "localhost:6000",
+		CustomDelim:  "",
+		MaxBackoffMS: 30000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Socket is an output type that writes delimited messages to a socket.
+type Socket struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf  SocketConfig
+	delim []byte
+
+	backoff time.Duration
+
+	connMut sync.Mutex
+	conn    net.Conn
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSocket creates a new Socket output type.
+func NewSocket(
+	conf SocketConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Socket, error) {
+	switch conf.Network {
+	case "tcp", "udp", "unix":
+	default:
+		return nil, types.ErrInvalidSocketNetwork
+	}
+
+	delim := []byte("\n")
+	if len(conf.CustomDelim) > 0 {
+		delim = []byte(conf.CustomDelim)
+	}
+
+	return &Socket{
+		log:       log.NewModule(".output.socket"),
+		stats:     stats,
+		conf:      conf,
+		delim:     delim,
+		closeChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect dials the socket address. Consecutive failed attempts are delayed
+// by an exponential backoff, up to a configured maximum.
+func (s *Socket) Connect() error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.conn != nil {
+		return nil
+	}
+
+	if s.backoff > 0 {
+		select {
+		case <-time.After(s.backoff):
+		case <-s.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+
+	select {
+	case <-s.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	conn, err := net.Dial(s.conf.Network, s.conf.Address)
+	if err != nil {
+		s.increaseBackoff()
+		return err
+	}
+
+	s.backoff = 0
+	s.conn = conn
+	s.log.Infof("Sending socket messages to: %v://%v\n", s.conf.Network, s.conf.Address)
+	return nil
+}
+
+func (s *Socket) increaseBackoff() {
+	maxBackoff := time.Duration(s.conf.MaxBackoffMS) * time.Millisecond
+	if s.backoff == 0 {
+		s.backoff = time.Millisecond * 100
+	} else {
+		s.backoff *= 2
+	}
+	if s.backoff > maxBackoff {
+		s.backoff = maxBackoff
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Write attempts to write a message to the socket. Message parts are written
+// delimited, and messages of multiple parts are followed by an extra delimiter
+// in order to mark the end of the message.
+func (s *Socket) Write(msg types.Message) error {
+	s.connMut.Lock()
+	conn := s.conn
+	s.connMut.Unlock()
+
+	if conn == nil {
+		return types.ErrNotConnected
+	}
+
+	var data []byte
+	if msg.Len() == 1 {
+		data = append(append(data, msg.Get(0)...), s.delim...)
+	} else {
+		data = append(bytes.Join(msg.GetAll(), s.delim), s.delim...)
+		data = append(data, s.delim...)
+	}
+
+	if _, err := conn.Write(data); err != nil {
+		select {
+		case <-s.closeChan:
+			return types.ErrTypeClosed
+		default:
+		}
+		s.log.Errorf("Lost socket connection: %v\n", err)
+		conn.Close()
+
+		s.connMut.Lock()
+		s.conn = nil
+		s.connMut.Unlock()
+		return types.ErrNotConnected
+	}
+	return nil
+}
+
+// CloseAsync shuts down the Socket output and stops processing messages.
+func (s *Socket) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+
+		s.connMut.Lock()
+		if s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+		s.connMut.Unlock()
+	})
+}
+
+// WaitForClose blocks until the Socket output has closed down.
+func (s *Socket) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
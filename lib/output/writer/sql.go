@@ -0,0 +1,228 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/gabs"
+
+	// SQL drivers supported by the SQL output.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrSQLInvalidDriver is returned when an SQL driver is not supported.
+var ErrSQLInvalidDriver = errors.New("sql driver must be one of mysql, postgres or sqlite3")
+
+// ErrSQLDriverUnavailable is returned when the sqlite3 driver is configured but
+// Benthos was built without cgo, which the driver requires.
+var ErrSQLDriverUnavailable = errors.New("sqlite3 driver is unavailable as benthos was built without cgo")
+
+// SQLConfig is configuration for the SQL output type.
+type SQLConfig struct {
+	Driver string   `json:"driver" yaml:"driver"`
+	DSN    string   `json:"dsn" yaml:"dsn"`
+	Query  string   `json:"query" yaml:"query"`
+	Args   []string `json:"args" yaml:"args"`
+}
+
+// NewSQLConfig creates a new SQLConfig with default values.
+func NewSQLConfig() SQLConfig {
+	return SQLConfig{
+		Driver: "mysql",
+		DSN:    "",
+		Query:  "INSERT INTO benthos_table (id, body) VALUES (?, ?)",
+		Args:   []string{"id", "body"},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SQL is a writer type that executes a statement for each message part against
+// an SQL database, with arguments mapped from fields of the part as JSON.
+type SQL struct {
+	db    *sql.DB
+	dbMut sync.Mutex
+
+	conf SQLConfig
+
+	stats metrics.Type
+	log   log.Modular
+}
+
+// NewSQL creates a new SQL writer type.
+func NewSQL(conf SQLConfig, log log.Modular, stats metrics.Type) (*SQL, error) {
+	switch conf.Driver {
+	case "mysql", "postgres":
+	case "sqlite3":
+		if !sqlite3Available {
+			return nil, ErrSQLDriverUnavailable
+		}
+	default:
+		return nil, ErrSQLInvalidDriver
+	}
+	if len(conf.Query) == 0 {
+		return nil, errors.New("an sql query must be specified")
+	}
+	return &SQL{
+		conf:  conf,
+		stats: stats,
+		log:   log.NewModule(".output.sql"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect opens a connection to the database.
+func (s *SQL) Connect() error {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	db, err := sql.Open(s.conf.Driver, s.conf.DSN)
+	if err != nil {
+		return err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+
+	s.log.Infof("Writing messages to %v database\n", s.conf.Driver)
+
+	s.db = db
+	return nil
+}
+
+// partArgs returns the arguments of the statement for a message part, where an
+// empty path refers to the raw part and fields that do not exist are NULL.
+func (s *SQL) partArgs(part []byte) ([]interface{}, error) {
+	var jObj *gabs.Container
+	args := make([]interface{}, len(s.conf.Args))
+	for i, path := range s.conf.Args {
+		if len(path) == 0 {
+			args[i] = string(part)
+			continue
+		}
+		if jObj == nil {
+			dec := json.NewDecoder(bytes.NewReader(part))
+			dec.UseNumber()
+
+			var err error
+			if jObj, err = gabs.ParseJSONDecoder(dec); err != nil {
+				return nil, err
+			}
+		}
+		switch t := jObj.Path(path).Data().(type) {
+		case map[string]interface{}, []interface{}:
+			argBytes, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = string(argBytes)
+		case json.Number:
+			if n, err := t.Int64(); err == nil {
+				args[i] = n
+			} else if f, err := t.Float64(); err == nil {
+				args[i] = f
+			} else {
+				args[i] = t.String()
+			}
+		default:
+			args[i] = t
+		}
+	}
+	return args, nil
+}
+
+// Write executes the statement for each part of a message within a single
+// transaction, which is rolled back if any part fails.
+func (s *SQL) Write(msg types.Message) error {
+	s.dbMut.Lock()
+	db := s.db
+	s.dbMut.Unlock()
+
+	if db == nil {
+		return types.ErrNotConnected
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(s.conf.Query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for i, part := range msg.GetAll() {
+		args, err := s.partArgs(part)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to parse message part %v: %v", i, err)
+		}
+		if _, err = stmt.Exec(args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	s.stats.Incr("output.sql.rows", int64(msg.Len()))
+	return nil
+}
+
+// CloseAsync shuts down the SQL output and stops processing messages.
+func (s *SQL) CloseAsync() {
+	s.dbMut.Lock()
+	if s.db != nil {
+		s.db.Close()
+		s.db = nil
+	}
+	s.dbMut.Unlock()
+}
+
+// WaitForClose blocks until the SQL output has closed down.
+func (s *SQL) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
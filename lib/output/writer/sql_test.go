@@ -0,0 +1,157 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestSQLNotConnected(t *testing.T) {
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	s, err := NewSQL(NewSQLConfig(), logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Write(types.NewMessage([][]byte{[]byte("foo")})); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+}
+
+func TestSQLDriverUnavailable(t *testing.T) {
+	if sqlite3Available {
+		t.Skip("The sqlite3 driver is available")
+	}
+
+	conf := NewSQLConfig()
+	conf.Driver = "sqlite3"
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	if _, err := NewSQL(conf, logger, metrics.DudType{}); err != ErrSQLDriverUnavailable {
+		t.Errorf("Wrong error: %v != %v", err, ErrSQLDriverUnavailable)
+	}
+}
+
+func TestSQLUpsert(t *testing.T) {
+	if !sqlite3Available {
+		t.Skip("The sqlite3 driver requires cgo")
+	}
+
+	dir, err := ioutil.TempDir("", "benthos_sql_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dsn := filepath.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err = db.Exec(
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT NOT NULL, tags TEXT, raw TEXT)",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewSQLConfig()
+	conf.Driver = "sqlite3"
+	conf.DSN = dsn
+	conf.Query = "INSERT INTO events (id, name, tags, raw) VALUES (?, ?, ?, ?) " +
+		"ON CONFLICT (id) DO UPDATE SET name = excluded.name, tags = excluded.tags, raw = excluded.raw"
+	conf.Args = []string{"id", "user.name", "tags", ""}
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	s, err := NewSQL(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Write(types.NewMessage([][]byte{
+		[]byte(`{"id":1,"user":{"name":"foo"},"tags":["a","b"]}`),
+		[]byte(`{"id":2,"user":{"name":"bar"}}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Write(types.NewMessage([][]byte{
+		[]byte(`{"id":2,"user":{"name":"baz"}}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed part must roll back the whole message.
+	if err = s.Write(types.NewMessage([][]byte{
+		[]byte(`{"id":3,"user":{"name":"qux"}}`),
+		[]byte(`{"id":4}`),
+	})); err == nil {
+		t.Error("Expected error from missing name")
+	}
+	if err = s.Write(types.NewMessage([][]byte{
+		[]byte(`{"id":5,"user":{"name":"quz"}}`),
+		[]byte(`not json`),
+	})); err == nil {
+		t.Error("Expected error from invalid JSON")
+	}
+
+	rows, err := db.Query("SELECT id, name, tags, raw FROM events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var act [][]interface{}
+	for rows.Next() {
+		var id int64
+		var name string
+		var tags, raw sql.NullString
+		if err = rows.Scan(&id, &name, &tags, &raw); err != nil {
+			t.Fatal(err)
+		}
+		act = append(act, []interface{}{id, name, tags.String, raw.String})
+	}
+
+	exp := [][]interface{}{
+		{int64(1), "foo", `["a","b"]`, `{"id":1,"user":{"name":"foo"},"tags":["a","b"]}`},
+		{int64(2), "baz", "", `{"id":2,"user":{"name":"baz"}}`},
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rows: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,368 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package writer
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"golang.org/x/net/websocket"
+)
+
+//------------------------------------------------------------------------------
+
+// Websocket modes.
+const (
+	WebsocketModeClient = "client"
+	WebsocketModeServer = "server"
+)
+
+// WebsocketConfig is configuration for the Websocket output type.
+type WebsocketConfig struct {
+	Mode           string `json:"mode" yaml:"mode"`
+	URL            string `json:"url" yaml:"url"`
+	Origin         string `json:"origin" yaml:"origin"`
+	Address        string `json:"address" yaml:"address"`
+	Path           string `json:"path" yaml:"path"`
+	PingPeriodMS   int64  `json:"ping_period_ms" yaml:"ping_period_ms"`
+	WriteTimeoutMS int64  `json:"write_timeout_ms" yaml:"write_timeout_ms"`
+}
+
+// NewWebsocketConfig creates a new WebsocketConfig with default values.
+func NewWebsocketConfig() WebsocketConfig {
+	return WebsocketConfig{
+		Mode:           WebsocketModeClient,
+		URL:            "ws://localhost:4195/post/ws",
+		Origin:         "http://localhost",
+		Address:        "",
+		Path:           "/get/ws",
+		PingPeriodMS:   30000,
+		WriteTimeoutMS: 5000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Websocket is an output type that writes messages as websocket frames, either
+// by dialing a remote server or by broadcasting to connected clients.
+type Websocket struct {
+	conf         WebsocketConfig
+	stats        metrics.Type
+	log          log.Modular
+	pingPeriod   time.Duration
+	writeTimeout time.Duration
+
+	lock      sync.Mutex
+	cond      *sync.Cond
+	client    *websocket.Conn
+	server    *http.Server
+	listeners map[*websocket.Conn]chan struct{}
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWebsocket creates a new Websocket output type.
+func NewWebsocket(
+	conf WebsocketConfig, log log.Modular, stats metrics.Type,
+) (*Websocket, error) {
+	if conf.Mode != WebsocketModeClient && conf.Mode != WebsocketModeServer {
+		return nil, types.ErrInvalidWebsocketMode
+	}
+	w := &Websocket{
+		conf:         conf,
+		stats:        stats,
+		log:          log.NewModule(".output.websocket"),
+		pingPeriod:   time.Duration(conf.PingPeriodMS) * time.Millisecond,
+		writeTimeout: time.Duration(conf.WriteTimeoutMS) * time.Millisecond,
+		listeners:    map[*websocket.Conn]chan struct{}{},
+		closeChan:    make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.lock)
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+// pingCodec writes empty ping frames to a websocket connection. Pong frames
+// are discarded by the connection as they are received.
+var pingCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		return nil, websocket.PingFrame, nil
+	},
+}
+
+// setWriteDeadline sets the deadline for the next write to a connection, or
+// clears it when the timeout is zero.
+func setWriteDeadline(conn *websocket.Conn, timeout time.Duration) error {
+	if timeout <= 0 {
+		return conn.SetWriteDeadline(time.Time{})
+	}
+	return conn.SetWriteDeadline(time.Now().Add(timeout))
+}
+
+// keepAlive periodically pings a connection until either it fails or done is
+// closed. A failed ping closes the connection.
+func keepAlive(conn *websocket.Conn, period, timeout time.Duration, done <-chan struct{}) {
+	if period <= 0 {
+		return
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := setWriteDeadline(conn, timeout); err != nil {
+				conn.Close()
+				return
+			}
+			if err := pingCodec.Send(conn, nil); err != nil {
+				conn.Close()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// writeMessage writes each part of a message as a frame. Messages of multiple
+// parts are followed by an empty frame in order to mark the end of the
+// message. The whole message must be written within the timeout when it is
+// greater than zero.
+func writeMessage(conn *websocket.Conn, msg types.Message, timeout time.Duration) error {
+	if err := setWriteDeadline(conn, timeout); err != nil {
+		return err
+	}
+	for _, part := range msg.GetAll() {
+		if err := websocket.Message.Send(conn, part); err != nil {
+			return err
+		}
+	}
+	if msg.Len() > 1 {
+		return websocket.Message.Send(conn, []byte{})
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// ServeHTTP accepts websocket connections from clients when in server mode,
+// which then receive all messages written until they disconnect. This handler
+// can be registered with an HTTP server, which is done automatically when an
+// address is configured.
+func (w *Websocket) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	websocket.Server{Handler: w.handleConn}.ServeHTTP(res, req)
+}
+
+func (w *Websocket) handleConn(conn *websocket.Conn) {
+	done := make(chan struct{})
+
+	w.lock.Lock()
+	w.listeners[conn] = done
+	w.cond.Broadcast()
+	w.lock.Unlock()
+
+	w.stats.Incr("output.websocket.server.connection.up", 1)
+	defer w.stats.Incr("output.websocket.server.connection.down", 1)
+
+	go keepAlive(conn, w.pingPeriod, w.writeTimeout, done)
+
+	// Frames sent by clients are discarded, but reading is required in order to
+	// process control frames and detect closed connections.
+	for {
+		var frame []byte
+		if err := websocket.Message.Receive(conn, &frame); err != nil {
+			break
+		}
+	}
+	w.removeListener(conn)
+}
+
+func (w *Websocket) removeListener(conn *websocket.Conn) {
+	w.lock.Lock()
+	if done, exists := w.listeners[conn]; exists {
+		delete(w.listeners, conn)
+		close(done)
+		conn.Close()
+	}
+	w.lock.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// Connect dials the configured URL in client mode, or begins listening on the
+// configured address in server mode.
+func (w *Websocket) Connect() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	select {
+	case <-w.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	if w.conf.Mode == WebsocketModeServer {
+		if w.server != nil || len(w.conf.Address) == 0 {
+			return nil
+		}
+		listener, err := net.Listen("tcp", w.conf.Address)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle(w.conf.Path, w)
+		w.server = &http.Server{Handler: mux}
+		go func(s *http.Server) {
+			if sErr := s.Serve(listener); sErr != http.ErrServerClosed {
+				w.log.Errorf("Server error: %v\n", sErr)
+			}
+		}(w.server)
+		w.log.Infof(
+			"Sending websocket messages at: %s\n", w.conf.Address+w.conf.Path,
+		)
+		return nil
+	}
+
+	if w.client != nil {
+		return nil
+	}
+	conn, err := websocket.Dial(w.conf.URL, "", w.conf.Origin)
+	if err != nil {
+		return err
+	}
+	w.client = conn
+	go keepAlive(conn, w.pingPeriod, w.writeTimeout, w.closeChan)
+
+	w.log.Infof("Sending websocket messages to: %s\n", w.conf.URL)
+	return nil
+}
+
+// Write attempts to write a message to the websocket. In server mode this
+// blocks until at least one client is connected, and the message is written to
+// all connected clients.
+func (w *Websocket) Write(msg types.Message) error {
+	if w.conf.Mode == WebsocketModeServer {
+		return w.broadcast(msg)
+	}
+
+	w.lock.Lock()
+	conn := w.client
+	w.lock.Unlock()
+
+	if conn == nil {
+		return types.ErrNotConnected
+	}
+
+	if err := writeMessage(conn, msg, w.writeTimeout); err != nil {
+		select {
+		case <-w.closeChan:
+			return types.ErrTypeClosed
+		default:
+		}
+		w.log.Errorf("Lost websocket connection: %v\n", err)
+		conn.Close()
+
+		w.lock.Lock()
+		w.client = nil
+		w.lock.Unlock()
+		return types.ErrNotConnected
+	}
+	return nil
+}
+
+func (w *Websocket) broadcast(msg types.Message) error {
+	w.lock.Lock()
+	for len(w.listeners) == 0 {
+		select {
+		case <-w.closeChan:
+			w.lock.Unlock()
+			return types.ErrTypeClosed
+		default:
+		}
+		w.cond.Wait()
+	}
+	conns := make([]*websocket.Conn, 0, len(w.listeners))
+	for conn := range w.listeners {
+		conns = append(conns, conn)
+	}
+	w.lock.Unlock()
+
+	// Clients are written to in parallel so that a slow client only delays
+	// the message by at most the write timeout.
+	var sent int32
+	wg := sync.WaitGroup{}
+	wg.Add(len(conns))
+	for _, conn := range conns {
+		go func(c *websocket.Conn) {
+			defer wg.Done()
+			if err := writeMessage(c, msg, w.writeTimeout); err != nil {
+				w.log.Debugf("Dropping websocket client: %v\n", err)
+				w.stats.Incr("output.websocket.server.connection.dropped", 1)
+				w.removeListener(c)
+				return
+			}
+			atomic.AddInt32(&sent, 1)
+		}(conn)
+	}
+	wg.Wait()
+
+	if sent == 0 {
+		return types.ErrNotConnected
+	}
+	return nil
+}
+
+// CloseAsync shuts down the Websocket output and stops processing messages.
+func (w *Websocket) CloseAsync() {
+	w.closeOnce.Do(func() {
+		close(w.closeChan)
+
+		w.lock.Lock()
+		if w.client != nil {
+			w.client.Close()
+			w.client = nil
+		}
+		if w.server != nil {
+			w.server.Close()
+		}
+		for conn, done := range w.listeners {
+			delete(w.listeners, conn)
+			close(done)
+			conn.Close()
+		}
+		w.cond.Broadcast()
+		w.lock.Unlock()
+	})
+}
+
+// WaitForClose blocks until the Websocket output has closed down.
+func (w *Websocket) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,125 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebsocketServerStalledClient(t *testing.T) {
+	conf := NewWebsocketConfig()
+	conf.Mode = WebsocketModeServer
+	conf.PingPeriodMS = 0
+	conf.WriteTimeoutMS = 100
+
+	logger := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+	w, err := NewWebsocket(conf, logger, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	server := httptest.NewServer(w)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// The stalled client never reads any frames.
+	stalled, err := websocket.Dial(url, "", "http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+
+	healthy, err := websocket.Dial(url, "", "http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer healthy.Close()
+
+	listeners := func() int {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		return len(w.listeners)
+	}
+	for i := 0; i < 100 && listeners() < 2; i++ {
+		<-time.After(time.Millisecond * 10)
+	}
+	if exp, act := 2, listeners(); exp != act {
+		t.Fatalf("Wrong count of clients: %v != %v", act, exp)
+	}
+
+	// Enough data to fill the socket buffers of the stalled client.
+	n := 50
+	part := make([]byte, 1024*1024)
+
+	receivedChan := make(chan int)
+	go func() {
+		received := 0
+		for received < n {
+			var frame []byte
+			if err := websocket.Message.Receive(healthy, &frame); err != nil {
+				break
+			}
+			received++
+		}
+		receivedChan <- received
+	}()
+
+	writeErrChan := make(chan error)
+	go func() {
+		for i := 0; i < n; i++ {
+			if err := w.Write(types.NewMessage([][]byte{part})); err != nil {
+				writeErrChan <- err
+				return
+			}
+		}
+		writeErrChan <- nil
+	}()
+
+	select {
+	case err = <-writeErrChan:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("Timed out writing messages")
+	}
+	select {
+	case received := <-receivedChan:
+		if received != n {
+			t.Errorf("Wrong count of received messages: %v != %v", received, n)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("Timed out receiving messages")
+	}
+	if exp, act := 1, listeners(); exp != act {
+		t.Errorf("Wrong count of clients: %v != %v", act, exp)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/service/log"
 	"github.com/Jeffail/benthos/lib/util/service/metrics"
@@ -569,4 +570,61 @@ func TestWriterSadPath(t *testing.T) {
 	}
 }
 
+func TestWriterBatchPending(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := newMockWriter()
+
+	w, err := NewWriter(
+		"foo", writerImpl,
+		log.NewLogger(os.Stdout, logConfig), metrics.DudType{},
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = w.StartReceiving(msgChan); err != nil {
+		t.Error(err)
+	}
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	for _, writeErr := range []error{writer.ErrBatchPending, nil} {
+		select {
+		case msgChan <- types.NewTransaction(types.NewMessage(nil), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case writerImpl.writeChan <- writeErr:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Errorf("Unexpected error: %v", res.Error())
+			}
+			if exp, act := writeErr == writer.ErrBatchPending, res.SkipAck(); exp != act {
+				t.Errorf("Wrong skip ack: %v != %v", act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,244 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/aws/attribute"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/Jeffail/gabs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors["amazon_dynamodb"] = TypeSpec{
+		constructor: NewAmazonDynamoDB,
+		description: `
+Enriches JSON message parts with items fetched from an Amazon DynamoDB table.
+For each targeted part the primary key of an item is read from the part, the
+item is fetched, and its attributes are written into the part as JSON.
+
+The partition key of the table is set with 'hash_key' and, for tables with a
+composite primary key, the sort key with 'range_key'. By default key values are
+read from the fields of the same name, but they can instead be read from any
+field of the part by setting 'hash_key_path' and 'range_key_path' to a dot
+separated path.
+
+If 'target_path' is empty the attributes of the item are merged into the root
+of the part, otherwise the item is set as an object at that path. Parts for
+which no item is found, that are not JSON objects or that lack a key are left
+unchanged, as are parts for which the fetch fails.
+
+If the list of target parts is empty all message parts are enriched. Part
+indexes can be negative, and if so the part will be selected from the end
+counting backwards starting from -1.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AmazonDynamoDBConfig contains any configuration for the AmazonDynamoDB
+// processor.
+type AmazonDynamoDBConfig struct {
+	sess.Config    `json:",inline" yaml:",inline"`
+	Table          string `json:"table" yaml:"table"`
+	HashKey        string `json:"hash_key" yaml:"hash_key"`
+	HashKeyPath    string `json:"hash_key_path" yaml:"hash_key_path"`
+	RangeKey       string `json:"range_key" yaml:"range_key"`
+	RangeKeyPath   string `json:"range_key_path" yaml:"range_key_path"`
+	ConsistentRead bool   `json:"consistent_read" yaml:"consistent_read"`
+	TargetPath     string `json:"target_path" yaml:"target_path"`
+	Parts          []int  `json:"parts" yaml:"parts"`
+}
+
+// NewAmazonDynamoDBConfig returns an AmazonDynamoDBConfig with default values.
+func NewAmazonDynamoDBConfig() AmazonDynamoDBConfig {
+	return AmazonDynamoDBConfig{
+		Config:         sess.NewConfig(),
+		Table:          "",
+		HashKey:        "id",
+		HashKeyPath:    "",
+		RangeKey:       "",
+		RangeKeyPath:   "",
+		ConsistentRead: false,
+		TargetPath:     "",
+		Parts:          []int{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AmazonDynamoDB is a processor that enriches message parts with items fetched
+// from an Amazon DynamoDB table.
+type AmazonDynamoDB struct {
+	conf         AmazonDynamoDBConfig
+	hashKeyPath  string
+	rangeKeyPath string
+
+	dynamo *dynamodb.DynamoDB
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewAmazonDynamoDB returns an AmazonDynamoDB processor.
+func NewAmazonDynamoDB(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	dConf := conf.AmazonDynamoDB
+	if len(dConf.Table) == 0 {
+		return nil, errors.New("invalid table (cannot be empty)")
+	}
+	if len(dConf.HashKey) == 0 {
+		return nil, errors.New("invalid hash_key (cannot be empty)")
+	}
+
+	sess, err := dConf.GetSession()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &AmazonDynamoDB{
+		conf:         dConf,
+		hashKeyPath:  dConf.HashKeyPath,
+		rangeKeyPath: dConf.RangeKeyPath,
+		dynamo:       dynamodb.New(sess),
+		log:          log.NewModule(".processor.amazon_dynamodb"),
+		stats:        stats,
+	}
+	if len(d.hashKeyPath) == 0 {
+		d.hashKeyPath = dConf.HashKey
+	}
+	if len(d.rangeKeyPath) == 0 {
+		d.rangeKeyPath = dConf.RangeKey
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// enrich fetches the item for a part and returns the enriched part, or nil if
+// no item was found.
+func (d *AmazonDynamoDB) enrich(part []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(part))
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("failed to parse part as JSON object: %v", err)
+	}
+	gObj, err := gabs.Consume(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	key := map[string]*dynamodb.AttributeValue{}
+	keyAttrs := [][2]string{{d.conf.HashKey, d.hashKeyPath}}
+	if len(d.conf.RangeKey) > 0 {
+		keyAttrs = append(keyAttrs, [2]string{d.conf.RangeKey, d.rangeKeyPath})
+	}
+	for _, attr := range keyAttrs {
+		v := gObj.Path(attr[1]).Data()
+		if v == nil {
+			return nil, fmt.Errorf("key path '%v' not found", attr[1])
+		}
+		if key[attr[0]], err = attribute.FromJSON(v); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := d.dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(d.conf.Table),
+		Key:            key,
+		ConsistentRead: aws.Bool(d.conf.ConsistentRead),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch item: %v", err)
+	}
+	if len(res.Item) == 0 {
+		return nil, nil
+	}
+
+	item := attribute.MapToJSON(res.Item)
+	if len(d.conf.TargetPath) == 0 {
+		for k, v := range item {
+			obj[k] = v
+		}
+	} else {
+		gObj.SetP(item, d.conf.TargetPath)
+	}
+	return json.Marshal(obj)
+}
+
+// ProcessMessage takes a message, attempts to enrich parts of the message with
+// items from a DynamoDB table and returns the result.
+func (d *AmazonDynamoDB) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	d.stats.Incr("processor.amazon_dynamodb.count", 1)
+
+	newMsg := types.NewMessage(nil)
+	lParts := msg.Len()
+
+	noParts := len(d.conf.Parts) == 0
+	for i, part := range msg.GetAll() {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, t := range d.conf.Parts {
+				if t == nI || t == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			newMsg.Append(part)
+			continue
+		}
+		newPart, err := d.enrich(part)
+		if err != nil {
+			d.log.Errorf("Failed to enrich message part: %v\n", err)
+			d.stats.Incr("processor.amazon_dynamodb.error", 1)
+			newMsg.Append(part)
+		} else if newPart == nil {
+			d.stats.Incr("processor.amazon_dynamodb.not_found", 1)
+			newMsg.Append(part)
+		} else {
+			d.stats.Incr("processor.amazon_dynamodb.success", 1)
+			newMsg.Append(newPart)
+		}
+	}
+
+	d.stats.Incr("processor.amazon_dynamodb.sent", 1)
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,152 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+// dynamoDBTestHandler is a minimal DynamoDB stand-in that serves GetItem
+// requests for a table with the partition key "id".
+func dynamoDBTestHandler(items map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			TableName string
+			Key       map[string]map[string]string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		if item, exists := items[req.Key["id"]["S"]]; exists {
+			w.Write([]byte(`{"Item":` + item + `}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}
+}
+
+func newDynamoDBTestConfig(url string) Config {
+	conf := NewConfig()
+	conf.Type = "amazon_dynamodb"
+	conf.AmazonDynamoDB.Endpoint = url
+	conf.AmazonDynamoDB.Credentials.ID = "id"
+	conf.AmazonDynamoDB.Credentials.Secret = "secret"
+	conf.AmazonDynamoDB.Table = "foo"
+	return conf
+}
+
+func TestAmazonDynamoDBBadConfig(t *testing.T) {
+	testLog := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	if _, err := NewAmazonDynamoDB(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from empty table")
+	}
+}
+
+func TestAmazonDynamoDBEnrich(t *testing.T) {
+	server := httptest.NewServer(dynamoDBTestHandler(map[string]string{
+		"a": `{"id":{"S":"a"},"name":{"S":"foo"},"age":{"N":"12345678901234567890"}}`,
+	}))
+	defer server.Close()
+
+	testLog := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := newDynamoDBTestConfig(server.URL)
+	conf.AmazonDynamoDB.Parts = []int{0, -1}
+	proc, err := NewAmazonDynamoDB(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte(`{"id":"a","value":1}`),
+		[]byte(`{"id":"a","value":2}`),
+		[]byte(`{"id":"b","value":3}`),
+	}
+	exp := [][]byte{
+		[]byte(`{"age":12345678901234567890,"id":"a","name":"foo","value":1}`),
+		[]byte(`{"id":"a","value":2}`),
+		[]byte(`{"id":"b","value":3}`),
+	}
+
+	msgs, res := proc.ProcessMessage(types.NewMessage(input))
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	for i, part := range msgs[0].GetAll() {
+		if exp, act := string(exp[i]), string(part); exp != act {
+			t.Errorf("Wrong part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestAmazonDynamoDBEnrichTargetPath(t *testing.T) {
+	server := httptest.NewServer(dynamoDBTestHandler(map[string]string{
+		"a": `{"id":{"S":"a"},"tags":{"SS":["x","y"]}}`,
+	}))
+	defer server.Close()
+
+	testLog := log.NewLogger(os.Stdout, log.LoggerConfig{LogLevel: "NONE"})
+
+	conf := newDynamoDBTestConfig(server.URL)
+	conf.AmazonDynamoDB.HashKeyPath = "user.id"
+	conf.AmazonDynamoDB.TargetPath = "user.profile"
+	proc, err := NewAmazonDynamoDB(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte(`{"user":{"id":"a"}}`),
+		[]byte(`not json`),
+	}
+	exp := [][]byte{
+		[]byte(`{"user":{"id":"a","profile":{"id":"a","tags":["x","y"]}}}`),
+		[]byte(`not json`),
+	}
+
+	msgs, res := proc.ProcessMessage(types.NewMessage(input))
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	for i, part := range msgs[0].GetAll() {
+		if exp, act := string(exp[i]), string(part); exp != act {
+			t.Errorf("Wrong part %v: %v != %v", i, act, exp)
+		}
+	}
+}
@@ -51,39 +51,41 @@ var Constructors = map[string]TypeSpec{}
 
 // Config is the all encompassing configuration struct for all processor types.
 type Config struct {
-	Type        string            `json:"type" yaml:"type"`
-	Archive     ArchiveConfig     `json:"archive" yaml:"archive"`
-	BoundsCheck BoundsCheckConfig `json:"bounds_check" yaml:"bounds_check"`
-	Combine     CombineConfig     `json:"combine" yaml:"combine"`
-	Compress    CompressConfig    `json:"compress" yaml:"compress"`
-	Condition   ConditionConfig   `json:"condition" yaml:"condition"`
-	Decompress  DecompressConfig  `json:"decompress" yaml:"decompress"`
-	HashSample  HashSampleConfig  `json:"hash_sample" yaml:"hash_sample"`
-	InsertPart  InsertPartConfig  `json:"insert_part" yaml:"insert_part"`
-	Sample      SampleConfig      `json:"sample" yaml:"sample"`
-	SelectParts SelectPartsConfig `json:"select_parts" yaml:"select_parts"`
-	SetJSON     SetJSONConfig     `json:"set_json" yaml:"set_json"`
-	Split       struct{}          `json:"split" yaml:"split"`
-	Unarchive   UnarchiveConfig   `json:"unarchive" yaml:"unarchive"`
+	Type           string               `json:"type" yaml:"type"`
+	AmazonDynamoDB AmazonDynamoDBConfig `json:"amazon_dynamodb" yaml:"amazon_dynamodb"`
+	Archive        ArchiveConfig        `json:"archive" yaml:"archive"`
+	BoundsCheck    BoundsCheckConfig    `json:"bounds_check" yaml:"bounds_check"`
+	Combine        CombineConfig        `json:"combine" yaml:"combine"`
+	Compress       CompressConfig       `json:"compress" yaml:"compress"`
+	Condition      ConditionConfig      `json:"condition" yaml:"condition"`
+	Decompress     DecompressConfig     `json:"decompress" yaml:"decompress"`
+	HashSample     HashSampleConfig     `json:"hash_sample" yaml:"hash_sample"`
+	InsertPart     InsertPartConfig     `json:"insert_part" yaml:"insert_part"`
+	Sample         SampleConfig         `json:"sample" yaml:"sample"`
+	SelectParts    SelectPartsConfig    `json:"select_parts" yaml:"select_parts"`
+	SetJSON        SetJSONConfig        `json:"set_json" yaml:"set_json"`
+	Split          struct{}             `json:"split" yaml:"split"`
+	Unarchive      UnarchiveConfig      `json:"unarchive" yaml:"unarchive"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:        "bounds_check",
-		Archive:     NewArchiveConfig(),
-		BoundsCheck: NewBoundsCheckConfig(),
-		Combine:     NewCombineConfig(),
-		Compress:    NewCompressConfig(),
-		Condition:   NewConditionConfig(),
-		Decompress:  NewDecompressConfig(),
-		HashSample:  NewHashSampleConfig(),
-		InsertPart:  NewInsertPartConfig(),
-		Sample:      NewSampleConfig(),
-		SelectParts: NewSelectPartsConfig(),
-		SetJSON:     NewSetJSONConfig(),
-		Split:       struct{}{},
-		Unarchive:   NewUnarchiveConfig(),
+		Type:           "bounds_check",
+		AmazonDynamoDB: NewAmazonDynamoDBConfig(),
+		Archive:        NewArchiveConfig(),
+		BoundsCheck:    NewBoundsCheckConfig(),
+		Combine:        NewCombineConfig(),
+		Compress:       NewCompressConfig(),
+		Condition:      NewConditionConfig(),
+		Decompress:     NewDecompressConfig(),
+		HashSample:     NewHashSampleConfig(),
+		InsertPart:     NewInsertPartConfig(),
+		Sample:         NewSampleConfig(),
+		SelectParts:    NewSelectPartsConfig(),
+		SetJSON:        NewSetJSONConfig(),
+		Split:          struct{}{},
+		Unarchive:      NewUnarchiveConfig(),
 	}
 }
 
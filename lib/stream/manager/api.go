@@ -0,0 +1,201 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/api"
+	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/gorilla/mux"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// Stream statuses reported by the HTTP API.
+const (
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+)
+
+// registerEndpoints registers the HTTP endpoints for managing streams.
+func (m *Type) registerEndpoints(mgr types.Manager) {
+	m.dynAPI.OnUpdate(m.handleUpdate)
+	m.dynAPI.OnDelete(m.handleDelete)
+
+	mgr.RegisterEndpoint(
+		"/streams",
+		"Get a map of stream ids to their status, uptime and configuration.",
+		m.handleList,
+	)
+	mgr.RegisterEndpoint(
+		"/streams/{id}",
+		"Perform CRUD operations on streams. A POST request with a JSON or YAML"+
+			" config creates or replaces a stream, GET returns the config of a"+
+			" stream and DELETE gracefully stops and removes a stream.",
+		m.handleCRUD,
+	)
+}
+
+// handleCRUD serves the config of a stream for GET requests, and delegates
+// all other requests to the dynamic CRUD API.
+func (m *Type) handleCRUD(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		// A stream that stopped by itself is recreated by any update, even
+		// when its config is unchanged.
+		if id := mux.Vars(r)["id"]; m.isStopped(id) {
+			m.dynAPI.Reset(id)
+		}
+		m.dynAPI.HandleCRUD(w, r)
+		return
+	}
+	if r.Body != nil {
+		r.Body.Close()
+	}
+
+	id := mux.Vars(r)["id"]
+	conf, err := m.Read(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Stream '%v' does not exist", id), http.StatusNotFound)
+		return
+	}
+
+	sConf, err := conf.Sanitised()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusBadGateway)
+		return
+	}
+	resBytes, err := json.Marshal(sConf)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusBadGateway)
+		return
+	}
+	w.Write(resBytes)
+}
+
+// handleUpdate parses a stream config from a JSON or YAML body and replaces
+// any existing stream of the same id with a new stream. If the new stream
+// cannot be created then the existing stream is recreated from its previous
+// config.
+func (m *Type) handleUpdate(id string, confBytes []byte) error {
+	conf := stream.NewConfig()
+	if err := yaml.Unmarshal(confBytes, &conf); err != nil {
+		return api.ErrStatus{
+			Code: http.StatusBadRequest,
+			Err:  fmt.Errorf("failed to parse config: %v", err),
+		}
+	}
+
+	prevConf, prevErr := m.Read(id)
+	if err := m.Delete(id, m.apiTimeout); err != nil && err != ErrStreamDoesNotExist {
+		return err
+	}
+	if err := m.Create(id, conf); err != nil {
+		if err == types.ErrTypeClosed {
+			return api.ErrStatus{Code: http.StatusServiceUnavailable, Err: err}
+		}
+		if prevErr == nil {
+			if rErr := m.Create(id, prevConf); rErr != nil {
+				m.log.Errorf("Failed to restore stream '%v': %v\n", id, rErr)
+			}
+		}
+		return api.ErrStatus{
+			Code: http.StatusBadRequest,
+			Err:  fmt.Errorf("failed to create stream: %v", err),
+		}
+	}
+	return nil
+}
+
+// isStopped returns true if a stream exists and has stopped.
+func (m *Type) isStopped(id string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	info, exists := m.streams[id]
+	if !exists {
+		return false
+	}
+	select {
+	case <-info.strm.Done():
+		return true
+	default:
+	}
+	return false
+}
+
+// handleDelete gracefully stops and removes a stream.
+func (m *Type) handleDelete(id string) error {
+	err := m.Delete(id, m.apiTimeout)
+	if err == ErrStreamDoesNotExist {
+		return api.ErrStatus{Code: http.StatusNotFound, Err: err}
+	}
+	return err
+}
+
+// handleList writes a map of stream ids to their status, uptime and
+// configuration.
+func (m *Type) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+
+	type streamStatus struct {
+		Status string      `json:"status"`
+		Uptime string      `json:"uptime"`
+		Config interface{} `json:"config"`
+	}
+	statuses := map[string]streamStatus{}
+
+	m.lock.Lock()
+	for id, info := range m.streams {
+		status := StatusRunning
+		select {
+		case <-info.strm.Done():
+			status = StatusStopped
+		default:
+		}
+
+		sConf, err := info.strm.Config().Sanitised()
+		if err != nil {
+			m.log.Errorf("Failed to sanitise config: %v\n", err)
+		}
+		statuses[id] = streamStatus{
+			Status: status,
+			Uptime: time.Since(info.created).String(),
+			Config: sConf,
+		}
+	}
+	m.lock.Unlock()
+
+	resBytes, err := json.Marshal(statuses)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusBadGateway)
+		return
+	}
+	w.Write(resBytes)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,255 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func doRequest(mgr *mockManager, verb, path string, body []byte) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(verb, path, bytes.NewReader(body))
+	response := httptest.NewRecorder()
+	mgr.router.ServeHTTP(response, request)
+	return response
+}
+
+func TestManagerAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_manager_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mgr := newMockManager()
+	m := New(
+		mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{},
+		OptSetAPITimeout(time.Second*5),
+	)
+	defer m.Stop(time.Second * 5)
+
+	yamlConf := []byte(`
+input:
+  type: http_server
+buffer:
+  type: memory
+output:
+  type: file
+  file:
+    path: ` + filepath.Join(dir, "out.txt") + `
+`)
+
+	response := doRequest(mgr, "POST", "/streams/foo", yamlConf)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v: %s", act, exp, response.Body.Bytes())
+	}
+
+	response = doRequest(mgr, "GET", "/streams/foo", nil)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+	conf := map[string]interface{}{}
+	if err = json.Unmarshal(response.Body.Bytes(), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := conf["input"]; !exists {
+		t.Errorf("Unexpected config: %s", response.Body.Bytes())
+	}
+
+	response = doRequest(mgr, "GET", "/streams/bar", nil)
+	if exp, act := http.StatusNotFound, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+
+	// Validation errors are returned with details.
+	response = doRequest(mgr, "POST", "/streams/bar", []byte(`{"buffer":{"type":"nope"}}`))
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+	if !strings.Contains(response.Body.String(), "buffer type was not recognised") {
+		t.Errorf("Unexpected response body: %s", response.Body.Bytes())
+	}
+
+	response = doRequest(mgr, "POST", "/streams/bar", []byte(`{"input":`))
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+
+	response = doRequest(mgr, "GET", "/streams", nil)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+	statuses := map[string]struct {
+		Status string `json:"status"`
+		Uptime string `json:"uptime"`
+	}{}
+	if err = json.Unmarshal(response.Body.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(statuses); exp != act {
+		t.Errorf("Wrong count of streams: %v != %v", act, exp)
+	}
+	if exp, act := StatusRunning, statuses["foo"].Status; exp != act {
+		t.Errorf("Wrong stream status: %v != %v", act, exp)
+	}
+	if len(statuses["foo"].Uptime) == 0 {
+		t.Error("Expected stream uptime")
+	}
+
+	response = doRequest(mgr, "DELETE", "/streams/foo", nil)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+	response = doRequest(mgr, "DELETE", "/streams/foo", nil)
+	if exp, act := http.StatusNotFound, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+	if exp, act := 0, len(m.List()); exp != act {
+		t.Errorf("Wrong count of streams: %v != %v", act, exp)
+	}
+}
+
+func TestManagerAPIInvalidUpdate(t *testing.T) {
+	mgr := newMockManager()
+	m := New(
+		mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{},
+		OptSetAPITimeout(time.Second*5),
+	)
+	defer m.Stop(time.Second * 5)
+
+	response := doRequest(mgr, "POST", "/streams/foo", []byte(`
+input:
+  type: http_server
+buffer:
+  type: memory
+`))
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected response code: %v != %v: %s", act, exp, response.Body.Bytes())
+	}
+
+	response = doRequest(mgr, "POST", "/streams/foo", []byte(`{"buffer":{"type":"nope"}}`))
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+
+	conf, err := m.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "http_server", conf.Input.Type; exp != act {
+		t.Errorf("Wrong input type: %v != %v", act, exp)
+	}
+	if exp, act := "memory", conf.Buffer.Type; exp != act {
+		t.Errorf("Wrong buffer type: %v != %v", act, exp)
+	}
+
+	response = doRequest(mgr, "GET", "/streams", nil)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected response code: %v != %v", act, exp)
+	}
+	statuses := map[string]struct {
+		Status string `json:"status"`
+	}{}
+	if err = json.Unmarshal(response.Body.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := StatusRunning, statuses["foo"].Status; exp != act {
+		t.Errorf("Wrong stream status: %v != %v", act, exp)
+	}
+}
+
+func TestManagerAPIRestartStopped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_manager_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join(dir, "in.txt")
+	if err = ioutil.WriteFile(inPath, []byte("foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := newMockManager()
+	m := New(
+		mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{},
+		OptSetAPITimeout(time.Second*5),
+	)
+	defer m.Stop(time.Second * 5)
+
+	// The stream stops by itself once the file has been read.
+	yamlConf := []byte(`
+input:
+  type: file
+  file:
+    path: ` + inPath + `
+output:
+  type: file
+  file:
+    path: ` + filepath.Join(dir, "out.txt") + `
+`)
+
+	response := doRequest(mgr, "POST", "/streams/foo", yamlConf)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected response code: %v != %v: %s", act, exp, response.Body.Bytes())
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for !m.isStopped("foo") {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for stream to stop")
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+
+	m.lock.Lock()
+	prevStream := m.streams["foo"].strm
+	m.lock.Unlock()
+
+	response = doRequest(mgr, "POST", "/streams/foo", yamlConf)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected response code: %v != %v: %s", act, exp, response.Body.Bytes())
+	}
+
+	m.lock.Lock()
+	info, exists := m.streams["foo"]
+	m.lock.Unlock()
+	if !exists {
+		t.Fatal("Stream was not recreated")
+	}
+	if info.strm == prevStream {
+		t.Error("Stopped stream was not recreated by identical config")
+	}
+}
+
+//------------------------------------------------------------------------------
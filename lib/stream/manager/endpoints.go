@@ -0,0 +1,111 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package manager
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// endpointRouter registers the HTTP endpoints of streams under a prefix
+// containing the stream id. Endpoints are only registered with the underlying
+// manager once, and the handler of an endpoint is swapped when a stream is
+// recreated, since HTTP routes cannot be removed from the server.
+type endpointRouter struct {
+	mgr types.Manager
+
+	handlers   map[string]http.HandlerFunc
+	registered map[string]struct{}
+	sync.RWMutex
+}
+
+func newEndpointRouter(mgr types.Manager) *endpointRouter {
+	return &endpointRouter{
+		mgr:        mgr,
+		handlers:   map[string]http.HandlerFunc{},
+		registered: map[string]struct{}{},
+	}
+}
+
+// streamPrefix returns the HTTP path prefix of the endpoints of a stream.
+func streamPrefix(id string) string {
+	return "/streams/" + id
+}
+
+// forStream returns a types.Manager for the components of a stream.
+func (e *endpointRouter) forStream(id string) types.Manager {
+	return streamEndpoints{id: id, router: e}
+}
+
+func (e *endpointRouter) register(path, desc string, h http.HandlerFunc) {
+	e.Lock()
+	_, exists := e.registered[path]
+	e.handlers[path] = h
+	e.registered[path] = struct{}{}
+	e.Unlock()
+
+	if exists {
+		return
+	}
+	e.mgr.RegisterEndpoint(path, desc, func(w http.ResponseWriter, r *http.Request) {
+		e.RLock()
+		handler, ok := e.handlers[path]
+		e.RUnlock()
+
+		if !ok {
+			http.Error(w, "Stream not found", http.StatusNotFound)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// removeStream removes the handlers of all endpoints of a stream.
+func (e *endpointRouter) removeStream(id string) {
+	prefix := streamPrefix(id) + "/"
+
+	e.Lock()
+	for path := range e.handlers {
+		if strings.HasPrefix(path, prefix) {
+			delete(e.handlers, path)
+		}
+	}
+	e.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// streamEndpoints implements types.Manager for a single stream.
+type streamEndpoints struct {
+	id     string
+	router *endpointRouter
+}
+
+// RegisterEndpoint registers an HTTP endpoint under the prefix of the stream.
+func (s streamEndpoints) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	s.router.register(streamPrefix(s.id)+path, desc, h)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,212 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package manager
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/api"
+	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// Errors specifically returned by a stream manager.
+var (
+	ErrStreamExists       = errors.New("stream already exists")
+	ErrStreamDoesNotExist = errors.New("stream does not exist")
+	ErrInvalidStreamID    = errors.New("stream id must be non-empty and must not contain '/'")
+)
+
+//------------------------------------------------------------------------------
+
+// streamInfo contains a running stream and the time at which it was created.
+type streamInfo struct {
+	strm    *stream.Type
+	created time.Time
+}
+
+// Type manages a collection of named streams, each with its own input, buffer
+// and output, that share the HTTP server, logger and metrics aggregator of the
+// process.
+//
+// The metrics of each stream are prefixed with 'stream.<id>', log messages are
+// prefixed with '.stream.<id>' and the HTTP endpoints registered by stream
+// components are prefixed with '/streams/<id>'.
+type Type struct {
+	streams   map[string]streamInfo
+	endpoints *endpointRouter
+	closed    bool
+
+	dynAPI     *api.Dynamic
+	apiTimeout time.Duration
+
+	log   log.Modular
+	stats metrics.Type
+
+	lock sync.Mutex
+}
+
+// New creates a new stream manager and registers HTTP endpoints for managing
+// streams at runtime with mgr.
+func New(mgr types.Manager, log log.Modular, stats metrics.Type, opts ...func(*Type)) *Type {
+	m := &Type{
+		streams:    map[string]streamInfo{},
+		endpoints:  newEndpointRouter(mgr),
+		dynAPI:     api.NewDynamic(),
+		apiTimeout: time.Second * 5,
+		log:        log,
+		stats:      stats,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.registerEndpoints(mgr)
+	return m
+}
+
+//------------------------------------------------------------------------------
+
+// OptSetAPITimeout sets the maximum time that a request to the HTTP API waits
+// for a stream to gracefully shut down when it is updated or deleted.
+func OptSetAPITimeout(tout time.Duration) func(*Type) {
+	return func(m *Type) {
+		m.apiTimeout = tout
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Create attempts to create and run a new stream under a unique id.
+func (m *Type) Create(id string, conf stream.Config) error {
+	if len(id) == 0 || strings.Contains(id, "/") {
+		return ErrInvalidStreamID
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return types.ErrTypeClosed
+	}
+	if _, exists := m.streams[id]; exists {
+		return ErrStreamExists
+	}
+
+	s, err := stream.New(
+		conf,
+		m.endpoints.forStream(id),
+		m.log.NewModule(".stream."+id),
+		metrics.NewNamespaced(m.stats, "stream."+id),
+	)
+	if err != nil {
+		m.endpoints.removeStream(id)
+		return err
+	}
+
+	m.streams[id] = streamInfo{
+		strm:    s,
+		created: time.Now(),
+	}
+
+	m.log.Infof("Created stream: %v\n", id)
+	return nil
+}
+
+// Read returns the configuration of a stream.
+func (m *Type) Read(id string) (stream.Config, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	info, exists := m.streams[id]
+	if !exists {
+		return stream.Config{}, ErrStreamDoesNotExist
+	}
+	return info.strm.Config(), nil
+}
+
+// List returns the ids of all streams.
+func (m *Type) List() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ids := make([]string, 0, len(m.streams))
+	for id := range m.streams {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Delete attempts to gracefully stop and remove a stream by its id within the
+// specified timeout period.
+func (m *Type) Delete(id string, timeout time.Duration) error {
+	m.lock.Lock()
+	info, exists := m.streams[id]
+	if exists {
+		delete(m.streams, id)
+	}
+	m.lock.Unlock()
+
+	if !exists {
+		return ErrStreamDoesNotExist
+	}
+
+	err := info.strm.Stop(timeout)
+	m.endpoints.removeStream(id)
+	m.log.Infof("Deleted stream: %v\n", id)
+	return err
+}
+
+// Stop attempts to gracefully shut down all streams within the specified
+// timeout period, after which no further streams can be created.
+func (m *Type) Stop(timeout time.Duration) error {
+	m.lock.Lock()
+	m.closed = true
+	streams := m.streams
+	m.streams = map[string]streamInfo{}
+	m.lock.Unlock()
+
+	errChan := make(chan error, len(streams))
+	for id, info := range streams {
+		go func(id string, s *stream.Type) {
+			err := s.Stop(timeout)
+			if err != nil {
+				m.log.Errorf("Failed to stop stream '%v': %v\n", id, err)
+			}
+			errChan <- err
+		}(id, info.strm)
+	}
+
+	var err error
+	for range streams {
+		if sErr := <-errChan; sErr != nil {
+			err = sErr
+		}
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
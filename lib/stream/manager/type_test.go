@@ -0,0 +1,167 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package manager
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/stream"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	"github.com/gorilla/mux"
+)
+
+//------------------------------------------------------------------------------
+
+var logConfig = log.LoggerConfig{
+	LogLevel: "NONE",
+}
+
+type mockManager struct {
+	endpoints map[string]http.HandlerFunc
+	router    *mux.Router
+}
+
+func (m *mockManager) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	if _, exists := m.endpoints[path]; exists {
+		panic("endpoint registered twice: " + path)
+	}
+	m.endpoints[path] = h
+	m.router.HandleFunc(path, h)
+}
+
+func newMockManager() *mockManager {
+	return &mockManager{
+		endpoints: map[string]http.HandlerFunc{},
+		router:    mux.NewRouter(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// newFileStreamConfig returns a stream config that reads from a file that will
+// never be written to, and therefore runs until closed.
+func newFileStreamConfig(t *testing.T, dir string) stream.Config {
+	inFile, err := ioutil.TempFile(dir, "in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inFile.Close()
+
+	conf := stream.NewConfig()
+	conf.Input.Type = "file"
+	conf.Input.File.Path = inFile.Name()
+	conf.Buffer.Type = "memory"
+	conf.Output.Type = "file"
+	conf.Output.File.Path = filepath.Join(dir, "out.txt")
+	return conf
+}
+
+func TestManagerCRUD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_manager_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mgr := newMockManager()
+	m := New(mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+
+	if err = m.Create("", newFileStreamConfig(t, dir)); err != ErrInvalidStreamID {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+	if err = m.Create("foo", newFileStreamConfig(t, dir)); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Create("bar", newFileStreamConfig(t, dir)); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Create("foo", newFileStreamConfig(t, dir)); err != ErrStreamExists {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+
+	ids := m.List()
+	sort.Strings(ids)
+	if exp := []string{"bar", "foo"}; !reflect.DeepEqual(exp, ids) {
+		t.Errorf("Wrong stream ids: %v != %v", ids, exp)
+	}
+
+	if _, err = m.Read("foo"); err != nil {
+		t.Error(err)
+	}
+	if _, err = m.Read("baz"); err != ErrStreamDoesNotExist {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+
+	handler, exists := mgr.endpoints["/streams/foo/buffer/stats"]
+	if !exists {
+		t.Fatal("Stream endpoint was not registered")
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/streams/foo/buffer/stats", nil))
+	if exp, act := http.StatusOK, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+
+	if err = m.Delete("foo", time.Second); err != nil {
+		t.Error(err)
+	}
+	if err = m.Delete("foo", time.Second); err != ErrStreamDoesNotExist {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/streams/foo/buffer/stats", nil))
+	if exp, act := http.StatusNotFound, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+
+	// Recreating a stream must not register its endpoints a second time.
+	if err = m.Create("foo", newFileStreamConfig(t, dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/streams/foo/buffer/stats", nil))
+	if exp, act := http.StatusOK, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+
+	if err = m.Stop(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+	if exp, act := 0, len(m.List()); exp != act {
+		t.Errorf("Wrong count of streams: %v != %v", act, exp)
+	}
+	if err = m.Create("baz", newFileStreamConfig(t, dir)); err == nil {
+		t.Error("Expected error after stop")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,226 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package stream
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/buffer"
+	"github.com/Jeffail/benthos/lib/input"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util"
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// Config is a configuration struct for a single Benthos stream.
+type Config struct {
+	Input  input.Config  `json:"input" yaml:"input"`
+	Buffer buffer.Config `json:"buffer" yaml:"buffer"`
+	Output output.Config `json:"output" yaml:"output"`
+}
+
+// NewConfig returns a configuration struct fully populated with default values.
+func NewConfig() Config {
+	return Config{
+		Input:  input.NewConfig(),
+		Buffer: buffer.NewConfig(),
+		Output: output.NewConfig(),
+	}
+}
+
+// Sanitised returns a sanitised copy of the stream configuration, meaning
+// fields of no consequence (unused inputs, outputs, processors etc) are
+// excluded.
+func (c Config) Sanitised() (interface{}, error) {
+	inConf, err := input.SanitiseConfig(c.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	var bufConf interface{}
+	bufConf, err = buffer.SanitiseConfig(c.Buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	var outConf interface{}
+	outConf, err = output.SanitiseConfig(c.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct {
+		Input  interface{} `json:"input" yaml:"input"`
+		Buffer interface{} `json:"buffer" yaml:"buffer"`
+		Output interface{} `json:"output" yaml:"output"`
+	}{
+		Input:  inConf,
+		Buffer: bufConf,
+		Output: outConf,
+	}, nil
+}
+
+// UnmarshalJSON ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (c *Config) UnmarshalJSON(bytes []byte) error {
+	type confAlias Config
+	aliased := confAlias(NewConfig())
+
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+
+	*c = Config(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias Config
+	aliased := confAlias(NewConfig())
+
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+
+	*c = Config(aliased)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Type creates and manages the lifetime of a Benthos stream.
+type Type struct {
+	conf Config
+
+	inputLayer  input.Type
+	bufferLayer buffer.Type
+	outputLayer output.Type
+
+	// Two pools help manage the ordered closure of all stream components. The
+	// tiered pool (t1) closes components in order, if this fails within the
+	// allotted time period then the non-tiered pool (t2) attempts to close all
+	// components at once.
+	poolTiered    *util.ClosablePool
+	poolNonTiered *util.ClosablePool
+
+	doneChan chan struct{}
+
+	log log.Modular
+}
+
+// New creates a new stream from a configuration, where components of the
+// stream register their HTTP endpoints with mgr.
+func New(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (*Type, error) {
+	t := &Type{
+		conf:          conf,
+		poolTiered:    util.NewClosablePool(),
+		poolNonTiered: util.NewClosablePool(),
+		doneChan:      make(chan struct{}),
+		log:           log,
+	}
+
+	var err error
+	if t.inputLayer, err = input.New(conf.Input, mgr, log, stats); err != nil {
+		log.Errorf("Input error (%s): %v\n", conf.Input.Type, err)
+		return nil, err
+	}
+	t.poolTiered.Add(1, t.inputLayer)
+	t.poolNonTiered.Add(0, t.inputLayer)
+
+	if t.bufferLayer, err = buffer.New(conf.Buffer, mgr, log, stats); err != nil {
+		log.Errorf("Buffer error (%s): %v\n", conf.Buffer.Type, err)
+		t.abort()
+		return nil, err
+	}
+	t.poolTiered.Add(3, t.bufferLayer)
+	t.poolNonTiered.Add(0, t.bufferLayer)
+
+	if t.outputLayer, err = output.New(conf.Output, mgr, log, stats); err != nil {
+		log.Errorf("Output error (%s): %v\n", conf.Output.Type, err)
+		t.abort()
+		return nil, err
+	}
+	t.poolTiered.Add(10, t.outputLayer)
+	t.poolNonTiered.Add(0, t.outputLayer)
+
+	if err = t.outputLayer.StartReceiving(t.bufferLayer.TransactionChan()); err != nil {
+		t.abort()
+		return nil, err
+	}
+	if err = t.bufferLayer.StartReceiving(t.inputLayer.TransactionChan()); err != nil {
+		t.abort()
+		return nil, err
+	}
+
+	// If our output closes down then the stream is finished.
+	go func() {
+		for {
+			if err := t.outputLayer.WaitForClose(time.Second * 60); err == nil {
+				close(t.doneChan)
+				return
+			}
+		}
+	}()
+
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+
+// abort closes any components that were created before a failure during
+// construction.
+func (t *Type) abort() {
+	t.poolNonTiered.Close(time.Second)
+}
+
+// Config returns the configuration the stream was created with.
+func (t *Type) Config() Config {
+	return t.conf
+}
+
+// Done returns a channel that is closed once the output of the stream has
+// closed, at which point the stream is no longer processing messages.
+func (t *Type) Done() <-chan struct{} {
+	return t.doneChan
+}
+
+// Stop attempts to close the stream within the specified timeout period.
+// Components are first closed in order so that in flight messages can reach
+// their destination. If this fails then all components are closed at once,
+// and an error is returned if that also fails.
+func (t *Type) Stop(timeout time.Duration) error {
+	if err := t.poolTiered.Close(timeout / 2); err == nil {
+		return nil
+	}
+	t.log.Warnln(
+		"Stream failed to close using ordered tiers, you may receive a duplicate " +
+			"message on the next start.",
+	)
+	return t.poolNonTiered.Close(timeout / 2)
+}
+
+//------------------------------------------------------------------------------
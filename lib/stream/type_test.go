@@ -0,0 +1,167 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package stream
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/util/service/log"
+	"github.com/Jeffail/benthos/lib/util/service/metrics"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//------------------------------------------------------------------------------
+
+var logConfig = log.LoggerConfig{
+	LogLevel: "NONE",
+}
+
+type mockManager struct {
+	endpoints map[string]http.HandlerFunc
+}
+
+func (m *mockManager) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	m.endpoints[path] = h
+}
+
+//------------------------------------------------------------------------------
+
+func TestConfigDefaults(t *testing.T) {
+	streams := map[string]Config{}
+	if err := yaml.Unmarshal([]byte(`
+foo:
+  input:
+    type: file
+    file:
+      path: /tmp/foo.txt
+`), &streams); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := streams["foo"]
+	if exp, act := "/tmp/foo.txt", conf.Input.File.Path; exp != act {
+		t.Errorf("Wrong input path: %v != %v", exp, act)
+	}
+	if exp, act := NewConfig().Output.Type, conf.Output.Type; exp != act {
+		t.Errorf("Wrong default output type: %v != %v", exp, act)
+	}
+	if exp, act := NewConfig().Buffer.Memory.Limit, conf.Buffer.Memory.Limit; exp != act {
+		t.Errorf("Wrong default buffer limit: %v != %v", exp, act)
+	}
+}
+
+func TestStreamFileToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_stream_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath, outPath := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+	if err = ioutil.WriteFile(inPath, []byte("foo\nbar\nbaz\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.Input.Type = "file"
+	conf.Input.File.Path = inPath
+	conf.Buffer.Type = "memory"
+	conf.Output.Type = "file"
+	conf.Output.File.Path = outPath
+
+	mgr := &mockManager{endpoints: map[string]http.HandlerFunc{}}
+	s, err := New(conf, mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exists := mgr.endpoints["/buffer/stats"]; !exists {
+		t.Error("Buffer endpoints were not registered")
+	}
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for stream to finish")
+	}
+
+	if err = s.Stop(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	outBytes, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar\nbaz\n", string(outBytes); exp != act {
+		t.Errorf("Wrong output: %q != %q", act, exp)
+	}
+}
+
+func TestStreamSyncResponse(t *testing.T) {
+	conf := NewConfig()
+	conf.Input.Type = "http_server"
+	conf.Input.HTTPServer.SyncResponse.Enabled = true
+	conf.Buffer.Type = "none"
+	conf.Output.Type = "sync_response"
+
+	mgr := &mockManager{endpoints: map[string]http.HandlerFunc{}}
+	s, err := New(conf, mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop(time.Second)
+
+	handler, exists := mgr.endpoints["/post"]
+	if !exists {
+		t.Fatal("Input endpoint was not registered")
+	}
+
+	req := httptest.NewRequest("POST", "/post", bytes.NewBufferString("hello world"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if exp, act := http.StatusOK, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	if exp, act := "hello world", rec.Body.String(); exp != act {
+		t.Errorf("Wrong response body: %v != %v", act, exp)
+	}
+}
+
+func TestStreamBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Buffer.Type = "does_not_exist"
+
+	mgr := &mockManager{endpoints: map[string]http.HandlerFunc{}}
+	if _, err := New(conf, mgr, log.NewLogger(os.Stdout, logConfig), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad buffer type")
+	}
+}
+
+//------------------------------------------------------------------------------
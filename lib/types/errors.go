@@ -43,6 +43,9 @@ var (
 
 	ErrInvalidZMQType        = errors.New("invalid ZMQ socket type")
 	ErrInvalidScaleProtoType = errors.New("invalid Scalability Protocols socket type")
+	ErrInvalidWebsocketMode  = errors.New("websocket mode was not recognised")
+	ErrInvalidSocketNetwork  = errors.New("socket network was not recognised")
+	ErrInvalidSocketMode     = errors.New("socket mode was not recognised")
 
 	// ErrAlreadyStarted is returned when an input or output type gets started a
 	// second time.
@@ -59,7 +62,11 @@ var (
 
 // Buffer errors
 var (
-	ErrMessageTooLarge = errors.New("message body larger than buffer space")
+	ErrMessageTooLarge       = errors.New("message body larger than buffer space")
+	ErrInvalidOverflowPolicy = errors.New("buffer overflow policy was not recognised")
+
+	ErrInvalidPriorityOrdering = errors.New("priority buffer ordering was not recognised")
+	ErrNoPriorityQueues        = errors.New("priority buffer requires at least one queue")
 )
 
 //------------------------------------------------------------------------------
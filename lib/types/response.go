@@ -82,3 +82,32 @@ func NewUnacknowledgedResponse() UnacknowledgedResponse {
 }
 
 //------------------------------------------------------------------------------
+
+// MessageResponse is a successful response that carries a message back to the
+// source of a transaction, allowing inputs such as the HTTP server to reply to
+// requests with the result of processing.
+type MessageResponse struct {
+	msg Message
+}
+
+// Error returns the underlying error.
+func (m MessageResponse) Error() error { return nil }
+
+// SkipAck indicates whether a successful message should be acknowledged.
+func (m MessageResponse) SkipAck() bool {
+	return false
+}
+
+// Message returns the message carried by the response.
+func (m MessageResponse) Message() Message {
+	return m.msg
+}
+
+// NewMessageResponse returns a MessageResponse carrying msg.
+func NewMessageResponse(msg Message) MessageResponse {
+	return MessageResponse{
+		msg: msg,
+	}
+}
+
+//------------------------------------------------------------------------------
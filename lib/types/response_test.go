@@ -47,3 +47,18 @@ func TestUnackResponse(t *testing.T) {
 		t.Error("Should have received skip ack on unack response")
 	}
 }
+
+func TestMessageResponse(t *testing.T) {
+	msg := NewMessage([][]byte{[]byte("foo")})
+	res := NewMessageResponse(msg)
+
+	if res.Error() != nil {
+		t.Error(res.Error())
+	}
+	if res.SkipAck() {
+		t.Error("Should not received skip ack on message response")
+	}
+	if exp, act := "foo", string(res.Message().Get(0)); exp != act {
+		t.Errorf("Wrong message: %v != %v", exp, act)
+	}
+}
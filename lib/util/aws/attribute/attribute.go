@@ -0,0 +1,139 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package attribute
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+//------------------------------------------------------------------------------
+
+// FromJSON converts a JSON value, as decoded by a json.Decoder with UseNumber
+// set, into a DynamoDB attribute value. Numbers are converted without loss of
+// precision.
+func FromJSON(v interface{}) (*dynamodb.AttributeValue, error) {
+	switch t := v.(type) {
+	case nil:
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}, nil
+	case bool:
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(t)}, nil
+	case string:
+		return &dynamodb.AttributeValue{S: aws.String(t)}, nil
+	case json.Number:
+		return &dynamodb.AttributeValue{N: aws.String(t.String())}, nil
+	case float64:
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(t, 'f', -1, 64))}, nil
+	case []interface{}:
+		l := make([]*dynamodb.AttributeValue, 0, len(t))
+		for _, e := range t {
+			av, err := FromJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, av)
+		}
+		return &dynamodb.AttributeValue{L: l}, nil
+	case map[string]interface{}:
+		m, err := MapFromJSON(t)
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{M: m}, nil
+	}
+	return nil, fmt.Errorf("unsupported JSON type: %T", v)
+}
+
+// MapFromJSON converts a JSON object into a map of DynamoDB attribute values,
+// which is the form of a DynamoDB item.
+func MapFromJSON(obj map[string]interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	m := make(map[string]*dynamodb.AttributeValue, len(obj))
+	for k, v := range obj {
+		av, err := FromJSON(v)
+		if err != nil {
+			return nil, fmt.Errorf("field '%v': %v", k, err)
+		}
+		m[k] = av
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ToJSON converts a DynamoDB attribute value into a JSON value. Numbers are
+// converted into json.Number values and binary values into byte slices, which
+// are encoded as base64 strings.
+func ToJSON(av *dynamodb.AttributeValue) interface{} {
+	switch {
+	case av == nil:
+		return nil
+	case av.S != nil:
+		return *av.S
+	case av.N != nil:
+		return json.Number(*av.N)
+	case av.B != nil:
+		return av.B
+	case av.BOOL != nil:
+		return *av.BOOL
+	case av.M != nil:
+		return MapToJSON(av.M)
+	case av.L != nil:
+		l := make([]interface{}, 0, len(av.L))
+		for _, e := range av.L {
+			l = append(l, ToJSON(e))
+		}
+		return l
+	case av.SS != nil:
+		l := make([]interface{}, 0, len(av.SS))
+		for _, s := range av.SS {
+			l = append(l, aws.StringValue(s))
+		}
+		return l
+	case av.NS != nil:
+		l := make([]interface{}, 0, len(av.NS))
+		for _, n := range av.NS {
+			l = append(l, json.Number(aws.StringValue(n)))
+		}
+		return l
+	case av.BS != nil:
+		l := make([]interface{}, 0, len(av.BS))
+		for _, b := range av.BS {
+			l = append(l, b)
+		}
+		return l
+	}
+	return nil
+}
+
+// MapToJSON converts a map of DynamoDB attribute values into a JSON object.
+func MapToJSON(m map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	obj := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		obj[k] = ToJSON(v)
+	}
+	return obj
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,96 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package attribute
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `{"a":"foo","b":12345678901234567890,"c":1.5,"d":true,"e":null,"f":[1,"two",{"three":3}],"g":{"h":{"i":"j"}}}`
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(input)))
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := MapFromJSON(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "12345678901234567890", aws.StringValue(item["b"].N); exp != act {
+		t.Errorf("Wrong number attribute: %v != %v", act, exp)
+	}
+	if exp, act := "j", aws.StringValue(item["g"].M["h"].M["i"].S); exp != act {
+		t.Errorf("Wrong nested attribute: %v != %v", act, exp)
+	}
+
+	output, err := json.Marshal(MapToJSON(item))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := input, string(output); exp != act {
+		t.Errorf("Wrong round trip result: %v != %v", act, exp)
+	}
+}
+
+func TestFromJSONFloat(t *testing.T) {
+	av, err := FromJSON(float64(100000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "100000000", aws.StringValue(av.N); exp != act {
+		t.Errorf("Wrong number attribute: %v != %v", act, exp)
+	}
+}
+
+func TestToJSONSets(t *testing.T) {
+	av := &dynamodb.AttributeValue{
+		M: map[string]*dynamodb.AttributeValue{
+			"ss": {SS: []*string{aws.String("a"), aws.String("b")}},
+			"ns": {NS: []*string{aws.String("1"), aws.String("2.5")}},
+			"bs": {BS: [][]byte{[]byte("foo")}},
+			"b":  {B: []byte("bar")},
+		},
+	}
+
+	output, err := json.Marshal(ToJSON(av))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"b":"YmFy","bs":["Zm9v"],"ns":[1,2.5],"ss":["a","b"]}`, string(output); exp != act {
+		t.Errorf("Wrong JSON result: %v != %v", act, exp)
+	}
+}
+
+func TestFromJSONUnsupported(t *testing.T) {
+	if _, err := FromJSON(struct{}{}); err == nil {
+		t.Error("Expected error from unsupported type")
+	}
+}
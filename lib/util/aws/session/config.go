@@ -0,0 +1,121 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package session
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+//------------------------------------------------------------------------------
+
+// CredentialsConfig contains configuration params for AWS credentials.
+type CredentialsConfig struct {
+	Profile        string `json:"profile" yaml:"profile"`
+	ID             string `json:"id" yaml:"id"`
+	Secret         string `json:"secret" yaml:"secret"`
+	Token          string `json:"token" yaml:"token"`
+	Role           string `json:"role" yaml:"role"`
+	RoleExternalID string `json:"role_external_id" yaml:"role_external_id"`
+}
+
+// Config contains configuration params for creating an AWS session, and is
+// embedded within the configuration of each AWS component.
+type Config struct {
+	Region      string            `json:"region" yaml:"region"`
+	Endpoint    string            `json:"endpoint" yaml:"endpoint"`
+	Credentials CredentialsConfig `json:"credentials" yaml:"credentials"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		Region:   "eu-west-1",
+		Endpoint: "",
+		Credentials: CredentialsConfig{
+			Profile:        "",
+			ID:             "",
+			Secret:         "",
+			Token:          "",
+			Role:           "",
+			RoleExternalID: "",
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GetSession creates an AWS session from the config. Static credentials are
+// used when an ID is set, otherwise credentials are taken from the named
+// profile if set, or the default credential chain. When a role is set it is
+// assumed using those credentials. Options are applied to the AWS config of the
+// session after the fields of the config.
+func (c Config) GetSession(opts ...func(*aws.Config)) (*session.Session, error) {
+	awsConf := aws.NewConfig()
+	if len(c.Region) > 0 {
+		awsConf = awsConf.WithRegion(c.Region)
+	}
+	if len(c.Endpoint) > 0 {
+		awsConf = awsConf.WithEndpoint(c.Endpoint)
+	}
+	if len(c.Credentials.ID) > 0 {
+		awsConf = awsConf.WithCredentials(credentials.NewStaticCredentials(
+			c.Credentials.ID,
+			c.Credentials.Secret,
+			c.Credentials.Token,
+		))
+	}
+	for _, opt := range opts {
+		opt(awsConf)
+	}
+
+	sessOpts := session.Options{
+		Config: *awsConf,
+	}
+	if len(c.Credentials.Profile) > 0 {
+		sessOpts.Profile = c.Credentials.Profile
+		sessOpts.SharedConfigState = session.SharedConfigEnable
+	}
+
+	sess, err := session.NewSessionWithOptions(sessOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Credentials.Role) > 0 {
+		// The role is assumed through the default STS endpoint rather than a
+		// custom endpoint intended for the component.
+		stsSess := sess.Copy(&aws.Config{Endpoint: aws.String("")})
+		sess.Config.Credentials = stscreds.NewCredentials(
+			stsSess, c.Credentials.Role, func(p *stscreds.AssumeRoleProvider) {
+				if len(c.Credentials.RoleExternalID) > 0 {
+					p.ExternalID = aws.String(c.Credentials.RoleExternalID)
+				}
+			},
+		)
+	}
+
+	return sess, nil
+}
+
+//------------------------------------------------------------------------------
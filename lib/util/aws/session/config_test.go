@@ -0,0 +1,235 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package session
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// setEnv sets environment variables, where an empty value unsets the variable,
+// and returns a func that restores their previous values.
+func setEnv(vars map[string]string) func() {
+	restore := map[string]*string{}
+	for k, v := range vars {
+		if prev, exists := os.LookupEnv(k); exists {
+			restore[k] = &prev
+		} else {
+			restore[k] = nil
+		}
+		if len(v) > 0 {
+			os.Setenv(k, v)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+	return func() {
+		for k, v := range restore {
+			if v != nil {
+				os.Setenv(k, *v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}
+}
+
+func TestGetSessionStatic(t *testing.T) {
+	conf := NewConfig()
+	conf.Region = "us-east-1"
+	conf.Endpoint = "http://localhost:4572"
+	conf.Credentials.ID = "foo"
+	conf.Credentials.Secret = "bar"
+	conf.Credentials.Token = "baz"
+
+	sess, err := conf.GetSession(func(c *aws.Config) {
+		c.S3ForcePathStyle = aws.Bool(true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "us-east-1", aws.StringValue(sess.Config.Region); exp != act {
+		t.Errorf("Wrong region: %v != %v", act, exp)
+	}
+	if exp, act := "http://localhost:4572", aws.StringValue(sess.Config.Endpoint); exp != act {
+		t.Errorf("Wrong endpoint: %v != %v", act, exp)
+	}
+	if !aws.BoolValue(sess.Config.S3ForcePathStyle) {
+		t.Error("Option was not applied")
+	}
+
+	creds, err := sess.Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo", creds.AccessKeyID; exp != act {
+		t.Errorf("Wrong access key: %v != %v", act, exp)
+	}
+	if exp, act := "bar", creds.SecretAccessKey; exp != act {
+		t.Errorf("Wrong secret: %v != %v", act, exp)
+	}
+	if exp, act := "baz", creds.SessionToken; exp != act {
+		t.Errorf("Wrong token: %v != %v", act, exp)
+	}
+}
+
+func TestGetSessionProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_aws_session_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	credsPath := filepath.Join(dir, "credentials")
+	if err = ioutil.WriteFile(credsPath, []byte(`[default]
+aws_access_key_id = default_id
+aws_secret_access_key = default_secret
+
+[foo]
+aws_access_key_id = foo_id
+aws_secret_access_key = foo_secret
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer setEnv(map[string]string{
+		"AWS_SHARED_CREDENTIALS_FILE": credsPath,
+		"AWS_CONFIG_FILE":             filepath.Join(dir, "config"),
+	})()
+
+	conf := NewConfig()
+	conf.Credentials.Profile = "foo"
+
+	sess, err := conf.GetSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := sess.Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo_id", creds.AccessKeyID; exp != act {
+		t.Errorf("Wrong access key: %v != %v", act, exp)
+	}
+	if exp, act := "foo_secret", creds.SecretAccessKey; exp != act {
+		t.Errorf("Wrong secret: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestGetSessionRole(t *testing.T) {
+	// A custom CA bundle cannot be applied to the stubbed transport.
+	defer setEnv(map[string]string{"AWS_CA_BUNDLE": ""})()
+
+	var reqHost string
+	var reqForm url.Values
+
+	client := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			reqHost = req.URL.Host
+			if reqForm, err = url.ParseQuery(string(body)); err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/xml"}},
+				Body: ioutil.NopCloser(bytes.NewReader([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>role_id</AccessKeyId>
+      <SecretAccessKey>role_secret</SecretAccessKey>
+      <SessionToken>role_token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`))),
+				Request: req,
+			}, nil
+		}),
+	}
+
+	conf := NewConfig()
+	conf.Endpoint = "http://localhost:4572"
+	conf.Credentials.ID = "foo"
+	conf.Credentials.Secret = "bar"
+	conf.Credentials.Role = "arn:aws:iam::123456789012:role/foo"
+	conf.Credentials.RoleExternalID = "baz"
+
+	sess, err := conf.GetSession(func(c *aws.Config) {
+		c.HTTPClient = client
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "http://localhost:4572", aws.StringValue(sess.Config.Endpoint); exp != act {
+		t.Errorf("Wrong endpoint: %v != %v", act, exp)
+	}
+	if !sess.Config.Credentials.IsExpired() {
+		t.Error("Expected role credentials to be retrieved lazily")
+	}
+
+	creds, err := sess.Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "role_id", creds.AccessKeyID; exp != act {
+		t.Errorf("Wrong access key: %v != %v", act, exp)
+	}
+	if exp, act := "role_token", creds.SessionToken; exp != act {
+		t.Errorf("Wrong token: %v != %v", act, exp)
+	}
+
+	if exp, act := "sts.amazonaws.com", reqHost; exp != act {
+		t.Errorf("Wrong STS host: %v != %v", act, exp)
+	}
+	if exp, act := "AssumeRole", reqForm.Get("Action"); exp != act {
+		t.Errorf("Wrong action: %v != %v", act, exp)
+	}
+	if exp, act := conf.Credentials.Role, reqForm.Get("RoleArn"); exp != act {
+		t.Errorf("Wrong role: %v != %v", act, exp)
+	}
+	if exp, act := "baz", reqForm.Get("ExternalId"); exp != act {
+		t.Errorf("Wrong external ID: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,67 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package metrics
+
+//------------------------------------------------------------------------------
+
+// Namespaced wraps a metrics aggregator and adds a prefix to the path of all
+// metrics passed to it. This allows components sharing a single aggregator to
+// be distinguished from one another.
+type Namespaced struct {
+	prefix string
+	child  Type
+}
+
+// NewNamespaced creates a new metrics aggregator that prefixes all paths with
+// the namespace followed by a dot before passing them to child.
+func NewNamespaced(child Type, namespace string) *Namespaced {
+	return &Namespaced{
+		prefix: namespace + ".",
+		child:  child,
+	}
+}
+
+// Incr increments a metric by an amount.
+func (n *Namespaced) Incr(path string, count int64) error {
+	return n.child.Incr(n.prefix+path, count)
+}
+
+// Decr decrements a metric by an amount.
+func (n *Namespaced) Decr(path string, count int64) error {
+	return n.child.Decr(n.prefix+path, count)
+}
+
+// Timing sets a timing metric.
+func (n *Namespaced) Timing(path string, delta int64) error {
+	return n.child.Timing(n.prefix+path, delta)
+}
+
+// Gauge sets a gauge metric.
+func (n *Namespaced) Gauge(path string, value int64) error {
+	return n.child.Gauge(n.prefix+path, value)
+}
+
+// Close does nothing, as the underlying aggregator is shared and should be
+// closed by its owner.
+func (n *Namespaced) Close() error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,71 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+type recordingType struct {
+	paths []string
+}
+
+func (r *recordingType) Incr(path string, count int64) error {
+	r.paths = append(r.paths, path)
+	return nil
+}
+
+func (r *recordingType) Decr(path string, count int64) error {
+	r.paths = append(r.paths, path)
+	return nil
+}
+
+func (r *recordingType) Timing(path string, delta int64) error {
+	r.paths = append(r.paths, path)
+	return nil
+}
+
+func (r *recordingType) Gauge(path string, value int64) error {
+	r.paths = append(r.paths, path)
+	return nil
+}
+
+func (r *recordingType) Close() error { return nil }
+
+func TestNamespacedPaths(t *testing.T) {
+	rec := &recordingType{}
+	n := NewNamespaced(rec, "stream.foo")
+
+	n.Incr("input.count", 1)
+	n.Decr("input.count", 1)
+	n.Timing("output.latency", 1)
+	n.Gauge("buffer.backlog", 1)
+
+	exp := []string{
+		"stream.foo.input.count",
+		"stream.foo.input.count",
+		"stream.foo.output.latency",
+		"stream.foo.buffer.backlog",
+	}
+	if !reflect.DeepEqual(exp, rec.paths) {
+		t.Errorf("Wrong paths: %v != %v", rec.paths, exp)
+	}
+}
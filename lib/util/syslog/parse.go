@@ -0,0 +1,309 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Syslog message formats.
+const (
+	FormatAuto    = "auto"
+	FormatRFC5424 = "rfc5424"
+	FormatRFC3164 = "rfc3164"
+)
+
+// Errors returned when parsing syslog messages.
+var (
+	ErrInvalidFormat    = errors.New("syslog format was not recognised")
+	ErrInvalidPriority  = errors.New("invalid syslog priority")
+	ErrInvalidHeader    = errors.New("invalid syslog header")
+	ErrInvalidTimestamp = errors.New("invalid syslog timestamp")
+	ErrInvalidSD        = errors.New("invalid syslog structured data")
+)
+
+//------------------------------------------------------------------------------
+
+// Message is a parsed syslog message. Fields that are absent from a message
+// are left empty.
+type Message struct {
+	Priority       int                          `json:"priority"`
+	Facility       int                          `json:"facility"`
+	Severity       int                          `json:"severity"`
+	Version        int                          `json:"version,omitempty"`
+	Timestamp      string                       `json:"timestamp,omitempty"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	AppName        string                       `json:"appname,omitempty"`
+	ProcID         string                       `json:"procid,omitempty"`
+	MsgID          string                       `json:"msgid,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        string                       `json:"message"`
+}
+
+//------------------------------------------------------------------------------
+
+// ValidFormat returns whether a format is recognised.
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatAuto, FormatRFC5424, FormatRFC3164:
+		return true
+	}
+	return false
+}
+
+// Parse parses a syslog message of a format. When the format is FormatAuto
+// messages with a version following the priority are parsed as RFC5424, and
+// all others are parsed as RFC3164.
+func Parse(b []byte, format string) (*Message, error) {
+	if !ValidFormat(format) {
+		return nil, ErrInvalidFormat
+	}
+
+	msg := &Message{}
+	rest, err := parsePriority(b, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == FormatAuto {
+		format = FormatRFC3164
+		if i := bytes.IndexByte(rest, ' '); i > 0 && i <= 3 && isDigits(rest[:i]) {
+			format = FormatRFC5424
+		}
+	}
+
+	if format == FormatRFC5424 {
+		err = parseRFC5424(rest, msg)
+	} else {
+		err = parseRFC3164(rest, msg, time.Now())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
+
+func isDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(b) > 0
+}
+
+func parsePriority(b []byte, msg *Message) ([]byte, error) {
+	if len(b) < 3 || b[0] != '<' {
+		return nil, ErrInvalidPriority
+	}
+	end := bytes.IndexByte(b, '>')
+	if end < 2 || end > 4 || !isDigits(b[1:end]) {
+		return nil, ErrInvalidPriority
+	}
+	pri, _ := strconv.Atoi(string(b[1:end]))
+	if pri > 191 {
+		return nil, ErrInvalidPriority
+	}
+	msg.Priority = pri
+	msg.Facility = pri / 8
+	msg.Severity = pri % 8
+	return b[end+1:], nil
+}
+
+// nextField returns the next space delimited field, where a value of "-" is
+// the nil value.
+func nextField(b []byte) (string, []byte, error) {
+	i := bytes.IndexByte(b, ' ')
+	if i < 0 {
+		i = len(b)
+	}
+	if i == 0 {
+		return "", nil, ErrInvalidHeader
+	}
+	field := string(b[:i])
+	if i < len(b) {
+		b = b[i+1:]
+	} else {
+		b = nil
+	}
+	if field == "-" {
+		field = ""
+	}
+	return field, b, nil
+}
+
+//------------------------------------------------------------------------------
+
+func parseRFC5424(b []byte, msg *Message) error {
+	var err error
+	var field string
+
+	if field, b, err = nextField(b); err != nil {
+		return err
+	}
+	if !isDigits([]byte(field)) {
+		return ErrInvalidHeader
+	}
+	if msg.Version, err = strconv.Atoi(field); err != nil || msg.Version == 0 {
+		return ErrInvalidHeader
+	}
+
+	if msg.Timestamp, b, err = nextField(b); err != nil {
+		return err
+	}
+	if len(msg.Timestamp) > 0 {
+		if _, err = time.Parse(time.RFC3339Nano, msg.Timestamp); err != nil {
+			return ErrInvalidTimestamp
+		}
+	}
+
+	for _, f := range []*string{&msg.Hostname, &msg.AppName, &msg.ProcID, &msg.MsgID} {
+		if *f, b, err = nextField(b); err != nil {
+			return err
+		}
+	}
+
+	if len(b) == 0 {
+		return ErrInvalidSD
+	}
+	if b[0] == '-' {
+		b = b[1:]
+	} else if msg.StructuredData, b, err = parseSD(b); err != nil {
+		return err
+	}
+
+	if len(b) > 0 {
+		if b[0] != ' ' {
+			return ErrInvalidSD
+		}
+		b = bytes.TrimPrefix(b[1:], []byte("\xef\xbb\xbf"))
+	}
+	msg.Message = string(b)
+	return nil
+}
+
+// parseSD parses one or more structured data elements.
+func parseSD(b []byte) (map[string]map[string]string, []byte, error) {
+	sd := map[string]map[string]string{}
+	for len(b) > 0 && b[0] == '[' {
+		b = b[1:]
+
+		end := bytes.IndexAny(b, " ]")
+		if end <= 0 {
+			return nil, nil, ErrInvalidSD
+		}
+		params := map[string]string{}
+		sd[string(b[:end])] = params
+		b = b[end:]
+
+		for len(b) > 0 && b[0] == ' ' {
+			b = b[1:]
+			eq := bytes.IndexByte(b, '=')
+			if eq <= 0 || len(b) < eq+2 || b[eq+1] != '"' {
+				return nil, nil, ErrInvalidSD
+			}
+			name := string(b[:eq])
+			b = b[eq+2:]
+
+			var value []byte
+			closed := false
+			for i := 0; i < len(b); i++ {
+				if b[i] == '\\' && i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']') {
+					value = append(value, b[i+1])
+					i++
+					continue
+				}
+				if b[i] == '"' {
+					b = b[i+1:]
+					closed = true
+					break
+				}
+				value = append(value, b[i])
+			}
+			if !closed {
+				return nil, nil, ErrInvalidSD
+			}
+			params[name] = string(value)
+		}
+
+		if len(b) == 0 || b[0] != ']' {
+			return nil, nil, ErrInvalidSD
+		}
+		b = b[1:]
+	}
+	return sd, b, nil
+}
+
+//------------------------------------------------------------------------------
+
+// parseRFC3164 parses the remainder of a BSD syslog message. The timestamp of
+// these messages lacks a year, which is taken from now, and messages that lack
+// a valid timestamp are read entirely as the message content.
+func parseRFC3164(b []byte, msg *Message, now time.Time) error {
+	if len(b) < len(time.Stamp) {
+		msg.Message = string(b)
+		return nil
+	}
+
+	ts, err := time.ParseInLocation(time.Stamp, string(b[:len(time.Stamp)]), now.Location())
+	if err != nil {
+		msg.Message = string(b)
+		return nil
+	}
+	ts = ts.AddDate(now.Year(), 0, 0)
+	if ts.After(now.AddDate(0, 1, 0)) {
+		// Messages from the end of last year received in the new year.
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	msg.Timestamp = ts.Format(time.RFC3339)
+
+	b = bytes.TrimPrefix(b[len(time.Stamp):], []byte(" "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		msg.Hostname = string(b[:i])
+		b = b[i+1:]
+	} else {
+		msg.Hostname = string(b)
+		b = nil
+	}
+
+	// The tag is an app name, optionally followed by a process ID in square
+	// brackets, terminated by a colon.
+	if i := bytes.IndexByte(b, ':'); i > 0 && bytes.IndexByte(b[:i], ' ') < 0 {
+		tag := b[:i]
+		if j := bytes.IndexByte(tag, '['); j > 0 && tag[len(tag)-1] == ']' {
+			msg.ProcID = string(tag[j+1 : len(tag)-1])
+			tag = tag[:j]
+		}
+		msg.AppName = string(tag)
+		b = bytes.TrimPrefix(b[i+1:], []byte(" "))
+	}
+
+	msg.Message = string(b)
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,168 @@
+// Copyright (c) 2014 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package syslog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	tests := map[string]Message{
+		`<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - BOM'su root' failed for lonvick on /dev/pts/8`: {
+			Priority:  34,
+			Facility:  4,
+			Severity:  2,
+			Version:   1,
+			Timestamp: "2003-10-11T22:14:15.003Z",
+			Hostname:  "mymachine.example.com",
+			AppName:   "su",
+			MsgID:     "ID47",
+			Message:   "BOM'su root' failed for lonvick on /dev/pts/8",
+		},
+		`<165>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - - %% It's time to make the do-nuts.`: {
+			Priority:  165,
+			Facility:  20,
+			Severity:  5,
+			Version:   1,
+			Timestamp: "2003-08-24T05:14:15.000003-07:00",
+			Hostname:  "192.0.2.1",
+			AppName:   "myproc",
+			ProcID:    "8710",
+			Message:   "%% It's time to make the do-nuts.",
+		},
+		`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high\"\]"] ` + "\xef\xbb\xbf" + `An application event log entry...`: {
+			Priority:  165,
+			Facility:  20,
+			Severity:  5,
+			Version:   1,
+			Timestamp: "2003-10-11T22:14:15.003Z",
+			Hostname:  "mymachine.example.com",
+			AppName:   "evntslog",
+			MsgID:     "ID47",
+			StructuredData: map[string]map[string]string{
+				"exampleSDID@32473": {
+					"iut":         "3",
+					"eventSource": "Application",
+					"eventID":     "1011",
+				},
+				"examplePriority@32473": {
+					"class": `high"]`,
+				},
+			},
+			Message: "An application event log entry...",
+		},
+		`<13>1 - - - - - [meta]`: {
+			Priority:       13,
+			Facility:       1,
+			Severity:       5,
+			Version:        1,
+			StructuredData: map[string]map[string]string{"meta": {}},
+		},
+	}
+
+	for input, exp := range tests {
+		for _, format := range []string{FormatAuto, FormatRFC5424} {
+			act, err := Parse([]byte(input), format)
+			if err != nil {
+				t.Errorf("Failed to parse '%v': %v", input, err)
+				continue
+			}
+			if !reflect.DeepEqual(exp, *act) {
+				t.Errorf("Wrong result for '%v': %+v != %+v", input, *act, exp)
+			}
+		}
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	now := time.Date(2018, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]Message{
+		`Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`: {
+			Timestamp: "2017-10-11T22:14:15Z",
+			Hostname:  "mymachine",
+			AppName:   "su",
+			Message:   "'su root' failed for lonvick on /dev/pts/8",
+		},
+		`Feb  5 17:32:18 10.0.0.99 sshd[1234]: Accepted publickey for root`: {
+			Timestamp: "2018-02-05T17:32:18Z",
+			Hostname:  "10.0.0.99",
+			AppName:   "sshd",
+			ProcID:    "1234",
+			Message:   "Accepted publickey for root",
+		},
+		`Use the BFG!`: {
+			Message: "Use the BFG!",
+		},
+	}
+
+	for input, exp := range tests {
+		act := Message{}
+		if err := parseRFC3164([]byte(input), &act, now); err != nil {
+			t.Errorf("Failed to parse '%v': %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result for '%v': %+v != %+v", input, act, exp)
+		}
+	}
+}
+
+func TestParseAutoRFC3164(t *testing.T) {
+	act, err := Parse([]byte(`<34>Oct 11 22:14:15 mymachine su: failed`), FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := 34; act.Priority != exp {
+		t.Errorf("Wrong priority: %v != %v", act.Priority, exp)
+	}
+	if exp := "su"; act.AppName != exp {
+		t.Errorf("Wrong app name: %v != %v", act.AppName, exp)
+	}
+	if exp := 0; act.Version != exp {
+		t.Errorf("Wrong version: %v != %v", act.Version, exp)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]error{
+		`no priority`:                                ErrInvalidPriority,
+		`<>1 - - - - - -`:                            ErrInvalidPriority,
+		`<192>1 - - - - - -`:                         ErrInvalidPriority,
+		`<34>1 yesterday host app - - -`:             ErrInvalidTimestamp,
+		`<34>1 - host app - -`:                       ErrInvalidSD,
+		`<34>1 - host app - - [id foo="bar]`:         ErrInvalidSD,
+		`<34>1 - host app - - [id foo=bar]`:          ErrInvalidSD,
+		`<34>1 - host app - - [id foo="bar"]message`: ErrInvalidSD,
+		`<34>1  - host app - - -`:                    ErrInvalidHeader,
+	}
+
+	for input, exp := range tests {
+		if _, err := Parse([]byte(input), FormatRFC5424); err != exp {
+			t.Errorf("Wrong error for '%v': %v != %v", input, err, exp)
+		}
+	}
+
+	if _, err := Parse([]byte(`<34>1 - - - - - -`), "nope"); err != ErrInvalidFormat {
+		t.Errorf("Wrong error: %v != %v", err, ErrInvalidFormat)
+	}
+}
@@ -22,11 +22,15 @@ package text
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"regexp"
 	"strconv"
 	"sync"
 	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/gabs"
 )
 
 //------------------------------------------------------------------------------
@@ -92,6 +96,33 @@ var functionVars = map[string]func(arg string) []byte{
 	},
 }
 
+// messageFunctionVars are functions that reference the content of a message
+// part.
+var messageFunctionVars = map[string]func(msg types.Message, index int, arg string) []byte{
+	"content": func(msg types.Message, index int, arg string) []byte {
+		return msg.Get(index)
+	},
+	"json_field": func(msg types.Message, index int, arg string) []byte {
+		jObj, err := msg.GetJSON(index)
+		if err != nil {
+			return nil
+		}
+		gObj, err := gabs.Consume(jObj)
+		if err != nil {
+			return nil
+		}
+		switch t := gObj.Path(arg).Data().(type) {
+		case nil:
+			return nil
+		case string:
+			return []byte(t)
+		default:
+			jBytes, _ := json.Marshal(t)
+			return jBytes
+		}
+	},
+}
+
 // ContainsFunctionVariables returns true if inBytes contains function variable
 // replace patterns.
 func ContainsFunctionVariables(inBytes []byte) bool {
@@ -104,18 +135,48 @@ func ContainsFunctionVariables(inBytes []byte) bool {
 // For each aforementioned pattern found in the blob the contents of the
 // respective function will be run and will replace the pattern.
 func ReplaceFunctionVariables(inBytes []byte) []byte {
+	return replaceFunctionVariables(inBytes, func(name, arg string) ([]byte, bool) {
+		if ftor, exists := functionVars[name]; exists {
+			return ftor(arg), true
+		}
+		return nil, false
+	})
+}
+
+// ReplaceMessageFunctionVariables behaves the same as ReplaceFunctionVariables,
+// but also supports functions that reference the content of a message part:
+//
+// `${!content}` is replaced with the raw contents of the part.
+//
+// `${!json_field:foo.bar}` is replaced with the value at the path `foo.bar` of
+// the part parsed as JSON, where strings are inserted raw and other values as
+// JSON. Missing values are replaced with an empty string.
+func ReplaceMessageFunctionVariables(msg types.Message, index int, inBytes []byte) []byte {
+	return replaceFunctionVariables(inBytes, func(name, arg string) ([]byte, bool) {
+		if ftor, exists := messageFunctionVars[name]; exists {
+			return ftor(msg, index, arg), true
+		}
+		if ftor, exists := functionVars[name]; exists {
+			return ftor(arg), true
+		}
+		return nil, false
+	})
+}
+
+func replaceFunctionVariables(
+	inBytes []byte, lookup func(name, arg string) ([]byte, bool),
+) []byte {
 	return functionRegex.ReplaceAllFunc(inBytes, func(content []byte) []byte {
 		if len(content) > 4 {
 			if colonIndex := bytes.IndexByte(content, ':'); colonIndex == -1 {
-				targetFunc := string(content[3 : len(content)-1])
-				if ftor, exists := functionVars[targetFunc]; exists {
-					return ftor("")
+				if res, exists := lookup(string(content[3:len(content)-1]), ""); exists {
+					return res
 				}
 			} else {
 				targetFunc := string(content[3:colonIndex])
 				argVal := string(content[colonIndex+1 : len(content)-1])
-				if ftor, exists := functionVars[targetFunc]; exists {
-					return ftor(argVal)
+				if res, exists := lookup(targetFunc, argVal); exists {
+					return res
 				}
 			}
 		}
@@ -26,6 +26,8 @@ import (
 	"strconv"
 	"testing"
 	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestFunctionVarDetection(t *testing.T) {
@@ -159,3 +161,30 @@ func TestCountersFunction(t *testing.T) {
 		}
 	}
 }
+
+func TestMessageFunctions(t *testing.T) {
+	msg := types.NewMessage([][]byte{
+		[]byte(`{"foo":{"bar":"baz","qux":[1,2]}}`),
+		[]byte(`not json`),
+	})
+
+	tests := []struct {
+		index int
+		in    string
+		exp   string
+	}{
+		{0, "key-${!json_field:foo.bar}", "key-baz"},
+		{0, "${!json_field:foo.qux}", "[1,2]"},
+		{0, "missing:${!json_field:foo.nope}", "missing:"},
+		{1, "${!content}", "not json"},
+		{1, "${!json_field:foo}", ""},
+		{1, "${!echo:foo} ${!nope}", "foo ${!nope}"},
+	}
+
+	for _, test := range tests {
+		act := string(ReplaceMessageFunctionVariables(msg, test.index, []byte(test.in)))
+		if act != test.exp {
+			t.Errorf("Wrong result for '%v': %v != %v", test.in, act, test.exp)
+		}
+	}
+}